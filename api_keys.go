@@ -0,0 +1,276 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// keyProbeTTL is how long a key that passed a probe is used without probing it again.
+const keyProbeTTL = 10 * time.Minute
+
+// ApiKeyEntry is one of several API keys a provider can rotate through.
+type ApiKeyEntry struct {
+	Label       string `json:"label"` // e.g. "personal" or "team"
+	Key         string `json:"key"`
+	Exhausted   bool   `json:"exhausted"`              // Skipped at launch until reset or a probe succeeds
+	ExhaustedAt string `json:"exhausted_at,omitempty"` // RFC 3339
+	Reason      string `json:"reason,omitempty"`       // Why it was marked exhausted
+	LastUsed    string `json:"last_used,omitempty"`    // RFC 3339 time of the last launch with this key
+}
+
+type ApiKeyProbe struct {
+	Index  int                `json:"index"`
+	Label  string             `json:"label"`
+	Result ProviderTestResult `json:"result"`
+}
+
+// normalizeApiKeys keeps ActiveKey in range and ApiKey equal to the active entry.
+func normalizeApiKeys(m *ModelConfig) {
+	if len(m.ApiKeys) == 0 {
+		m.ActiveKey = 0
+		return
+	}
+	if m.ActiveKey < 0 || m.ActiveKey >= len(m.ApiKeys) {
+		m.ActiveKey = 0
+	}
+	m.ApiKey = m.ApiKeys[m.ActiveKey].Key
+}
+
+// setActiveApiKey replaces the key in use. A provider with a key list gets it in
+// the active entry, which normalizeApiKeys would otherwise copy back over ApiKey.
+func setActiveApiKey(m *ModelConfig, key string) {
+	m.ApiKey = key
+	if len(m.ApiKeys) == 0 {
+		return
+	}
+	if m.ActiveKey < 0 || m.ActiveKey >= len(m.ApiKeys) {
+		m.ActiveKey = 0
+	}
+	if k := &m.ApiKeys[m.ActiveKey]; k.Key != key {
+		*k = ApiKeyEntry{Label: k.Label, Key: key}
+	}
+}
+
+// keyFailed reports whether a probe result means the key itself is unusable,
+// as opposed to a network or server problem.
+func keyFailed(r ProviderTestResult) bool {
+	return r.Kind == "auth" || r.Kind == "quota"
+}
+
+// nextUsableKey returns the first usable key at or after start, wrapping around.
+func nextUsableKey(keys []ApiKeyEntry, start int) (int, bool) {
+	for n := 0; n < len(keys); n++ {
+		i := (start + n) % len(keys)
+		if !keys[i].Exhausted && keys[i].Key != "" {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// chooseLaunchKey picks the key index for a launch. Round-robin moves on to the next
+// key every time; sticky stays on the active key while it is usable.
+func chooseLaunchKey(m *ModelConfig) (int, bool) {
+	start := m.ActiveKey
+	if m.KeyPolicy == "round_robin" && m.ApiKeys[m.ActiveKey].LastUsed != "" {
+		start = m.ActiveKey + 1
+	}
+	return nextUsableKey(m.ApiKeys, start)
+}
+
+// hasApiKey reports whether the user gave the provider a key.
+func hasApiKey(m ModelConfig) bool {
+	if m.ApiKey != "" {
+		return true
+	}
+	for _, k := range m.ApiKeys {
+		if k.Key != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// keyProbeCache remembers which keys recently passed a probe, so that a launch
+// does not send a request before every start.
+type keyProbeCache struct {
+	mu     sync.Mutex
+	passed map[string]time.Time // keyProbeId -> time of the last good probe
+}
+
+// keyProbeId identifies a key at an endpoint without keeping the key itself.
+func keyProbeId(tool string, m *ModelConfig, apiKey string) string {
+	sum := sha256.Sum256([]byte(tool + "\x00" + resolveProvider(tool, m).BaseUrl + "\x00" + apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *keyProbeCache) fresh(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	at, ok := c.passed[id]
+	return ok && time.Since(at) < keyProbeTTL
+}
+
+// note records a probe result: a good one is trusted for keyProbeTTL, a failed key
+// is probed again next time.
+func (c *keyProbeCache) note(id string, r ProviderTestResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case r.Ok:
+		if c.passed == nil {
+			c.passed = make(map[string]time.Time)
+		}
+		c.passed[id] = time.Now()
+	case keyFailed(r):
+		delete(c.passed, id)
+	}
+}
+
+func markKeyExhausted(k *ApiKeyEntry, reason string) {
+	k.Exhausted = true
+	k.ExhaustedAt = time.Now().Format(time.RFC3339)
+	k.Reason = reason
+}
+
+// pickLaunchKey chooses the key for this launch, records it in the config store and
+// updates config and selectedModel. With more than one key the chosen key is probed first, unless
+// it passed a probe within keyProbeTTL, and a key that fails authentication or is out of quota is
+// marked and skipped, in sticky and round_robin mode alike.
+func (a *App) pickLaunchKey(config *AppConfig, tool string, selectedModel *ModelConfig) {
+	m := getProviderModel(getToolConfig(config, tool), selectedModel.ModelName)
+	if m == nil || len(m.ApiKeys) == 0 {
+		return
+	}
+	i, ok := chooseLaunchKey(m)
+	if ok && len(m.ApiKeys) > 1 {
+		for ok {
+			id := keyProbeId(tool, m, m.ApiKeys[i].Key)
+			if a.keyProbes.fresh(id) {
+				break
+			}
+			r := probeProviderKey(tool, m, m.ApiKeys[i].Key)
+			a.keyProbes.note(id, r)
+			if !keyFailed(r) {
+				break
+			}
+			markKeyExhausted(&m.ApiKeys[i], r.Message)
+			a.log(fmt.Sprintf("API key %q of %s failed (%s), trying the next one", m.ApiKeys[i].Label, m.ModelName, r.Kind))
+			i, ok = nextUsableKey(m.ApiKeys, i+1)
+		}
+	}
+	if !ok {
+		// Every key is marked exhausted; launch with the active one rather than none
+		i = m.ActiveKey
+		a.log(fmt.Sprintf("All API keys of %s are marked exhausted, using %q", m.ModelName, m.ApiKeys[i].Label))
+	}
+	m.ActiveKey = i
+	m.ApiKey = m.ApiKeys[i].Key
+	m.ApiKeys[i].LastUsed = time.Now().Format(time.RFC3339)
+	*selectedModel = *m
+	a.log(fmt.Sprintf("Launching %s with API key %q", m.ModelName, m.ApiKeys[i].Label))
+	chosen := *m
+	// Written without key sync, so the choice is not taken for an edit to copy to other tools
+	updated, err := a.configStore().update(configSourceApp, func(c *AppConfig) error {
+		if stored := getProviderModel(getToolConfig(c, tool), chosen.ModelName); stored != nil {
+			stored.ApiKeys = chosen.ApiKeys
+			stored.ActiveKey = chosen.ActiveKey
+			stored.ApiKey = chosen.ApiKey
+		}
+		return nil
+	}, a.writeConfig)
+	if err != nil {
+		a.log("Failed to record API key choice: " + err.Error())
+		return
+	}
+	// config already has the choice and keeps the launch's own settings, such as a
+	// provider from .aicoder.json
+	a.emitEvent("config-updated", updated)
+}
+
+// findProviderModel returns the named provider of a tool.
+func findProviderModel(config *AppConfig, tool, providerName string) (*ModelConfig, error) {
+	toolCfg := getToolConfig(config, tool)
+	if toolCfg == nil {
+		return nil, fmt.Errorf("unknown tool %s", tool)
+	}
+	m := getProviderModel(toolCfg, providerName)
+	if m == nil {
+		return nil, fmt.Errorf("provider %s not found for %s", providerName, tool)
+	}
+	return m, nil
+}
+
+// ProbeApiKeys checks every key of a provider with a minimal request. Keys that fail
+// authentication or are out of quota are marked exhausted; keys that work again are cleared.
+func (a *App) ProbeApiKeys(tool string, providerName string) ([]ApiKeyProbe, error) {
+	tool = strings.ToLower(tool)
+	config, err := a.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	m, err := findProviderModel(&config, tool, providerName)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.ApiKeys) == 0 {
+		return nil, fmt.Errorf("%s has no API key list", providerName)
+	}
+	// The requests run outside the config store, so other changes are not held up
+	// while they take
+	var probes []ApiKeyProbe
+	for i, k := range m.ApiKeys {
+		r := probeProviderKey(tool, m, k.Key)
+		a.keyProbes.note(keyProbeId(tool, m, k.Key), r)
+		probes = append(probes, ApiKeyProbe{Index: i, Label: k.Label, Result: r})
+	}
+	probed := m.ApiKeys
+	_, err = a.modifyConfig(func(config *AppConfig) error {
+		m, err := findProviderModel(config, tool, providerName)
+		if err != nil {
+			return err
+		}
+		// The list may have been edited meanwhile; each result goes to the key it is for
+		for _, p := range probes {
+			for i := range m.ApiKeys {
+				k := &m.ApiKeys[i]
+				if k.Key != probed[p.Index].Key {
+					continue
+				}
+				switch {
+				case keyFailed(p.Result):
+					markKeyExhausted(k, p.Result.Message)
+				case p.Result.Ok:
+					k.Exhausted, k.ExhaustedAt, k.Reason = false, "", ""
+				}
+			}
+		}
+		return nil
+	})
+	return probes, err
+}
+
+// SetApiKeyExhausted marks a key as exhausted or makes it usable again.
+func (a *App) SetApiKeyExhausted(tool string, providerName string, index int, exhausted bool) (AppConfig, error) {
+	tool = strings.ToLower(tool)
+	return a.modifyConfig(func(config *AppConfig) error {
+		m, err := findProviderModel(config, tool, providerName)
+		if err != nil {
+			return err
+		}
+		if index < 0 || index >= len(m.ApiKeys) {
+			return fmt.Errorf("%s has no API key %d", providerName, index)
+		}
+		k := &m.ApiKeys[index]
+		if exhausted {
+			markKeyExhausted(k, "marked by user")
+		} else {
+			k.Exhausted, k.ExhaustedAt, k.Reason = false, "", ""
+		}
+		return nil
+	})
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newKeyTestApp returns an App with a Codex provider at srv holding keys.
+func newKeyTestApp(t *testing.T, srv *httptest.Server, keys ...string) *App {
+	t.Helper()
+	a := newSecretTestApp(t, newFakeSecretBackend("fake"))
+	t.Setenv("HOME", a.testHomeDir)
+	m := ModelConfig{ModelName: "Proxy", IsCustom: true, ModelUrl: srv.URL + "/v1", ModelId: "m-1", KeyPolicy: "sticky"}
+	for _, k := range keys {
+		m.ApiKeys = append(m.ApiKeys, ApiKeyEntry{Label: k, Key: k})
+	}
+	normalizeApiKeys(&m)
+	config := secretTestConfig()
+	config.Codex = ToolConfig{CurrentModel: "Proxy", Models: []ModelConfig{{ModelName: "Original"}, m}}
+	path, _ := a.getConfigPath()
+	if err := a.saveToPath(path, config); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// keyTestHandler rejects sk-bad and accepts every other key, counting requests.
+func keyTestHandler(requests *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		if strings.HasSuffix(r.Header.Get("Authorization"), "sk-bad") {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"message":"invalid api key"}}`))
+			return
+		}
+		w.Write([]byte(`{"choices":[]}`))
+	}
+}
+
+func TestPickLaunchKeyProbesOnlyWhenStale(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(keyTestHandler(&requests))
+	defer srv.Close()
+	a := newKeyTestApp(t, srv, "sk-bad", "sk-good", "sk-other")
+
+	launch := func() ModelConfig {
+		config, _ := a.LoadConfig()
+		selected := *getProviderModel(&config.Codex, "Proxy")
+		a.pickLaunchKey(&config, "codex", &selected)
+		return selected
+	}
+	if m := launch(); m.ApiKey != "sk-good" || !m.ApiKeys[0].Exhausted {
+		t.Fatalf("first launch: key %q, exhausted %v", m.ApiKey, m.ApiKeys[0].Exhausted)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Fatalf("first launch sent %d probes, want 2", n)
+	}
+	if m := launch(); m.ApiKey != "sk-good" {
+		t.Fatalf("second launch: key %q", m.ApiKey)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Fatalf("key that just passed was probed again (%d requests)", n)
+	}
+	config, _ := a.LoadConfig()
+	if m := getProviderModel(&config.Codex, "Proxy"); m.ActiveKey != 1 || !m.ApiKeys[0].Exhausted {
+		t.Fatalf("choice not saved: %+v", m)
+	}
+}
+
+func TestPickLaunchKeyRoundRobinSkipsFailingKey(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(keyTestHandler(&requests))
+	defer srv.Close()
+	a := newKeyTestApp(t, srv, "sk-a", "sk-bad", "sk-c")
+	if _, err := a.modifyConfig(func(c *AppConfig) error {
+		getProviderModel(&c.Codex, "Proxy").KeyPolicy = "round_robin"
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		config, _ := a.LoadConfig()
+		selected := *getProviderModel(&config.Codex, "Proxy")
+		a.pickLaunchKey(&config, "codex", &selected)
+		got = append(got, selected.ApiKey)
+	}
+	if strings.Join(got, ",") != "sk-a,sk-c,sk-a,sk-c" {
+		t.Fatalf("launch keys = %v", got)
+	}
+	// sk-a and sk-c are probed once each, sk-bad once before it is marked
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Fatalf("%d probes, want 3", n)
+	}
+	config, _ := a.LoadConfig()
+	if m := getProviderModel(&config.Codex, "Proxy"); !m.ApiKeys[1].Exhausted || m.ApiKeys[1].Reason == "" {
+		t.Fatalf("failing key not marked: %+v", m.ApiKeys[1])
+	}
+}
+
+func TestProbeApiKeysDoesNotHoldConfig(t *testing.T) {
+	reached := make(chan struct{}, 3)
+	release := make(chan struct{})
+	var requests int32
+	handler := keyTestHandler(&requests)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached <- struct{}{}
+		<-release
+		handler(w, r)
+	}))
+	defer srv.Close()
+	a := newKeyTestApp(t, srv, "sk-bad", "sk-good")
+
+	type result struct {
+		probes []ApiKeyProbe
+		err    error
+	}
+	done := make(chan result)
+	go func() {
+		probes, err := a.ProbeApiKeys("codex", "Proxy")
+		done <- result{probes, err}
+	}()
+	<-reached
+	// Another change goes through while a probe is waiting for the provider
+	saved := make(chan error)
+	go func() {
+		_, err := a.modifyConfig(func(c *AppConfig) error {
+			c.EnvCheckInterval = 9
+			return nil
+		})
+		saved <- err
+	}()
+	select {
+	case err := <-saved:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		close(release)
+		t.Fatal("config change blocked by the probe")
+	}
+	close(release)
+	r := <-done
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if len(r.probes) != 2 || r.probes[0].Result.Kind != "auth" || !r.probes[1].Result.Ok {
+		t.Fatalf("probes = %+v", r.probes)
+	}
+	config, _ := a.LoadConfig()
+	m := getProviderModel(&config.Codex, "Proxy")
+	if !m.ApiKeys[0].Exhausted || m.ApiKeys[1].Exhausted {
+		t.Fatalf("results not applied: %+v", m.ApiKeys)
+	}
+	if config.EnvCheckInterval != 9 {
+		t.Fatal("concurrent change lost")
+	}
+}
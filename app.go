@@ -3,15 +3,18 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	goruntime "runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -33,6 +36,19 @@ type App struct {
 	installMutex      sync.Mutex
 	toolInstallLocks  map[string]bool    // Track which tools are currently being installed
 	toolLockMutex     sync.Mutex         // Mutex for toolInstallLocks map
+	secretStore       *secretStore       // Where API keys and passwords live; see secrets()
+	secretsOnce       sync.Once
+	redactor          secretRedactor     // Scrubs secrets from logs and log events
+	github            *githubClient      // Shared GitHub API client; see githubAPI()
+	githubOnce        sync.Once
+	configRecovery    *ConfigRecovery    // Set when a damaged config file was restored from a backup
+	store             *ConfigStore       // The configuration in memory; see configStore()
+	storeOnce         sync.Once
+	toolDrift         toolDriftState     // Managed tool values last reported as changed
+	historyMu         sync.Mutex         // Guards the config history files and historyCount
+	historyCount      int                // Entries in the history index, once historyCounted
+	historyCounted    bool
+	keyProbes         keyProbeCache      // Keys that recently passed a launch probe
 }
 var OnConfigChanged func(AppConfig)
 var UpdateTrayMenu func(string)
@@ -44,6 +60,34 @@ type ModelConfig struct {
 	ApiKey    string `json:"api_key"`
 	WireApi   string `json:"wire_api"`
 	IsCustom  bool   `json:"is_custom"`
+	Shared    bool   `json:"shared"` // Custom provider that exists in every tool with the same name and key
+	// Models for secondary roles; empty roles use ModelId
+	Roles ModelRoles `json:"roles"`
+	// Overrides for limits and tuning; zero values use the catalog defaults
+	Params ModelParams `json:"params"`
+	// Optional key list; ApiKey mirrors the entry picked at the last launch
+	ApiKeys   []ApiKeyEntry `json:"api_keys,omitempty"`
+	KeyPolicy string        `json:"key_policy,omitempty"` // "sticky" (default) or "round_robin"
+	ActiveKey int           `json:"active_key"`           // Index into ApiKeys
+	// Endpoint the catalog last set; a different ModelUrl is a user edit the catalog keeps
+	CatalogUrl string `json:"catalog_url,omitempty"`
+}
+// ModelRoles names the models a provider uses besides the main one (ModelId).
+type ModelRoles struct {
+	Fast      string `json:"fast"`      // Small, quick model for background work
+	Reasoning string `json:"reasoning"` // Strongest model, for planning and reviews
+	Compact   string `json:"compact"`   // Summarises long conversations
+}
+// ModelParams are the limits and tuning values written into tool configs.
+// Tools without a matching setting ignore a parameter.
+type ModelParams struct {
+	ContextWindow       int    `json:"context_window,omitempty"`
+	MaxOutputTokens     int    `json:"max_output_tokens,omitempty"`
+	MaxThinkingTokens   int    `json:"max_thinking_tokens,omitempty"`
+	ReasoningEffort     string `json:"reasoning_effort,omitempty"` // Codex: minimal, low, medium, high or xhigh
+	RequestMaxRetries   int    `json:"request_max_retries,omitempty"`
+	StreamMaxRetries    int    `json:"stream_max_retries,omitempty"`
+	StreamIdleTimeoutMs int    `json:"stream_idle_timeout_ms,omitempty"`
 }
 type ProjectConfig struct {
 	Id            string `json:"id"`
@@ -121,6 +165,20 @@ type AppConfig struct {
 	DefaultProxyPassword string `json:"default_proxy_password"`
 	// Terminal settings (Windows only)
 	UseWindowsTerminal bool `json:"use_windows_terminal"` // Use Windows Terminal instead of cmd.exe
+	// Signed provider catalog location (empty means the default channel)
+	ProviderCatalogUrl string `json:"provider_catalog_url"`
+	// Personal GitHub token for update checks and messages, kept in the secret store.
+	// GITHUB_TOKEN is used when it is empty.
+	GitHubToken string `json:"github_token,omitempty"`
+	// Number of config migrations applied; see configMigrations
+	SchemaVersion int `json:"schema_version"`
+	// API key sync policy per provider (lower-case name): "shared" (default) or "per_tool"
+	ProviderKeySync map[string]string `json:"provider_key_sync,omitempty"`
+	// SHA-256 of each project .aicoder.json the user trusted, by path
+	TrustedProjectFiles map[string]string `json:"trusted_project_files,omitempty"`
+	// Counts the changes committed in this session, so that SaveConfig can tell which
+	// config the UI edited; never saved to the file
+	Revision int64 `json:"revision,omitempty"`
 }
 type Skill struct {
 	Name        string `json:"name"`
@@ -176,6 +234,7 @@ func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 	// Platform specific initialization
 	a.platformStartup()
+	a.loadCachedCatalog()
 	a.startConfigWatcher()
 	// Initialize CodeBuddy config in project directory
 	if config, err := a.LoadConfig(); err == nil {
@@ -184,6 +243,14 @@ func (a *App) startup(ctx context.Context) {
 			a.SetLanguage(config.Language)
 		}
 	}
+	// Pick up provider endpoint changes without waiting for a release
+	if providerCatalogPublicKey != "" {
+		go func() {
+			if _, err := a.RefreshProviderCatalog(); err != nil {
+				a.log("Provider catalog refresh failed: " + err.Error())
+			}
+		}()
+	}
 }
 // domReady is called after the frontend Dom has been loaded
 func (a *App) domReady(ctx context.Context) {
@@ -191,54 +258,6 @@ func (a *App) domReady(ctx context.Context) {
 	// IsInitMode and PauseEnvCheck logic is handled inside CheckEnvironment
 	a.CheckEnvironment(false)
 }
-func (a *App) startConfigWatcher() {
-	var err error
-	a.watcher, err = fsnotify.NewWatcher()
-	if err != nil {
-		a.log("Failed to create file watcher: " + err.Error())
-		return
-	}
-	go func() {
-		for {
-			select {
-			case event, ok := <-a.watcher.Events:
-				if !ok {
-					return
-				}
-				if event.Op&fsnotify.Write == fsnotify.Write {
-					a.log(a.tr("Config file modified: ") + event.Name)
-					// Reload config and emit event
-					// We use a debounce-like approach or just reload.
-					// Since Wails events are async, it should be fine.
-					// However, writing the config (SaveConfig) also triggers a write event.
-					// We should probably check if the change was internal or external,
-					// but that's hard. For now, simply reloading might be okay,
-					// but it could cause a loop if we are not careful.
-					// Actually, if we just emit 'config-updated', the frontend updates.
-					// But if the frontend updates, it might save...
-					// Let's assume for now this is for external edits.
-					config, err := a.LoadConfig()
-					if err == nil {
-						a.emitEvent("config-updated", config)
-					}
-				}
-			case err, ok := <-a.watcher.Errors:
-				if !ok {
-					return
-				}
-				a.log("Watcher error: " + err.Error())
-			}
-		}
-	}()
-	configPath, err := a.getConfigPath()
-	if err == nil {
-		if err := a.watcher.Add(configPath); err != nil {
-			a.log("Failed to watch config file: " + err.Error())
-		} else {
-			a.log("Watching config file: " + configPath)
-		}
-	}
-}
 func (a *App) SetLanguage(lang string) {
 	a.CurrentLanguage = lang
 	if UpdateTrayMenu != nil {
@@ -327,25 +346,12 @@ func (a *App) getIFlowConfigPaths() (string, string) {
 	config := filepath.Join(dir, "settings.json")
 	return dir, config
 }
-func (a *App) clearClaudeConfig() {
-	dir, _, legacy := a.getClaudeConfigPaths()
-	home, _ := os.UserHomeDir()
-	os.RemoveAll(dir)
-	os.Remove(legacy)
-	os.Remove(filepath.Join(home, ".claude.json.backup"))
-	a.log("Cleared Claude configuration files")
-}
 func (a *App) clearGeminiConfig() {
 	dir, _, legacy := a.getGeminiConfigPaths()
 	os.RemoveAll(dir)
 	os.Remove(legacy)
 	a.log("Cleared Gemini configuration files")
 }
-func (a *App) clearCodexConfig() {
-	dir, _ := a.getCodexConfigPaths()
-	os.RemoveAll(dir)
-	a.log("Cleared Codex configuration directory")
-}
 func (a *App) clearOpencodeConfig() {
 	dir, _ := a.getOpencodeConfigPaths()
 	os.RemoveAll(dir)
@@ -378,20 +384,140 @@ func (a *App) clearKodeConfig() {
 	os.Remove(configPath)
 	a.log("Cleared Kode CLI configuration file")
 }
-func (a *App) clearEnvVars() {
-	vars := []string{
-		"ANTHROPIC_API_KEY", "ANTHROPIC_BASE_URL", "ANTHROPIC_AUTH_TOKEN",
-		"OPENAI_API_KEY", "OPENAI_BASE_URL", "WIRE_API",
-		"GEMINI_API_KEY", "GOOGLE_GEMINI_BASE_URL",
-		"OPENCODE_API_KEY", "OPENCODE_BASE_URL",
-		"CODEBUDDY_API_KEY", "CODEBUDDY_BASE_URL", "CODEBUDDY_CODE_MAX_OUTPUT_TOKENS",
-		"QODER_PERSONAL_ACCESS_TOKEN", "QODER_BASE_URL",
-		"IFLOW_API_KEY", "IFLOW_BASE_URL",
-		"KILO_API_KEY", "KILO_BASE_URL", "KILO_MODEL",
-	}
-	for _, v := range vars {
-		os.Unsetenv(v)
+// launchEnvVars lists the variables a launch sets for one tool or another. A launched
+// tool gets them from its launch only, never inherited from AICoder's environment.
+var launchEnvVars = []string{
+	"ANTHROPIC_API_KEY", "ANTHROPIC_BASE_URL", "ANTHROPIC_AUTH_TOKEN", "ANTHROPIC_MODEL",
+	"ANTHROPIC_DEFAULT_SONNET_MODEL", "ANTHROPIC_DEFAULT_HAIKU_MODEL", "ANTHROPIC_DEFAULT_OPUS_MODEL",
+	"CLAUDE_CODE_EXPERIMENTAL_AGENT_TEAMS",
+	"OPENAI_API_KEY", "OPENAI_BASE_URL", "OPENAI_MODEL", "WIRE_API",
+	"GEMINI_API_KEY", "GOOGLE_GEMINI_BASE_URL", "GOOGLE_GEMINI_MODEL",
+	"OPENCODE_API_KEY", "OPENCODE_BASE_URL", "OPENCODE_MODEL",
+	"CODEBUDDY_API_KEY", "CODEBUDDY_BASE_URL", "CODEBUDDY_CODE_MAX_OUTPUT_TOKENS",
+	"QODER_PERSONAL_ACCESS_TOKEN", "QODER_BASE_URL",
+	"IFLOW_API_KEY", "IFLOW_BASE_URL", "IFLOW_MODEL",
+	"KILO_API_KEY", "KILO_BASE_URL", "KILO_MODEL",
+	"KODE_MODEL",
+}
+// launchEnviron returns the environment for a launched tool: AICoder's own without
+// launchEnvVars and the keys of env, followed by env in key order.
+func launchEnviron(env map[string]string) []string {
+	drop := make(map[string]bool, len(launchEnvVars)+len(env))
+	for _, k := range launchEnvVars {
+		drop[k] = true
+	}
+	for k := range env {
+		drop[k] = true
+	}
+	var environ []string
+	for _, kv := range os.Environ() {
+		if k, _, _ := strings.Cut(kv, "="); !drop[k] {
+			environ = append(environ, kv)
+		}
+	}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		environ = append(environ, k+"="+env[k])
 	}
+	return environ
+}
+// claudeManagedEnvKeys lists the settings.json env keys AICoder writes, in the order
+// they are added to a new file. Every other key belongs to the user or other tools.
+func claudeManagedEnvKeys() []string {
+	keys := []string{
+		"ANTHROPIC_AUTH_TOKEN", "ANTHROPIC_BASE_URL", "ANTHROPIC_MODEL",
+		"ANTHROPIC_DEFAULT_SONNET_MODEL", "ANTHROPIC_DEFAULT_HAIKU_MODEL", "ANTHROPIC_DEFAULT_OPUS_MODEL",
+		"CLAUDE_CODE_USE_COLORS", "CLAUDE_CODE_MAX_OUTPUT_TOKENS", "MAX_THINKING_TOKENS",
+	}
+	var extra []string
+	seen := make(map[string]bool)
+	for _, k := range keys {
+		seen[k] = true
+	}
+	for _, p := range currentCatalog().Providers {
+		for k := range p.Tools["claude"].Env {
+			if !seen[k] {
+				seen[k] = true
+				extra = append(extra, k)
+			}
+		}
+	}
+	sort.Strings(extra)
+	return append(keys, extra...)
+}
+// applyClaudePermissions sets the permission keys the provider asks for and removes
+// values AICoder set for a previously selected provider. User values are left alone.
+func applyClaudePermissions(settings *orderedJSON, desired map[string]interface{}) error {
+	perms := settings.object("permissions")
+	changed := false
+	for _, p := range currentCatalog().Providers {
+		for k, v := range p.Tools["claude"].Permissions {
+			if _, want := desired[k]; want {
+				continue
+			}
+			var existing interface{}
+			if perms.get(k, &existing) && reflect.DeepEqual(existing, v) {
+				perms.remove(k)
+				changed = true
+			}
+		}
+	}
+	keys := make([]string, 0, len(desired))
+	for k := range desired {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var existing interface{}
+		if perms.get(k, &existing) && reflect.DeepEqual(existing, desired[k]) {
+			continue
+		}
+		if err := perms.set(k, desired[k]); err != nil {
+			return err
+		}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	if perms.len() == 0 {
+		settings.remove("permissions")
+		return nil
+	}
+	return settings.set("permissions", perms)
+}
+// removeClaudeManagedSettings undoes what syncToClaudeSettings wrote, for Original mode.
+// The official login in ~/.claude.json and the rest of settings.json are kept.
+func (a *App) removeClaudeManagedSettings() error {
+	_, settingsPath, _ := a.getClaudeConfigPaths()
+	if _, err := os.Stat(settingsPath); err != nil {
+		return nil
+	}
+	settings, err := readOrderedJSONFile(settingsPath)
+	if err != nil {
+		return err
+	}
+	env := settings.object("env")
+	for _, k := range claudeManagedEnvKeys() {
+		env.remove(k)
+	}
+	if env.len() == 0 {
+		settings.remove("env")
+	} else if err := settings.set("env", env); err != nil {
+		return err
+	}
+	if err := applyClaudePermissions(settings, nil); err != nil {
+		return err
+	}
+	if err := writeOrderedJSONFile(settingsPath, settings); err != nil {
+		return err
+	}
+	a.log("Removed AICoder provider settings from Claude configuration")
+	return nil
 }
 func (a *App) syncToClaudeSettings(config AppConfig) error {
 	var selectedModel *ModelConfig
@@ -404,114 +530,223 @@ func (a *App) syncToClaudeSettings(config AppConfig) error {
 	if selectedModel == nil {
 		return fmt.Errorf("selected model not found")
 	}
-	dir, settingsPath, legacyPath := a.getClaudeConfigPaths()
 	if strings.ToLower(selectedModel.ModelName) == "original" {
-		a.clearClaudeConfig()
-		return nil
+		return a.removeClaudeManagedSettings()
 	}
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	files, err := a.renderClaudeSettings(selectedModel)
+	if err != nil {
 		return err
 	}
-	settings := make(map[string]interface{})
+	return writeRenderedFiles(files)
+}
+// renderClaudeSettings merges the selected provider into settings.json and ~/.claude.json.
+func (a *App) renderClaudeSettings(selectedModel *ModelConfig) ([]renderedConfigFile, error) {
+	_, settingsPath, legacyPath := a.getClaudeConfigPaths()
 	env := make(map[string]string)
 	// Exclusively use AUTH_TOKEN for custom providers
 	env["ANTHROPIC_AUTH_TOKEN"] = selectedModel.ApiKey
 	env["CLAUDE_CODE_USE_COLORS"] = "true"
-	env["CLAUDE_CODE_MAX_OUTPUT_TOKENS"] = "64000"
-	env["MAX_THINKING_TOKENS"] = "31999"
-	switch strings.ToLower(selectedModel.ModelName) {
-	case "kimi":
-		env["ANTHROPIC_BASE_URL"] = "https://api.kimi.com/coding"
-		env["ANTHROPIC_DEFAULT_HAIKU_MODEL"] = selectedModel.ModelId
-		env["ANTHROPIC_DEFAULT_OPUS_MODEL"] = selectedModel.ModelId
-		env["ANTHROPIC_DEFAULT_SONNET_MODEL"] = selectedModel.ModelId
-		env["ANTHROPIC_MODEL"] = selectedModel.ModelId
-	case "glm", "glm-4.7":
-		env["ANTHROPIC_BASE_URL"] = "https://open.bigmodel.cn/api/anthropic"
-		env["ANTHROPIC_DEFAULT_HAIKU_MODEL"] = selectedModel.ModelId
-		env["ANTHROPIC_DEFAULT_OPUS_MODEL"] = selectedModel.ModelId
-		env["ANTHROPIC_DEFAULT_SONNET_MODEL"] = selectedModel.ModelId
-		env["ANTHROPIC_MODEL"] = selectedModel.ModelId
-		settings["permissions"] = map[string]string{"defaultMode": "dontAsk"}
-	case "doubao":
-		env["ANTHROPIC_BASE_URL"] = "https://ark.cn-beijing.volces.com/api/coding"
-		env["ANTHROPIC_DEFAULT_HAIKU_MODEL"] = selectedModel.ModelId
-		env["ANTHROPIC_DEFAULT_OPUS_MODEL"] = selectedModel.ModelId
-		env["ANTHROPIC_DEFAULT_SONNET_MODEL"] = selectedModel.ModelId
-		env["ANTHROPIC_MODEL"] = selectedModel.ModelId
-	case "minimax":
-		env["ANTHROPIC_BASE_URL"] = "https://api.minimaxi.com/anthropic"
-		env["ANTHROPIC_DEFAULT_HAIKU_MODEL"] = selectedModel.ModelId
-		env["ANTHROPIC_DEFAULT_OPUS_MODEL"] = selectedModel.ModelId
-		env["ANTHROPIC_DEFAULT_SONNET_MODEL"] = selectedModel.ModelId
-		env["ANTHROPIC_MODEL"] = selectedModel.ModelId
-		env["ANTHROPIC_SMALL_FAST_MODEL"] = selectedModel.ModelId
-		env["API_TIMEOUT_MS"] = "3000000"
-		env["CLAUDE_CODE_DISABLE_NONESSENTIAL_TRAFFIC"] = "1"
-	case "deepseek":
-		env["ANTHROPIC_BASE_URL"] = "https://api.deepseek.com/anthropic"
-		modelId := selectedModel.ModelId
-		if modelId == "" {
-			modelId = "deepseek-chat"
-		}
-		env["ANTHROPIC_DEFAULT_HAIKU_MODEL"] = modelId
-		env["ANTHROPIC_DEFAULT_OPUS_MODEL"] = modelId
-		env["ANTHROPIC_DEFAULT_SONNET_MODEL"] = modelId
-		env["ANTHROPIC_MODEL"] = modelId
-	case "gaccode":
-		env["ANTHROPIC_BASE_URL"] = "https://gaccode.com/claudecode"
-		modelId := selectedModel.ModelId
-		if modelId == "" {
-			modelId = "sonnet"
-		}
-		env["ANTHROPIC_DEFAULT_HAIKU_MODEL"] = modelId
-		env["ANTHROPIC_DEFAULT_OPUS_MODEL"] = modelId
-		env["ANTHROPIC_DEFAULT_SONNET_MODEL"] = modelId
-		env["ANTHROPIC_MODEL"] = modelId
-	default:
-		env["ANTHROPIC_BASE_URL"] = selectedModel.ModelUrl
-		env["ANTHROPIC_MODEL"] = selectedModel.ModelId
-	}
-	settings["env"] = env
-	data, err := json.MarshalIndent(settings, "", "  ")
+	provider := resolveProvider("claude", selectedModel)
+	if provider.Params.MaxOutputTokens > 0 {
+		env["CLAUDE_CODE_MAX_OUTPUT_TOKENS"] = strconv.Itoa(provider.Params.MaxOutputTokens)
+	}
+	if provider.Params.MaxThinkingTokens > 0 {
+		env["MAX_THINKING_TOKENS"] = strconv.Itoa(provider.Params.MaxThinkingTokens)
+	}
+	env["ANTHROPIC_BASE_URL"] = provider.BaseUrl
+	env["ANTHROPIC_MODEL"] = provider.ModelId
+	// Claude Code picks its sonnet/haiku/opus tiers from these; map our roles onto them
+	env["ANTHROPIC_DEFAULT_SONNET_MODEL"] = provider.ModelId
+	env["ANTHROPIC_DEFAULT_HAIKU_MODEL"] = provider.Roles.Fast
+	env["ANTHROPIC_DEFAULT_OPUS_MODEL"] = provider.Roles.Reasoning
+	// Provider-specific model aliases and tuning from the catalog
+	for k, v := range provider.Env {
+		env[k] = v
+	}
+	// Only touch the keys we manage; hooks, plugins, marketplaces etc. stay as they are
+	settings, err := readOrderedJSONFile(settingsPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	// Check if settings file needs update
-	if existingData, err := os.ReadFile(settingsPath); err == nil {
-		if bytes.Equal(existingData, data) {
-			// Settings file is already up to date, skip main settings write
-			// But still need to update .claude.json for API key responses
-			goto updateLegacyJson
+	envObj := settings.object("env")
+	// The token written by the last sync is the key AICoder approved in ~/.claude.json
+	var previousKey string
+	envObj.get("ANTHROPIC_AUTH_TOKEN", &previousKey)
+	for _, k := range claudeManagedEnvKeys() {
+		if v, ok := env[k]; ok {
+			if err := envObj.set(k, v); err != nil {
+				return nil, err
+			}
+		} else {
+			envObj.remove(k)
 		}
 	}
-	if err := os.WriteFile(settingsPath, data, 0644); err != nil {
-		return err
+	if err := settings.set("env", envObj); err != nil {
+		return nil, err
+	}
+	if err := applyClaudePermissions(settings, provider.Permissions); err != nil {
+		return nil, err
+	}
+	settingsData, err := settings.marshalIndent()
+	if err != nil {
+		return nil, err
 	}
-	// 2. Sync to ~/.claude.json for customApiKeyResponses
-updateLegacyJson:
-	var claudeJson map[string]interface{}
-	if jsonData, err := os.ReadFile(legacyPath); err == nil {
-		json.Unmarshal(jsonData, &claudeJson)
+	// 2. ~/.claude.json for customApiKeyResponses
+	claudeJson, err := readOrderedJSONFile(legacyPath)
+	if err != nil {
+		return nil, err
 	}
-	if claudeJson == nil {
-		claudeJson = make(map[string]interface{})
+	responses := claudeJson.object("customApiKeyResponses")
+	approved := []string{}
+	rejected := []string{}
+	responses.get("approved", &approved)
+	responses.get("rejected", &rejected)
+	if selectedModel.ApiKey != "" {
+		approved = replaceApprovedKey(approved, previousKey, selectedModel.ApiKey)
+		kept := []string{}
+		for _, k := range rejected {
+			if k != selectedModel.ApiKey {
+				kept = append(kept, k)
+			}
+		}
+		rejected = kept
 	}
-	claudeJson["customApiKeyResponses"] = map[string]interface{}{
-		"approved": []string{selectedModel.ApiKey},
-		"rejected": []string{},
+	if err := responses.set("approved", approved); err != nil {
+		return nil, err
 	}
-	data2, err := json.MarshalIndent(claudeJson, "", "  ")
+	if err := responses.set("rejected", rejected); err != nil {
+		return nil, err
+	}
+	if err := claudeJson.set("customApiKeyResponses", responses); err != nil {
+		return nil, err
+	}
+	legacyData, err := claudeJson.marshalIndent()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return []renderedConfigFile{
+		{Path: settingsPath, Format: "json", Content: settingsData, Fields: map[string]string{
+			"env.ANTHROPIC_AUTH_TOKEN":          "api_key",
+			"env.ANTHROPIC_BASE_URL":            "model_url",
+			"env.ANTHROPIC_MODEL":               "model_id",
+			"env.ANTHROPIC_DEFAULT_HAIKU_MODEL": "roles.fast",
+			"env.ANTHROPIC_DEFAULT_OPUS_MODEL":  "roles.reasoning",
+		}},
+		{Path: legacyPath, Format: "json", Content: legacyData},
+	}, nil
+}
+// replaceApprovedKey puts key in place of the one AICoder approved before, so keys the
+// user approved in Claude Code stay and switching providers does not pile up old keys.
+func replaceApprovedKey(approved []string, previous, key string) []string {
+	out := []string{}
+	added := false
+	for _, k := range approved {
+		if (previous != "" && k == previous) || k == key {
+			if !added {
+				out = append(out, key)
+				added = true
+			}
+			continue
+		}
+		out = append(out, k)
+	}
+	if !added {
+		out = append(out, key)
+	}
+	return out
+}
+// codexOwnedMark follows the top-level config.toml values AICoder writes from
+// ModelParams. Those keys are shared with the user, so only marked values are ever
+// removed again.
+const codexOwnedMark = "# set by AICoder"
+// setCodexOwnedInt writes n to a top-level key of config.toml with codexOwnedMark,
+// or for n == 0 removes a value AICoder wrote there before.
+func setCodexOwnedInt(doc *tomlDocument, key string, n int) {
+	if n > 0 {
+		doc.set(nil, key, strconv.Itoa(n)+" "+codexOwnedMark)
+	} else if raw, ok := doc.rawValue(nil, key); ok && strings.HasSuffix(raw, codexOwnedMark) {
+		doc.remove(nil, key)
+	}
+}
+// setCodexReasoningEffort writes model_reasoning_effort with codexOwnedMark. An effort
+// chosen for the model in AICoder replaces any value; the catalog default only replaces
+// one AICoder wrote, so an effort the user set in config.toml stays. Without an effort,
+// a value AICoder wrote for the previous provider is removed.
+func setCodexReasoningEffort(doc *tomlDocument, effort string, chosen bool) {
+	raw, ok := doc.rawValue(nil, "model_reasoning_effort")
+	owned := ok && strings.HasSuffix(raw, codexOwnedMark)
+	switch {
+	case effort == "":
+		if owned {
+			doc.remove(nil, "model_reasoning_effort")
+		}
+	case chosen || !ok || owned:
+		doc.set(nil, "model_reasoning_effort", tomlString(effort)+" "+codexOwnedMark)
+	}
+}
+// codexParamKeys are the [model_providers.<id>] keys written from ModelParams.
+var codexParamKeys = []string{"request_max_retries", "stream_max_retries", "stream_idle_timeout_ms"}
+// codexManagedProviderOptions lists the [model_providers.<id>] keys that come from
+// catalog provider options, so options of a previous catalog entry can be removed.
+func codexManagedProviderOptions() []string {
+	seen := make(map[string]bool)
+	for _, k := range codexParamKeys {
+		seen[k] = true
+	}
+	var keys []string
+	for _, p := range currentCatalog().Providers {
+		for k := range p.Tools["codex"].ProviderOptions {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+// backupUnparseableCodexConfig moves a config.toml we cannot parse aside so the
+// user keeps it, and the next sync starts from an empty document.
+func (a *App) backupUnparseableCodexConfig(configPath string) {
+	_, err := readTomlFile(configPath)
+	if err == nil {
+		return
 	}
-	// Check if legacy file needs update
-	if existingData, err := os.ReadFile(legacyPath); err == nil {
-		if bytes.Equal(existingData, data2) {
-			return nil
+	backup := configPath + ".bak-" + time.Now().Format("20060102150405")
+	if renameErr := os.Rename(configPath, backup); renameErr == nil {
+		a.log(fmt.Sprintf("Codex config.toml could not be parsed (%v), saved as %s", err, backup))
+	}
+}
+// removeCodexManagedSettings undoes what syncToCodexSettings wrote, for Original mode.
+func (a *App) removeCodexManagedSettings() error {
+	dir, authPath := a.getCodexConfigPaths()
+	configPath := filepath.Join(dir, "config.toml")
+	if doc, err := readTomlFile(configPath); err == nil {
+		// Only drop the selection if it points at a provider table, i.e. not a built-in provider
+		if id, ok := doc.get(nil, "model_provider"); ok {
+			if name, isString := id.(string); isString && doc.hasTable([]string{"model_providers", name}) {
+				doc.remove(nil, "model_provider")
+				doc.remove(nil, "model")
+				doc.remove(nil, "review_model")
+				setCodexOwnedInt(doc, "model_context_window", 0)
+				setCodexOwnedInt(doc, "model_max_output_tokens", 0)
+				setCodexReasoningEffort(doc, "", false)
+				// An API key preference would keep the official login from being used
+				if method, _ := doc.get(nil, "preferred_auth_method"); method == "apikey" {
+					doc.remove(nil, "preferred_auth_method")
+				}
+				if err := writePrivateFile(configPath, []byte(doc.String())); err != nil {
+					return err
+				}
+			}
 		}
 	}
-	return os.WriteFile(legacyPath, data2, 0644)
+	// auth.json holding nothing but our API key goes; a ChatGPT login is kept
+	if auth, err := readOrderedJSONFile(authPath); err == nil && auth.len() == 1 && auth.has("OPENAI_API_KEY") {
+		os.Remove(authPath)
+	}
+	a.log("Removed AICoder provider settings from Codex configuration")
+	return nil
 }
 func (a *App) syncToCodexSettings(config AppConfig) error {
 	var selectedModel *ModelConfig
@@ -524,238 +759,83 @@ func (a *App) syncToCodexSettings(config AppConfig) error {
 	if selectedModel == nil {
 		return fmt.Errorf("selected codex model not found")
 	}
-	dir, authPath := a.getCodexConfigPaths()
+	dir, _ := a.getCodexConfigPaths()
 	if strings.ToLower(selectedModel.ModelName) == "original" {
-		a.clearCodexConfig()
-		return nil
+		return a.removeCodexManagedSettings()
 	}
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	a.backupUnparseableCodexConfig(filepath.Join(dir, "config.toml"))
+	files, err := a.renderCodexSettings(selectedModel)
+	if err != nil {
 		return err
 	}
-	// Create auth.json
-	authData := map[string]string{
-		"OPENAI_API_KEY": selectedModel.ApiKey,
-	}
-	authJson, err := json.MarshalIndent(authData, "", "  ")
+	return writeRenderedFiles(files)
+}
+// renderCodexSettings merges the selected provider into auth.json and config.toml.
+func (a *App) renderCodexSettings(selectedModel *ModelConfig) ([]renderedConfigFile, error) {
+	dir, authPath := a.getCodexConfigPaths()
+	// auth.json: keep anything else the Codex CLI stored there
+	auth, err := readOrderedJSONFile(authPath)
 	if err != nil {
-		return err
+		auth = newOrderedJSON()
 	}
-	// Check if auth.json needs update
-	if existingData, err := os.ReadFile(authPath); err == nil {
-		if bytes.Equal(existingData, authJson) {
-			// Auth file is already up to date, skip writing
-			goto writeConfigToml
-		}
+	if err := auth.set("OPENAI_API_KEY", selectedModel.ApiKey); err != nil {
+		return nil, err
 	}
-	if err := os.WriteFile(authPath, authJson, 0644); err != nil {
-		return err
+	authData, err := auth.marshalIndent()
+	if err != nil {
+		return nil, err
 	}
-	// Create config.toml
-writeConfigToml:
+	// config.toml: we own model, model_provider and our provider table;
+	// MCP servers, profiles, project trust and everything else stay untouched.
 	configPath := filepath.Join(dir, "config.toml")
-	baseUrl := selectedModel.ModelUrl
-	var configToml string
-	if strings.ToLower(selectedModel.ModelName) == "aigocode" {
-		if baseUrl == "" {
-			baseUrl = "https://api.aigocode.com/openai"
-		}
-		modelId := selectedModel.ModelId
-		if modelId == "" {
-			modelId = "gpt-5.2-codex"
-		}
-		configToml = fmt.Sprintf(`model_provider = "aigocode"
-model = "%s"
-model_reasoning_effort = "high"
-disable_response_storage = true
-preferred_auth_method = "apikey"
-[model_providers.aigocode]
-name = "aigocode"
-base_url = "%s"
-wire_api = "responses"
-requires_openai_auth = true
-`, modelId, baseUrl)
-	} else if strings.ToLower(selectedModel.ModelName) == "deepseek" {
-		if baseUrl == "" {
-			baseUrl = "https://api.deepseek.com/v1"
-		}
-		modelId := selectedModel.ModelId
-		if modelId == "" {
-			modelId = "deepseek-chat"
-		}
-		configToml = fmt.Sprintf(`model_provider = "deepseek"
-model = "%s"
-model_reasoning_effort = "xhigh"
-disable_response_storage = true
-preferred_auth_method = "apikey"
-[model_providers.deepseek]
-name = "deepseek"
-base_url = "%s"
-wire_api = "chat"
-request_max_retries = 4
-stream_max_retries = 8
-stream_idle_timeout_ms = 120000
-`, modelId, baseUrl)
-	} else if strings.ToLower(selectedModel.ModelName) == "glm" {
-		if baseUrl == "" {
-			baseUrl = "https://open.bigmodel.cn/api/paas/v4"
-		}
-		modelId := selectedModel.ModelId
-		if modelId == "" {
-			modelId = "glm-4.7"
-		}
-		configToml = fmt.Sprintf(`model_provider = "glm"
-model = "%s"
-model_reasoning_effort = "xhigh"
-disable_response_storage = true
-preferred_auth_method = "apikey"
-[model_providers.glm]
-name = "glm"
-base_url = "%s"
-wire_api = "chat"
-request_max_retries = 4
-stream_max_retries = 8
-stream_idle_timeout_ms = 120000
-`, modelId, baseUrl)
-	} else if strings.ToLower(selectedModel.ModelName) == "doubao" {
-		if baseUrl == "" {
-			baseUrl = "https://ark.cn-beijing.volces.com/api/coding/v3"
-		}
-		modelId := selectedModel.ModelId
-		if modelId == "" {
-			modelId = "doubao-seed-code-preview-latest"
-		}
-		configToml = fmt.Sprintf(`model_provider = "doubao"
-model = "%s"
-model_reasoning_effort = "xhigh"
-disable_response_storage = true
-preferred_auth_method = "apikey"
-[model_providers.doubao]
-name = "doubao"
-base_url = "%s"
-wire_api = "chat"
-request_max_retries = 4
-stream_max_retries = 8
-stream_idle_timeout_ms = 120000
-`, modelId, baseUrl)
-	} else if strings.ToLower(selectedModel.ModelName) == "kimi" {
-		if baseUrl == "" {
-			baseUrl = "https://api.kimi.com/coding/v1"
-		}
-		modelId := selectedModel.ModelId
-		if modelId == "" {
-			modelId = "kimi-for-coding"
-		}
-		configToml = fmt.Sprintf(`model_provider = "kimi"
-model = "%s"
-model_reasoning_effort = "xhigh"
-disable_response_storage = true
-preferred_auth_method = "apikey"
-[model_providers.kimi]
-name = "kimi"
-base_url = "%s"
-wire_api = "chat"
-request_max_retries = 4
-stream_max_retries = 8
-stream_idle_timeout_ms = 120000
-`, modelId, baseUrl)
-	} else if strings.ToLower(selectedModel.ModelName) == "minimax" {
-		if baseUrl == "" {
-			baseUrl = "https://api.minimaxi.com/v1"
-		}
-		modelId := selectedModel.ModelId
-		if modelId == "" {
-			modelId = "MiniMax-M2.1"
-		}
-		configToml = fmt.Sprintf(`model_provider = "minimax"
-model = "%s"
-model_reasoning_effort = "xhigh"
-disable_response_storage = true
-preferred_auth_method = "apikey"
-[model_providers.minimax]
-name = "minimax"
-base_url = "%s"
-wire_api = "chat"
-request_max_retries = 4
-stream_max_retries = 8
-stream_idle_timeout_ms = 120000
-`, modelId, baseUrl)
-	} else if strings.ToLower(selectedModel.ModelName) == "coderelay" {
-		if baseUrl == "" {
-			baseUrl = "https://api.code-relay.com/v1"
-		}
-		modelId := selectedModel.ModelId
-		if modelId == "" {
-			modelId = "gpt-5.2-codex"
-		}
-		configToml = fmt.Sprintf(`model_provider = "coderelay"
-model = "%s"
-model_reasoning_effort = "xhigh"
-disable_response_storage = true
-preferred_auth_method = "apikey"
-[model_providers.coderelay]
-name = "coderelay"
-base_url = "%s"
-wire_api = "responses"
-`, modelId, baseUrl)
-	} else {
-		if baseUrl == "" {
-			baseUrl = "https://api.aicodemirror.com/api/codex/backend-api/codex"
-		}
-		modelId := selectedModel.ModelId
-		if modelId == "" {
-			modelId = "gpt-5.2-codex"
-		}
-		configToml = fmt.Sprintf(`model_provider = "aicodemirror"
-model = "%s"
-model_reasoning_effort = "xhigh"
-disable_response_storage = true
-preferred_auth_method = "apikey"
-[model_providers.aicodemirror]
-name = "aicodemirror"
-base_url = "%s"
-wire_api = "responses"
-`, modelId, baseUrl)
-	}
-	if selectedModel.IsCustom || (strings.ToLower(selectedModel.ModelName) != "aigocode" &&
-		strings.ToLower(selectedModel.ModelName) != "deepseek" &&
-		strings.ToLower(selectedModel.ModelName) != "glm" &&
-		strings.ToLower(selectedModel.ModelName) != "doubao" &&
-		strings.ToLower(selectedModel.ModelName) != "kimi" &&
-		strings.ToLower(selectedModel.ModelName) != "minimax" &&
-		strings.ToLower(selectedModel.ModelName) != "coderelay" &&
-		strings.ToLower(selectedModel.ModelName) != "aicodemirror") {
-		// --- CUSTOM OR OTHER PROVIDERS ---
-		wireApi := selectedModel.WireApi
-		if wireApi == "" {
-			wireApi = "chat"
-		}
-		providerName := strings.ToLower(selectedModel.ModelName)
-		if providerName == "" || providerName == "custom" {
-			providerName = "custom"
-		}
-		modelId := selectedModel.ModelId
-		if modelId == "" {
-			modelId = "gpt-5.2-codex"
-		}
-		configToml = fmt.Sprintf(`model_provider = "%s"
-model = "%s"
-model_reasoning_effort = "high"
-disable_response_storage = true
-preferred_auth_method = "apikey"
-[model_providers.%s]
-name = "%s"
-base_url = "%s"
-wire_api = "%s"
-`, providerName, modelId, providerName, providerName, baseUrl, wireApi)
-	}
-	configBytes := []byte(configToml)
-	// Check if config.toml needs update
-	if existingData, err := os.ReadFile(configPath); err == nil {
-		if bytes.Equal(existingData, configBytes) {
-			return nil
-		}
-	}
-	return os.WriteFile(configPath, configBytes, 0644)
+	doc, err := readTomlFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", configPath, err)
+	}
+	provider := resolveProvider("codex", selectedModel)
+	doc.set(nil, "model_provider", tomlString(provider.Id))
+	doc.set(nil, "model", tomlString(provider.ModelId))
+	// /review would otherwise ask the provider for OpenAI's default review model
+	doc.set(nil, "review_model", tomlString(provider.Roles.Reasoning))
+	setCodexReasoningEffort(doc, provider.Params.ReasoningEffort, selectedModel.Params.ReasoningEffort != "")
+	setOrRemove := func(table []string, key string, n int) {
+		if n > 0 {
+			doc.set(table, key, strconv.Itoa(n))
+		} else {
+			doc.remove(table, key)
+		}
+	}
+	setCodexOwnedInt(doc, "model_context_window", provider.Params.ContextWindow)
+	setCodexOwnedInt(doc, "model_max_output_tokens", provider.Params.MaxOutputTokens)
+	doc.setIfAbsent(nil, "disable_response_storage", "true")
+	doc.setIfAbsent(nil, "preferred_auth_method", tomlString("apikey"))
+	table := []string{"model_providers", provider.Id}
+	doc.set(table, "name", tomlString(provider.Id))
+	doc.set(table, "base_url", tomlString(provider.BaseUrl))
+	doc.set(table, "wire_api", tomlString(provider.WireApi))
+	setOrRemove(table, "request_max_retries", provider.Params.RequestMaxRetries)
+	setOrRemove(table, "stream_max_retries", provider.Params.StreamMaxRetries)
+	setOrRemove(table, "stream_idle_timeout_ms", provider.Params.StreamIdleTimeoutMs)
+	// Other provider-specific options from the catalog
+	for _, k := range codexManagedProviderOptions() {
+		if v, ok := provider.ProviderOptions[k]; ok {
+			doc.set(table, k, tomlLiteral(v))
+		} else {
+			doc.remove(table, k)
+		}
+	}
+	tablePath := strings.Join(table, ".")
+	return []renderedConfigFile{
+		{Path: authPath, Format: "json", Content: authData, Fields: map[string]string{
+			"OPENAI_API_KEY": "api_key",
+		}},
+		{Path: configPath, Format: "toml", Content: []byte(doc.String()), Fields: map[string]string{
+			"model":                 "model_id",
+			"review_model":          "roles.reasoning",
+			tablePath + ".base_url": "model_url",
+			tablePath + ".wire_api": "wire_api",
+		}},
+	}, nil
 }
 func (a *App) syncToOpencodeSettings(config AppConfig) error {
 	var selectedModel *ModelConfig
@@ -768,81 +848,68 @@ func (a *App) syncToOpencodeSettings(config AppConfig) error {
 	if selectedModel == nil {
 		return fmt.Errorf("selected opencode model not found")
 	}
-	dir, configPath := a.getOpencodeConfigPaths()
 	if strings.ToLower(selectedModel.ModelName) == "original" {
 		a.clearOpencodeConfig()
 		return nil
 	}
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	files, err := a.renderOpencodeSettings(selectedModel)
+	if err != nil {
 		return err
 	}
-	baseUrl := selectedModel.ModelUrl
-	modelId := selectedModel.ModelId
+	return writeRenderedFiles(files)
+}
+func (a *App) renderOpencodeSettings(selectedModel *ModelConfig) ([]renderedConfigFile, error) {
+	_, configPath := a.getOpencodeConfigPaths()
+	provider := resolveProvider("opencode", selectedModel)
+	baseUrl := provider.BaseUrl
+	modelId := provider.ModelId
 	providerName := selectedModel.ModelName
-	// Fallback logic for Opencode (align with Codex providers)
-	if modelId == "" {
-		switch strings.ToLower(providerName) {
-		case "deepseek":
-			modelId = "deepseek-chat"
-			if baseUrl == "" {
-				baseUrl = "https://api.deepseek.com/v1"
-			}
-		case "glm":
-			modelId = "glm-4.7"
-			if baseUrl == "" {
-				baseUrl = "https://open.bigmodel.cn/api/paas/v4"
-			}
-		case "doubao":
-			modelId = "doubao-seed-code-preview-latest"
-			if baseUrl == "" {
-				baseUrl = "https://ark.cn-beijing.volces.com/api/coding/v3"
-			}
-		case "kimi":
-			modelId = "kimi-for-coding"
-			if baseUrl == "" {
-				baseUrl = "https://api.kimi.com/coding/v1"
-			}
-		case "minimax":
-			modelId = "MiniMax-M2.1"
-			if baseUrl == "" {
-				baseUrl = "https://api.minimaxi.com/v1"
-			}
-		default:
-			modelId = "opencode-1.0"
-			if baseUrl == "" {
-				baseUrl = "https://api.aicodemirror.com/api/opencode/v1"
-			}
+	// Declare the main and the fast model; small_model handles titles and summaries
+	limit := map[string]interface{}{}
+	if provider.Params.ContextWindow > 0 {
+		limit["context"] = provider.Params.ContextWindow
+	}
+	if provider.Params.MaxOutputTokens > 0 {
+		limit["output"] = provider.Params.MaxOutputTokens
+	}
+	models := map[string]interface{}{}
+	for _, id := range []string{modelId, provider.Roles.Fast} {
+		model := map[string]interface{}{"name": id}
+		if len(limit) > 0 {
+			model["limit"] = limit
 		}
+		models[id] = model
+	}
+	options := map[string]interface{}{
+		"baseURL": baseUrl,
+		"apiKey":  selectedModel.ApiKey,
+	}
+	if provider.Params.MaxOutputTokens > 0 {
+		options["maxTokens"] = provider.Params.MaxOutputTokens
 	}
 	// Build the JSON structure
 	opencodeJson := map[string]interface{}{
-		"$schema": "https://opencode.ai/config.json",
+		"$schema":     "https://opencode.ai/config.json",
+		"small_model": "myprovider/" + provider.Roles.Fast,
 		"provider": map[string]interface{}{
 			"myprovider": map[string]interface{}{
 				"npm":  "@ai-sdk/openai-compatible",
 				"name": providerName,
-				"options": map[string]interface{}{
-					"baseURL":   baseUrl,
-					"apiKey":    selectedModel.ApiKey,
-					"maxTokens": 8192,
-				},
-				"models": map[string]interface{}{
-					modelId: map[string]interface{}{
-						"name": modelId,
-						"limit": map[string]interface{}{
-							"context": 8192,
-							"output":  8192,
-						},
-					},
-				},
+				"options": options,
+				"models":  models,
 			},
 		},
 	}
 	data, err := json.MarshalIndent(opencodeJson, "", "  ")
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return os.WriteFile(configPath, data, 0644)
+	return []renderedConfigFile{
+		{Path: configPath, Format: "json", Content: data, Fields: map[string]string{
+			"provider.myprovider.options.baseURL": "model_url",
+			"provider.myprovider.options.apiKey":  "api_key",
+		}},
+	}, nil
 }
 func (a *App) syncToGeminiSettings(config AppConfig) error {
 	var selectedModel *ModelConfig
@@ -919,62 +986,37 @@ func (a *App) syncToIFlowSettings(config AppConfig) error {
 	if selectedModel == nil {
 		return fmt.Errorf("selected iflow model not found")
 	}
-	dir, configPath := a.getIFlowConfigPaths()
 	if strings.ToLower(selectedModel.ModelName) == "original" {
 		a.clearIFlowConfig()
 		return nil
 	}
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	files, err := a.renderIFlowSettings(selectedModel)
+	if err != nil {
 		return err
 	}
-	// Prepare defaults
-	baseUrl := selectedModel.ModelUrl
-	modelId := selectedModel.ModelId
-	providerName := strings.ToLower(selectedModel.ModelName)
-	// Fallback logic for iFlow (align with Codex providers)
-	if modelId == "" {
-		switch providerName {
-		case "deepseek":
-			modelId = "deepseek-chat"
-			if baseUrl == "" {
-				baseUrl = "https://api.deepseek.com/v1"
-			}
-		case "glm":
-			modelId = "glm-4.7"
-			if baseUrl == "" {
-				baseUrl = "https://open.bigmodel.cn/api/paas/v4"
-			}
-		case "doubao":
-			modelId = "doubao-seed-code-preview-latest"
-			if baseUrl == "" {
-				baseUrl = "https://ark.cn-beijing.volces.com/api/coding/v3"
-			}
-		case "kimi":
-			modelId = "kimi-for-coding"
-			if baseUrl == "" {
-				baseUrl = "https://api.kimi.com/coding/v1"
-			}
-		case "minimax":
-			modelId = "MiniMax-M2.1"
-			if baseUrl == "" {
-				baseUrl = "https://api.minimaxi.com/v1"
-			}
-		default:
-			modelId = "gpt-4o"
-		}
-	}
+	return writeRenderedFiles(files)
+}
+func (a *App) renderIFlowSettings(selectedModel *ModelConfig) ([]renderedConfigFile, error) {
+	_, configPath := a.getIFlowConfigPaths()
+	provider := resolveProvider("iflow", selectedModel)
 	// Build the JSON structure for settings.json
 	settings := map[string]string{
 		"selectedAuthType": "openai-compatible",
 		"apiKey":           selectedModel.ApiKey,
-		"baseUrl":          baseUrl,
-		"modelName":        modelId,
+		"baseUrl":          provider.BaseUrl,
+		"modelName":        provider.ModelId,
 	}
 	data, err := json.MarshalIndent(settings, "", "  ")
 	if err != nil {
-		return err
-	}
-	return os.WriteFile(configPath, data, 0644)
+		return nil, err
+	}
+	return []renderedConfigFile{
+		{Path: configPath, Format: "json", Content: data, Fields: map[string]string{
+			"apiKey":    "api_key",
+			"baseUrl":   "model_url",
+			"modelName": "model_id",
+		}},
+	}, nil
 }
 func (a *App) syncToKiloSettings(config AppConfig) error {
 	var selectedModel *ModelConfig
@@ -987,14 +1029,18 @@ func (a *App) syncToKiloSettings(config AppConfig) error {
 	if selectedModel == nil {
 		return fmt.Errorf("selected kilo model not found")
 	}
-	dir, configPath := a.getKiloConfigPaths()
 	if strings.ToLower(selectedModel.ModelName) == "original" {
 		a.clearKiloConfig()
 		return nil
 	}
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	files, err := a.renderKiloSettings(selectedModel)
+	if err != nil {
 		return err
 	}
+	return writeRenderedFiles(files)
+}
+func (a *App) renderKiloSettings(selectedModel *ModelConfig) ([]renderedConfigFile, error) {
+	_, configPath := a.getKiloConfigPaths()
 	// Read existing config if it exists
 	var kiloConfig map[string]interface{}
 	existingData, err := os.ReadFile(configPath)
@@ -1009,62 +1055,39 @@ func (a *App) syncToKiloSettings(config AppConfig) error {
 		kiloConfig = make(map[string]interface{})
 	}
 	// Prepare provider configuration
-	baseUrl := selectedModel.ModelUrl
-	modelId := selectedModel.ModelId
-	providerName := strings.ToLower(selectedModel.ModelName)
-	// Fallback logic for common providers
-	if modelId == "" {
-		switch providerName {
-		case "deepseek":
-			modelId = "deepseek-chat"
-			if baseUrl == "" {
-				baseUrl = "https://api.deepseek.com/v1"
-			}
-		case "glm":
-			modelId = "glm-4.7"
-			if baseUrl == "" {
-				baseUrl = "https://open.bigmodel.cn/api/paas/v4"
-			}
-		case "doubao":
-			modelId = "doubao-seed-code-preview-latest"
-			if baseUrl == "" {
-				baseUrl = "https://ark.cn-beijing.volces.com/api/coding/v3"
-			}
-		case "kimi":
-			modelId = "kimi-for-coding"
-			if baseUrl == "" {
-				baseUrl = "https://api.kimi.com/coding/v1"
-			}
-		case "minimax":
-			modelId = "MiniMax-M2.1"
-			if baseUrl == "" {
-				baseUrl = "https://api.minimaxi.com/v1"
-			}
-		case "xiaomi":
-			modelId = "mimo-v2-flash"
-			if baseUrl == "" {
-				baseUrl = "https://api.xiaomimimo.com/v1"
-			}
-		default:
-			modelId = "gpt-4o"
-		}
-	}
+	resolved := resolveProvider("kilo", selectedModel)
 	// Build provider object
 	provider := map[string]interface{}{
 		"id":            "default",
 		"provider":      "openai",
 		"openAiApiKey":  selectedModel.ApiKey,
-		"openAiModelId": modelId,
-		"openAiBaseUrl": baseUrl,
+		"openAiModelId": resolved.ModelId,
+		"openAiBaseUrl": resolved.BaseUrl,
+	}
+	// Custom model info carries the limits for OpenAI-compatible providers
+	if resolved.Params.ContextWindow > 0 || resolved.Params.MaxOutputTokens > 0 {
+		info := map[string]interface{}{"supportsPromptCache": false}
+		if resolved.Params.ContextWindow > 0 {
+			info["contextWindow"] = resolved.Params.ContextWindow
+		}
+		if resolved.Params.MaxOutputTokens > 0 {
+			info["maxTokens"] = resolved.Params.MaxOutputTokens
+		}
+		provider["openAiCustomModelInfo"] = info
 	}
 	// Update providers array
 	kiloConfig["providers"] = []interface{}{provider}
-	// Write config file
 	data, err := json.MarshalIndent(kiloConfig, "", "  ")
 	if err != nil {
-		return err
-	}
-	return os.WriteFile(configPath, data, 0644)
+		return nil, err
+	}
+	return []renderedConfigFile{
+		{Path: configPath, Format: "json", Content: data, Fields: map[string]string{
+			"providers.0.openAiApiKey":  "api_key",
+			"providers.0.openAiModelId": "model_id",
+			"providers.0.openAiBaseUrl": "model_url",
+		}},
+	}, nil
 }
 
 func (a *App) syncToKodeSettings(config AppConfig) error {
@@ -1079,239 +1102,191 @@ func (a *App) syncToKodeSettings(config AppConfig) error {
 	if selectedModel == nil {
 		return fmt.Errorf("selected kode model not found")
 	}
-
-	home, err := os.UserHomeDir()
+	files, err := a.renderKodeSettings(selectedModel)
 	if err != nil {
 		return err
 	}
+	return writeRenderedFiles(files)
+}
+func (a *App) renderKodeSettings(selectedModel *ModelConfig) ([]renderedConfigFile, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
 
 	kodeConfigPath := filepath.Join(home, ".kode.json")
+	provider := resolveProvider("kode", selectedModel)
+	modelId := provider.ModelId
+
+	// Keep the creation times of existing profiles, so an unchanged selection
+	// renders the same file
+	createdAt := make(map[string]int64)
+	var existing struct {
+		ModelProfiles []struct {
+			ModelName string `json:"modelName"`
+			CreatedAt int64  `json:"createdAt"`
+		} `json:"modelProfiles"`
+	}
+	if data, err := os.ReadFile(kodeConfigPath); err == nil && json.Unmarshal(data, &existing) == nil {
+		for _, p := range existing.ModelProfiles {
+			if p.CreatedAt > 0 {
+				createdAt[p.ModelName] = p.CreatedAt
+			}
+		}
+	}
 
-	// Create model profile
-	modelProfile := map[string]interface{}{
-		"name":          fmt.Sprintf("Custom OpenAI-Compatible API %s", selectedModel.ModelId),
-		"provider":      "custom-openai",
-		"modelName":     selectedModel.ModelId,
-		"baseURL":       selectedModel.ModelUrl,
-		"apiKey":        selectedModel.ApiKey,
-		"maxTokens":     4096,
-		"contextLength": 128000,
-		"createdAt":     time.Now().UnixMilli(),
-		"isActive":      true,
+	// One profile per distinct model, main first; the pointers select a profile per role
+	var modelProfiles []interface{}
+	seen := make(map[string]bool)
+	for _, id := range []string{modelId, provider.Roles.Fast, provider.Roles.Reasoning, provider.Roles.Compact} {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		created, ok := createdAt[id]
+		if !ok {
+			created = time.Now().UnixMilli()
+		}
+		modelProfiles = append(modelProfiles, map[string]interface{}{
+			"name":          fmt.Sprintf("Custom OpenAI-Compatible API %s", id),
+			"provider":      "custom-openai",
+			"modelName":     id,
+			"baseURL":       provider.BaseUrl,
+			"apiKey":        selectedModel.ApiKey,
+			"maxTokens":     provider.Params.MaxOutputTokens,
+			"contextLength": provider.Params.ContextWindow,
+			"createdAt":     created,
+			"isActive":      true,
+		})
 	}
 
 	// Create full config structure
 	kodeConfig := map[string]interface{}{
-		"modelProfiles": []interface{}{modelProfile},
+		"modelProfiles": modelProfiles,
 		"modelPointers": map[string]string{
-			"main":    selectedModel.ModelId,
-			"task":    selectedModel.ModelId,
-			"compact": selectedModel.ModelId,
-			"quick":   selectedModel.ModelId,
+			"main":      modelId,
+			"task":      modelId,
+			"reasoning": provider.Roles.Reasoning,
+			"compact":   provider.Roles.Compact,
+			"quick":     provider.Roles.Fast,
 		},
-		"defaultModelName":        selectedModel.ModelId,
-		"hasCompletedOnboarding":  true,
-		"lastOnboardingVersion":   "2.0.3",
+		"defaultModelName":       modelId,
+		"hasCompletedOnboarding": true,
+		"lastOnboardingVersion":  "2.0.3",
 	}
 
-	// Write config file
 	data, err := json.MarshalIndent(kodeConfig, "", "  ")
 	if err != nil {
-		return err
-	}
-
-	return os.WriteFile(kodeConfigPath, data, 0644)
+		return nil, err
+	}
+	return []renderedConfigFile{
+		{Path: kodeConfigPath, Format: "json", Content: data, Fields: map[string]string{
+			"modelProfiles.0.apiKey":    "api_key",
+			"modelProfiles.0.baseURL":   "model_url",
+			"modelProfiles.0.modelName": "model_id",
+			"modelPointers.quick":       "roles.fast",
+			"modelPointers.reasoning":   "roles.reasoning",
+			"modelPointers.compact":     "roles.compact",
+		}},
+	}, nil
 }
-func (a *App) syncToCodeBuddySettings(config AppConfig, projectPath string) error {
-	if projectPath == "" {
-		projectPath = a.GetCurrentProjectPath()
-	}
-	if projectPath == "" {
-		return nil
+// chatCompletionsUrl turns an OpenAI-style base URL into its chat completions endpoint.
+func chatCompletionsUrl(baseUrl string) string {
+	if baseUrl == "" || strings.HasSuffix(baseUrl, "/chat/completions") {
+		return baseUrl
 	}
-	cbDir := filepath.Join(projectPath, ".codebuddy")
-	if err := os.MkdirAll(cbDir, 0755); err != nil {
-		return err
+	if strings.HasSuffix(baseUrl, "/") {
+		return baseUrl + "chat/completions"
 	}
-	cbFilePath := filepath.Join(cbDir, "models.json")
-	var cbModels []CodeBuddyModel
+	return baseUrl + "/chat/completions"
+}
+// buildModelsFile renders the models.json used by CodeBuddy and Qoder for the selected model
+func buildModelsFile(tool string, toolCfg ToolConfig) CodeBuddyFileConfig {
+	var models []CodeBuddyModel
 	var availableModelIds []string
-	for _, m := range config.CodeBuddy.Models {
+	for _, m := range toolCfg.Models {
 		// Only sync the currently selected model
-		if m.ModelName != config.CodeBuddy.CurrentModel {
+		if m.ModelName != toolCfg.CurrentModel {
 			continue
 		}
 		if strings.ToLower(m.ModelName) == "original" {
 			continue
 		}
-		vendor := strings.ToLower(m.ModelName)
-		idStr := m.ModelId
+		provider := resolveProvider(tool, &m)
+		vendor := provider.Id
+		idStr := provider.ModelId
 		if idStr == "" {
-			switch vendor {
-			case "deepseek":
-				idStr = "deepseek-chat"
-			case "glm":
-				idStr = "glm-4.7"
-			case "doubao":
-				idStr = "doubao-seed-code-preview-latest"
-			case "kimi":
-				idStr = "kimi-for-coding"
-			case "minimax":
-				idStr = "MiniMax-M2.1"
-			default:
-				idStr = vendor + "-model"
-			}
+			idStr = vendor + "-model"
 		}
 		modelIds := strings.Split(idStr, ",")
-		modelUrl := m.ModelUrl
-		if modelUrl != "" && !strings.HasSuffix(modelUrl, "/chat/completions") {
-			if strings.HasSuffix(modelUrl, "/") {
-				modelUrl += "chat/completions"
-			} else {
-				modelUrl += "/chat/completions"
-			}
-		}
+		modelUrl := chatCompletionsUrl(provider.BaseUrl)
 		for _, id := range modelIds {
 			id = strings.TrimSpace(id)
 			if id == "" {
 				continue
 			}
 			availableModelIds = append(availableModelIds, id)
-			cbModels = append(cbModels, CodeBuddyModel{
+			models = append(models, CodeBuddyModel{
 				Id:               id,
 				Name:             id,
 				Vendor:           vendor,
 				ApiKey:           m.ApiKey,
-				MaxInputTokens:   200000,
-				MaxOutputTokens:  8192,
+				MaxInputTokens:   provider.Params.ContextWindow,
+				MaxOutputTokens:  provider.Params.MaxOutputTokens,
 				Url:              modelUrl,
 				SupportsToolCall: true,
 				SupportsImages:   true,
 			})
 		}
 	}
-	cbConfig := CodeBuddyFileConfig{
-		Models:          cbModels,
+	return CodeBuddyFileConfig{
+		Models:          models,
 		AvailableModels: availableModelIds,
 	}
-	data, err := json.MarshalIndent(cbConfig, "", "  ")
+}
+// renderModelsFile renders .codebuddy/models.json or .qoder/models.json in a project.
+func renderModelsFile(tool string, toolCfg ToolConfig, projectPath string) ([]renderedConfigFile, error) {
+	data, err := json.MarshalIndent(buildModelsFile(tool, toolCfg), "", "  ")
 	if err != nil {
-		return err
-	}
-	return os.WriteFile(cbFilePath, data, 0644)
+		return nil, err
+	}
+	return []renderedConfigFile{
+		{Path: filepath.Join(projectPath, "."+tool, "models.json"), Format: "json", Content: data, Fields: map[string]string{
+			"Models.0.apiKey": "api_key",
+			"Models.0.id":     "model_id",
+			"Models.0.url":    "model_url",
+		}},
+	}, nil
 }
-func (a *App) syncToQoderSettings(config AppConfig, projectPath string) error {
+func (a *App) syncToCodeBuddySettings(config AppConfig, projectPath string) error {
 	if projectPath == "" {
 		projectPath = a.GetCurrentProjectPath()
 	}
 	if projectPath == "" {
 		return nil
 	}
-	qDir := filepath.Join(projectPath, ".qoder")
-	if err := os.MkdirAll(qDir, 0755); err != nil {
+	files, err := renderModelsFile("codebuddy", config.CodeBuddy, projectPath)
+	if err != nil {
 		return err
 	}
-	qFilePath := filepath.Join(qDir, "models.json")
-	var qModels []CodeBuddyModel
-	var availableModelIds []string
-	for _, m := range config.Qoder.Models {
-		// Only sync the currently selected model
-		if m.ModelName != config.Qoder.CurrentModel {
-			continue
-		}
-		if strings.ToLower(m.ModelName) == "original" {
-			continue
-		}
-		vendor := strings.ToLower(m.ModelName)
-		idStr := m.ModelId
-		if idStr == "" {
-			switch vendor {
-			case "deepseek":
-				idStr = "deepseek-chat"
-			case "glm":
-				idStr = "glm-4.7"
-			case "doubao":
-				idStr = "doubao-seed-code-preview-latest"
-			case "kimi":
-				idStr = "kimi-for-coding"
-			case "minimax":
-				idStr = "MiniMax-M2.1"
-			default:
-				idStr = vendor + "-model"
-			}
-		}
-		modelIds := strings.Split(idStr, ",")
-		modelUrl := m.ModelUrl
-		if modelUrl != "" && !strings.HasSuffix(modelUrl, "/chat/completions") {
-			if strings.HasSuffix(modelUrl, "/") {
-				modelUrl += "chat/completions"
-			} else {
-				modelUrl += "/chat/completions"
-			}
-		}
-		for _, id := range modelIds {
-			id = strings.TrimSpace(id)
-			if id == "" {
-				continue
-			}
-			availableModelIds = append(availableModelIds, id)
-			qModels = append(qModels, CodeBuddyModel{
-				Id:               id,
-				Name:             id,
-				Vendor:           vendor,
-				ApiKey:           m.ApiKey,
-				MaxInputTokens:   200000,
-				MaxOutputTokens:  8192,
-				Url:              modelUrl,
-				SupportsToolCall: true,
-				SupportsImages:   true,
-			})
-		}
+	return writeRenderedFiles(files)
+}
+func (a *App) syncToQoderSettings(config AppConfig, projectPath string) error {
+	if projectPath == "" {
+		projectPath = a.GetCurrentProjectPath()
 	}
-	qConfig := CodeBuddyFileConfig{
-		Models:          qModels,
-		AvailableModels: availableModelIds,
+	if projectPath == "" {
+		return nil
 	}
-	data, err := json.MarshalIndent(qConfig, "", "  ")
+	files, err := renderModelsFile("qoder", config.Qoder, projectPath)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(qFilePath, data, 0644)
-}
-func getBaseUrl(selectedModel *ModelConfig) string {
-	// If user provided a URL for the selected model, always prefer it.
-	if selectedModel.ModelUrl != "" {
-		return selectedModel.ModelUrl
-	}
-	// Otherwise, fall back to hardcoded defaults for known providers that have them.
-	baseUrl := "" // Default to empty string
-	switch strings.ToLower(selectedModel.ModelName) {
-	case "kimi":
-		baseUrl = "https://api.kimi.com/coding"
-	case "glm", "glm-4.7":
-		baseUrl = "https://open.bigmodel.cn/api/anthropic"
-	case "doubao":
-		baseUrl = "https://ark.cn-beijing.volces.com/api/coding"
-	case "minimax":
-		baseUrl = "https://api.minimaxi.com/anthropic"
-	case "deepseek":
-		baseUrl = "https://api.deepseek.com/anthropic"
-	case "gaccode":
-		baseUrl = "https://gaccode.com/claudecode"
-	}
-	return baseUrl
+	return writeRenderedFiles(files)
 }
 func (a *App) LaunchTool(toolName string, yoloMode bool, adminMode bool, pythonProject bool, pythonEnv string, projectDir string, useProxy bool) {
 	a.log(fmt.Sprintf("LaunchTool called: %s, yolo=%v, admin=%v, py=%v, pyenv=%s, dir=%s, proxy=%v",
 		toolName, yoloMode, adminMode, pythonProject, pythonEnv, projectDir, useProxy))
-	a.log(fmt.Sprintf("Launching %s...", toolName))
-	// Only process Python environment if pythonProject is true
-	if pythonProject && pythonEnv != "" && pythonEnv != "None (Default)" {
-		a.log(fmt.Sprintf("Python project: using Python environment: %s", pythonEnv))
-	} else {
-		// Clear pythonEnv if not a Python project
-		pythonEnv = ""
-	}
 	if projectDir == "" {
 		projectDir = a.GetCurrentProjectPath()
 	}
@@ -1320,6 +1295,41 @@ func (a *App) LaunchTool(toolName string, yoloMode bool, adminMode bool, pythonP
 		a.log("Error loading config: " + err.Error())
 		return
 	}
+	// Settings committed with the project in .aicoder.json take precedence
+	proj := findProject(&config, projectDir)
+	settings, projectFile, err := resolveLaunchSettings(&config, launchSettings{
+		Tool:          strings.ToLower(toolName),
+		YoloMode:      yoloMode,
+		AdminMode:     adminMode,
+		TeamMode:      proj != nil && proj.TeamMode,
+		UseProxy:      useProxy,
+		PythonProject: pythonProject,
+		PythonEnv:     pythonEnv,
+		sources:       make(map[string]string),
+	}, projectDir)
+	if err != nil {
+		a.log("Launch: " + err.Error())
+		a.ShowMessage("Launch Error", err.Error())
+		return
+	}
+	if projectFile != nil && projectFile.Error != "" {
+		a.log("Launch: ignored " + projectFile.Error)
+	} else if projectFile != nil && !projectFile.Trusted {
+		a.log(fmt.Sprintf("Launch: %s is not trusted yet and was ignored", projectFile.Path))
+		a.emitEvent("project-file-untrusted", projectFile.Path)
+	} else if projectFile != nil {
+		a.log("Launch: using " + projectFile.Path)
+	}
+	toolName, yoloMode, adminMode, useProxy = settings.Tool, settings.YoloMode, settings.AdminMode, settings.UseProxy
+	pythonProject, pythonEnv = settings.PythonProject, settings.PythonEnv
+	a.log(fmt.Sprintf("Launching %s...", toolName))
+	// Only process Python environment if pythonProject is true
+	if pythonProject && pythonEnv != "" && pythonEnv != "None (Default)" {
+		a.log(fmt.Sprintf("Python project: using Python environment: %s", pythonEnv))
+	} else {
+		// Clear pythonEnv if not a Python project
+		pythonEnv = ""
+	}
 	var toolCfg ToolConfig
 	var envKey, envBaseUrl string
 	var binaryName string
@@ -1389,32 +1399,21 @@ func (a *App) LaunchTool(toolName string, yoloMode bool, adminMode bool, pythonP
 		a.ShowMessage(title, message)
 		return
 	}
+	// Pick the API key for this launch when the provider has several
+	if len(selectedModel.ApiKeys) > 0 {
+		a.pickLaunchKey(&config, strings.ToLower(toolName), selectedModel)
+	}
 	// Ensure ActiveTool is set correctly for syncToSystemEnv
 	config.ActiveTool = strings.ToLower(toolName)
 	a.syncToSystemEnv(config)
-	// 1. CLEAR PROCESS ENV VARS (Safety First - avoid leaks from current process)
-	a.clearEnvVars()
+	// The launched tool's environment. It goes to the child only, see launchEnviron;
+	// keys and proxy passwords never enter AICoder's own environment.
 	env := make(map[string]string)
 	// Proxy settings
 	if useProxy && goruntime.GOOS != "windows" {
 		var proxyHost, proxyPort, proxyUsername, proxyPassword string
-		// Get proxy configuration (matching project path > global default)
-		var targetProj *ProjectConfig
-		for i := range config.Projects {
-			if config.Projects[i].Path == projectDir {
-				targetProj = &config.Projects[i]
-				break
-			}
-		}
-		// Fallback to CurrentProject if path match not found
-		if targetProj == nil {
-			for i := range config.Projects {
-				if config.Projects[i].Id == config.CurrentProject {
-					targetProj = &config.Projects[i]
-					break
-				}
-			}
-		}
+		// Get proxy configuration (matching project path > current project > global default)
+		targetProj := findProject(&config, projectDir)
 		if targetProj != nil {
 			proxyHost = targetProj.ProxyHost
 			proxyPort = targetProj.ProxyPort
@@ -1437,10 +1436,6 @@ func (a *App) LaunchTool(toolName string, yoloMode bool, adminMode bool, pythonP
 				proxyURL = fmt.Sprintf("http://%s:%s", proxyHost, proxyPort)
 			}
 			// Set proxy environment variables (both cases for compatibility)
-			os.Setenv("HTTP_PROXY", proxyURL)
-			os.Setenv("HTTPS_PROXY", proxyURL)
-			os.Setenv("http_proxy", proxyURL)
-			os.Setenv("https_proxy", proxyURL)
 			env["HTTP_PROXY"] = proxyURL
 			env["HTTPS_PROXY"] = proxyURL
 			env["http_proxy"] = proxyURL
@@ -1448,67 +1443,68 @@ func (a *App) LaunchTool(toolName string, yoloMode bool, adminMode bool, pythonP
 			a.log(fmt.Sprintf("Proxy enabled: %s:%s", proxyHost, proxyPort))
 		}
 	}
+	provider := resolveProvider(strings.ToLower(toolName), selectedModel)
 	if strings.ToLower(selectedModel.ModelName) != "original" {
 		// --- OTHER PROVIDER MODE: WRITE CONFIG & SET ENV ---
-		// Set process environment variables
-		os.Setenv(envKey, selectedModel.ApiKey)
 		env[envKey] = selectedModel.ApiKey
-		if selectedModel.ModelUrl != "" && envBaseUrl != "" {
-			os.Setenv(envBaseUrl, selectedModel.ModelUrl)
-			env[envBaseUrl] = selectedModel.ModelUrl
+		if provider.BaseUrl != "" && envBaseUrl != "" {
+			env[envBaseUrl] = provider.BaseUrl
 		}
-		// Add CODEBUDDY_CODE_MAX_OUTPUT_TOKENS for DeepSeek
-		if strings.ToLower(selectedModel.ModelName) == "deepseek" {
-			os.Setenv("CODEBUDDY_CODE_MAX_OUTPUT_TOKENS", "8192")
-			env["CODEBUDDY_CODE_MAX_OUTPUT_TOKENS"] = "8192"
+		// Provider-specific environment from the catalog
+		for k, v := range provider.Env {
+			env[k] = v
 		}
 		// Set generic model name env var if applicable
-		if selectedModel.ModelId != "" {
+		if provider.ModelId != "" {
 			switch strings.ToLower(toolName) {
 			case "claude":
-				os.Setenv("ANTHROPIC_MODEL", selectedModel.ModelId)
-				env["ANTHROPIC_MODEL"] = selectedModel.ModelId
+				env["ANTHROPIC_MODEL"] = provider.ModelId
+				// Model tiers follow the provider's roles, as in settings.json
+				roleEnv := map[string]string{
+					"ANTHROPIC_DEFAULT_SONNET_MODEL": provider.ModelId,
+					"ANTHROPIC_DEFAULT_HAIKU_MODEL":  provider.Roles.Fast,
+					"ANTHROPIC_DEFAULT_OPUS_MODEL":   provider.Roles.Reasoning,
+				}
+				for k, v := range roleEnv {
+					env[k] = v
+				}
 			case "gemini":
-				os.Setenv("GOOGLE_GEMINI_MODEL", selectedModel.ModelId)
-				env["GOOGLE_GEMINI_MODEL"] = selectedModel.ModelId
+				env["GOOGLE_GEMINI_MODEL"] = provider.ModelId
 			case "codex":
-				os.Setenv("OPENAI_MODEL", selectedModel.ModelId)
-				env["OPENAI_MODEL"] = selectedModel.ModelId
+				env["OPENAI_MODEL"] = provider.ModelId
 			case "opencode":
-				os.Setenv("OPENCODE_MODEL", selectedModel.ModelId)
-				env["OPENCODE_MODEL"] = selectedModel.ModelId
+				env["OPENCODE_MODEL"] = provider.ModelId
 			case "codebuddy":
-				// os.Setenv("CODEBUDDY_MODEL", selectedModel.ModelId)
-				// env["CODEBUDDY_MODEL"] = selectedModel.ModelId
+				// os.Setenv("CODEBUDDY_MODEL", provider.ModelId)
+				// env["CODEBUDDY_MODEL"] = provider.ModelId
 			case "qoder":
 				// Qoder doesn't use model env var
 			case "iflow":
 				// iFlow uses settings.json, but maybe env var too?
-				os.Setenv("IFLOW_MODEL", selectedModel.ModelId)
-				env["IFLOW_MODEL"] = selectedModel.ModelId
+				env["IFLOW_MODEL"] = provider.ModelId
 			case "kilo":
-				os.Setenv("KILO_MODEL", selectedModel.ModelId)
-				env["KILO_MODEL"] = selectedModel.ModelId
+				env["KILO_MODEL"] = provider.ModelId
 			}
 		}
 		// Tool-specific configurations
 		switch strings.ToLower(toolName) {
 		case "claude":
 			// Ensure AUTH_TOKEN is unset when using API_KEY to avoid conflict
-			a.syncToClaudeSettings(config)
+			if err := a.syncToClaudeSettings(config); err != nil {
+				a.log("Failed to update Claude settings: " + err.Error())
+			}
 		case "gemini":
 			a.syncToGeminiSettings(config)
 		case "codex":
-			os.Setenv("WIRE_API", "responses")
 			env["WIRE_API"] = "responses"
 			// Ensure OpenAI standard vars for Codex
-			os.Setenv("OPENAI_API_KEY", selectedModel.ApiKey)
 			env["OPENAI_API_KEY"] = selectedModel.ApiKey
-			if selectedModel.ModelUrl != "" {
-				os.Setenv("OPENAI_BASE_URL", selectedModel.ModelUrl)
-				env["OPENAI_BASE_URL"] = selectedModel.ModelUrl
+			if provider.BaseUrl != "" {
+				env["OPENAI_BASE_URL"] = provider.BaseUrl
+			}
+			if err := a.syncToCodexSettings(config); err != nil {
+				a.log("Failed to update Codex settings: " + err.Error())
 			}
-			a.syncToCodexSettings(config)
 		case "opencode":
 			// Opencode might use similar settings to Codex or its own
 			a.syncToOpencodeSettings(config)
@@ -1518,11 +1514,9 @@ func (a *App) LaunchTool(toolName string, yoloMode bool, adminMode bool, pythonP
 			a.syncToQoderSettings(config, projectDir)
 		case "iflow":
 			// Ensure OpenAI standard vars for iFlow (compatibility)
-			os.Setenv("OPENAI_API_KEY", selectedModel.ApiKey)
 			env["OPENAI_API_KEY"] = selectedModel.ApiKey
-			if selectedModel.ModelUrl != "" {
-				os.Setenv("OPENAI_BASE_URL", selectedModel.ModelUrl)
-				env["OPENAI_BASE_URL"] = selectedModel.ModelUrl
+			if provider.BaseUrl != "" {
+				env["OPENAI_BASE_URL"] = provider.BaseUrl
 			}
 			a.syncToIFlowSettings(config)
 		case "kilo":
@@ -1534,44 +1528,27 @@ func (a *App) LaunchTool(toolName string, yoloMode bool, adminMode bool, pythonP
 		}
 	} else {
 		// --- ORIGINAL MODE: CLEANUP SPECIFIC TOOL ONLY ---
-		// Clear process environment variables for this tool
-		os.Unsetenv(envKey)
-		os.Unsetenv(envBaseUrl)
 		if strings.ToLower(toolName) == "claude" {
-			os.Unsetenv("ANTHROPIC_AUTH_TOKEN")
-			os.Unsetenv("ANTHROPIC_MODEL")
-			a.clearClaudeConfig()
+			if err := a.removeClaudeManagedSettings(); err != nil {
+				a.log("Failed to update Claude settings: " + err.Error())
+			}
 		} else if strings.ToLower(toolName) == "gemini" {
-			os.Unsetenv("GOOGLE_GEMINI_MODEL")
 			a.syncToGeminiSettings(config)
 		} else if strings.ToLower(toolName) == "codex" {
-			os.Unsetenv("WIRE_API")
-			os.Unsetenv("OPENAI_API_KEY")
-			os.Unsetenv("OPENAI_BASE_URL")
-			os.Unsetenv("OPENAI_MODEL")
-			a.clearCodexConfig()
+			if err := a.removeCodexManagedSettings(); err != nil {
+				a.log("Failed to update Codex settings: " + err.Error())
+			}
 		} else if strings.ToLower(toolName) == "opencode" {
-			os.Unsetenv("OPENCODE_API_KEY")
-			os.Unsetenv("OPENCODE_BASE_URL")
-			os.Unsetenv("OPENCODE_MODEL")
 			a.clearOpencodeConfig()
 		} else if strings.ToLower(toolName) == "codebuddy" {
-			os.Unsetenv("CODEBUDDY_API_KEY")
-			os.Unsetenv("CODEBUDDY_BASE_URL")
-			os.Unsetenv("CODEBUDDY_CODE_MAX_OUTPUT_TOKENS")
 			// Codebuddy might need cleanup too if we added a clear function
 		} else if strings.ToLower(toolName) == "qoder" {
-			os.Unsetenv("QODER_PERSONAL_ACCESS_TOKEN")
-			os.Unsetenv("QODER_BASE_URL")
 			// Qoder cleanup if needed
 		} else if strings.ToLower(toolName) == "iflow" {
-			os.Unsetenv("IFLOW_MODEL")
 			a.clearIFlowConfig()
 		} else if strings.ToLower(toolName) == "kilo" {
-			os.Unsetenv("KILO_MODEL")
 			a.clearKiloConfig()
 		} else if strings.ToLower(toolName) == "kode" {
-			os.Unsetenv("KODE_MODEL")
 			a.clearKodeConfig()
 		}
 		a.log(fmt.Sprintf("Running %s in Original mode: Custom configurations cleared.", toolName))
@@ -1579,25 +1556,31 @@ func (a *App) LaunchTool(toolName string, yoloMode bool, adminMode bool, pythonP
 
 	// Claude Code Agent Teams mode
 	if strings.ToLower(toolName) == "claude" {
-		// Find the current project config to check team_mode
-		for _, proj := range config.Projects {
-			if proj.Path == projectDir || proj.Id == config.CurrentProject {
-				if proj.TeamMode {
-					os.Setenv("CLAUDE_CODE_EXPERIMENTAL_AGENT_TEAMS", "1")
-					env["CLAUDE_CODE_EXPERIMENTAL_AGENT_TEAMS"] = "1"
-					a.log("Claude Code Agent Teams mode enabled")
-				} else {
-					os.Unsetenv("CLAUDE_CODE_EXPERIMENTAL_AGENT_TEAMS")
-				}
-				break
-			}
+		if settings.TeamMode {
+			env["CLAUDE_CODE_EXPERIMENTAL_AGENT_TEAMS"] = "1"
+			a.log("Claude Code Agent Teams mode enabled")
 		}
 	}
+	// Extra environment from .aicoder.json, for this launch only; the variables set
+	// above take precedence
+	for k, v := range settings.Env {
+		if _, ok := env[k]; ok {
+			a.log(fmt.Sprintf("Launch: %s from %s ignored, AICoder sets it", k, projectFileName))
+			continue
+		}
+		env[k] = v
+	}
+	if err := a.runPreLaunch(projectDir, settings.PreLaunch, env); err != nil {
+		a.log("Pre-launch command failed: " + err.Error())
+		a.ShowMessage("Launch Error", "Pre-launch command failed: "+err.Error())
+		return
+	}
 
 	// Platform specific launch
-	a.platformLaunch(binaryName, yoloMode, adminMode, pythonEnv, projectDir, env, selectedModel.ModelId)
+	a.platformLaunch(binaryName, yoloMode, adminMode, pythonEnv, projectDir, env, provider.ModelId)
 }
 func (a *App) log(message string) {
+	message = a.redactor.redact(message)
 	if a.IsInitMode {
 		fmt.Println(message)
 	}
@@ -1615,111 +1598,19 @@ func (a *App) getConfigPath() (string, error) {
 	}
 	return filepath.Join(home, ".aicoder_config.json"), nil
 }
+// LoadConfig returns a copy of the current configuration.
 func (a *App) LoadConfig() (AppConfig, error) {
+	return a.configStore().Get()
+}
+// readConfig reads the config file and brings it up to date: it creates a default
+// config on first run, restores a damaged file, runs migrations and fills in
+// providers. The config store calls it; everything else uses LoadConfig.
+func (a *App) readConfig() (AppConfig, error) {
 	path, err := a.getConfigPath()
 	if err != nil {
 		return AppConfig{}, err
 	}
-	// Helper for default models
-	defaultClaudeModels := []ModelConfig{
-		{ModelName: "Original", ModelId: "", ModelUrl: "", ApiKey: ""},
-		{ModelName: "GLM", ModelId: "glm-4.7", ModelUrl: "https://open.bigmodel.cn/api/anthropic", ApiKey: ""},
-		{ModelName: "Kimi", ModelId: "kimi-k2-thinking", ModelUrl: "https://api.kimi.com/coding", ApiKey: ""},
-		{ModelName: "Doubao", ModelId: "doubao-seed-code-preview-latest", ModelUrl: "https://ark.cn-beijing.volces.com/api/coding", ApiKey: ""},
-		{ModelName: "MiniMax", ModelId: "MiniMax-M2.1", ModelUrl: "https://api.minimaxi.com/anthropic", ApiKey: ""},
-		{ModelName: "DeepSeek", ModelId: "deepseek-chat", ModelUrl: "https://api.deepseek.com/anthropic", ApiKey: ""},
-		{ModelName: "AIgoCode", ModelId: "sonnet", ModelUrl: "https://api.aigocode.com/api", ApiKey: ""},
-		{ModelName: "Noin.AI", ModelId: "sonnet", ModelUrl: "https://ai.ourines.com/api", ApiKey: ""},
-		{ModelName: "AiCodeMirror", ModelId: "sonnet", ModelUrl: "https://api.aicodemirror.com/api/claudecode", ApiKey: ""},
-		{ModelName: "GACCode", ModelId: "sonnet", ModelUrl: "https://gaccode.com/claudecode", ApiKey: ""},
-		{ModelName: "CodeRelay", ModelId: "claude-3-5-sonnet-20241022", ModelUrl: "https://api.code-relay.com/", ApiKey: ""},
-		{ModelName: "ChatFire", ModelId: "sonnet", ModelUrl: "https://api.chatfire.cn", ApiKey: ""},
-		{ModelName: "摩尔线程", ModelId: "GLM-4.7", ModelUrl: "https://coding-plan-endpoint.kuaecloud.net", ApiKey: ""},
-		{ModelName: "快手", ModelId: "kat-coder-pro-v1", ModelUrl: "https://wanqing.streamlakeapi.com/api/gateway/coding/kat-coder-pro-v1/claude-code-proxy", ApiKey: ""},
-		{ModelName: "Custom", ModelId: "", ModelUrl: "", ApiKey: "", IsCustom: true},
-		{ModelName: "Custom1", ModelId: "", ModelUrl: "", ApiKey: "", IsCustom: true},
-	}
-	defaultGeminiModels := []ModelConfig{
-		{ModelName: "Original", ModelId: "", ModelUrl: "", ApiKey: ""},
-		{ModelName: "AIgoCode", ModelId: "gemini-2.0-flash-exp", ModelUrl: "https://api.aigocode.com/gemini", ApiKey: ""},
-		{ModelName: "AiCodeMirror", ModelId: "gemini-2.0-flash-exp", ModelUrl: "https://api.aicodemirror.com/api/gemini", ApiKey: ""},
-		{ModelName: "ChatFire", ModelId: "gemini-2.5-pro", ModelUrl: "https://api.chatfire.cn/v1beta/models/gemini-2.5-pro:generateContent", ApiKey: ""},
-		{ModelName: "Custom", ModelId: "", ModelUrl: "", ApiKey: "", IsCustom: true},
-		{ModelName: "Custom1", ModelId: "", ModelUrl: "", ApiKey: "", IsCustom: true},
-	}
-	defaultCodexModels := []ModelConfig{
-		{ModelName: "Original", ModelId: "", ModelUrl: "", ApiKey: ""},
-		{ModelName: "AIgoCode", ModelId: "gpt-5.2-codex", ModelUrl: "https://api.aigocode.com/openai", ApiKey: ""},
-		{ModelName: "AiCodeMirror", ModelId: "gpt-5.2-codex", ModelUrl: "https://api.aicodemirror.com/api/codex/backend-api/codex", ApiKey: ""},
-		{ModelName: "CodeRelay", ModelId: "gpt-5.2-codex", ModelUrl: "https://api.code-relay.com/v1", ApiKey: ""},
-		{ModelName: "ChatFire", ModelId: "gpt-5.1-codex-mini", ModelUrl: "https://api.chatfire.cn/v1", ApiKey: "", WireApi: "responses"},
-		{ModelName: "DeepSeek", ModelId: "deepseek-chat", ModelUrl: "https://api.deepseek.com/v1", ApiKey: ""},
-		{ModelName: "GLM", ModelId: "glm-4.7", ModelUrl: "https://open.bigmodel.cn/api/coding/paas/v4", ApiKey: ""},
-		{ModelName: "Doubao", ModelId: "doubao-seed-code-preview-latest", ModelUrl: "https://ark.cn-beijing.volces.com/api/coding/v3", ApiKey: ""},
-		{ModelName: "Kimi", ModelId: "kimi-for-coding", ModelUrl: "https://api.kimi.com/coding/v1", ApiKey: ""},
-		{ModelName: "MiniMax", ModelId: "MiniMax-M2.1", ModelUrl: "https://api.minimaxi.com/v1", ApiKey: ""},
-		{ModelName: "摩尔线程", ModelId: "GLM-4.7", ModelUrl: "https://coding-plan-endpoint.kuaecloud.net/v1", ApiKey: ""},
-		{ModelName: "快手", ModelId: "kat-coder-pro-v1", ModelUrl: "https://wanqing.streamlakeapi.com/api/gateway/coding/v1", ApiKey: ""},
-		{ModelName: "Custom", ModelId: "", ModelUrl: "", ApiKey: "", IsCustom: true},
-		{ModelName: "Custom1", ModelId: "", ModelUrl: "", ApiKey: "", IsCustom: true},
-	}
-	defaultOpencodeModels := []ModelConfig{
-		{ModelName: "Original", ModelId: "", ModelUrl: "", ApiKey: ""},
-		{ModelName: "ChatFire", ModelId: "gpt-4o", ModelUrl: "https://api.chatfire.cn/v1", ApiKey: ""},
-		{ModelName: "DeepSeek", ModelId: "deepseek-chat", ModelUrl: "https://api.deepseek.com/v1", ApiKey: ""},
-		{ModelName: "GLM", ModelId: "glm-4.7", ModelUrl: "https://open.bigmodel.cn/api/coding/paas/v4", ApiKey: ""},
-		{ModelName: "Doubao", ModelId: "doubao-seed-code-preview-latest", ModelUrl: "https://ark.cn-beijing.volces.com/api/coding/v3", ApiKey: ""},
-		{ModelName: "Kimi", ModelId: "kimi-for-coding", ModelUrl: "https://api.kimi.com/coding/v1", ApiKey: ""},
-		{ModelName: "MiniMax", ModelId: "MiniMax-M2.1", ModelUrl: "https://api.minimaxi.com/v1", ApiKey: ""},
-		{ModelName: "摩尔线程", ModelId: "GLM-4.7", ModelUrl: "https://coding-plan-endpoint.kuaecloud.net/v1", ApiKey: ""},
-		{ModelName: "快手", ModelId: "kat-coder-pro-v1", ModelUrl: "https://wanqing.streamlakeapi.com/api/gateway/coding/v1", ApiKey: ""},
-		{ModelName: "Custom", ModelId: "", ModelUrl: "", ApiKey: "", IsCustom: true},
-		{ModelName: "Custom1", ModelId: "", ModelUrl: "", ApiKey: "", IsCustom: true},
-	}
-	defaultQoderModels := []ModelConfig{
-		{ModelName: "Original", ModelId: "", ModelUrl: "", ApiKey: ""},
-		{ModelName: "Qoder", ModelId: "qoder-1.0", ModelUrl: "https://api.qoder.com/v1", ApiKey: ""},
-	}
-	defaultIFlowModels := []ModelConfig{
-		{ModelName: "Original", ModelId: "", ModelUrl: "", ApiKey: ""},
-		{ModelName: "DeepSeek", ModelId: "deepseek-chat", ModelUrl: "https://api.deepseek.com/v1", ApiKey: ""},
-		{ModelName: "GLM", ModelId: "glm-4.7", ModelUrl: "https://open.bigmodel.cn/api/coding/paas/v4", ApiKey: ""},
-		{ModelName: "Doubao", ModelId: "doubao-seed-code-preview-latest", ModelUrl: "https://ark.cn-beijing.volces.com/api/coding/v3", ApiKey: ""},
-		{ModelName: "Kimi", ModelId: "kimi-for-coding", ModelUrl: "https://api.kimi.com/coding/v1", ApiKey: ""},
-		{ModelName: "MiniMax", ModelId: "MiniMax-M2.1", ModelUrl: "https://api.minimaxi.com/v1", ApiKey: ""},
-		{ModelName: "XiaoMi", ModelId: "mimo-v2-flash", ModelUrl: "https://api.xiaomimimo.com/v1", ApiKey: ""},
-		{ModelName: "摩尔线程", ModelId: "GLM-4.7", ModelUrl: "https://coding-plan-endpoint.kuaecloud.net/v1", ApiKey: ""},
-		{ModelName: "快手", ModelId: "kat-coder-pro-v1", ModelUrl: "https://wanqing.streamlakeapi.com/api/gateway/coding/v1", ApiKey: ""},
-		{ModelName: "Custom", ModelId: "", ModelUrl: "", ApiKey: "", IsCustom: true},
-		{ModelName: "Custom1", ModelId: "", ModelUrl: "", ApiKey: "", IsCustom: true},
-	}
-	defaultKiloModels := []ModelConfig{
-		{ModelName: "Original", ModelId: "", ModelUrl: "", ApiKey: ""},
-		{ModelName: "ChatFire", ModelId: "gpt-4o", ModelUrl: "https://api.chatfire.cn/v1", ApiKey: ""},
-		{ModelName: "DeepSeek", ModelId: "deepseek-chat", ModelUrl: "https://api.deepseek.com/v1", ApiKey: ""},
-		{ModelName: "GLM", ModelId: "glm-4.7", ModelUrl: "https://open.bigmodel.cn/api/coding/paas/v4", ApiKey: ""},
-		{ModelName: "Doubao", ModelId: "doubao-seed-code-preview-latest", ModelUrl: "https://ark.cn-beijing.volces.com/api/coding/v3", ApiKey: ""},
-		{ModelName: "Kimi", ModelId: "kimi-for-coding", ModelUrl: "https://api.kimi.com/coding/v1", ApiKey: ""},
-		{ModelName: "MiniMax", ModelId: "MiniMax-M2.1", ModelUrl: "https://api.minimaxi.com/v1", ApiKey: ""},
-		{ModelName: "XiaoMi", ModelId: "mimo-v2-flash", ModelUrl: "https://api.xiaomimimo.com/v1", ApiKey: ""},
-		{ModelName: "摩尔线程", ModelId: "GLM-4.7", ModelUrl: "https://coding-plan-endpoint.kuaecloud.net/v1", ApiKey: ""},
-		{ModelName: "快手", ModelId: "kat-coder-pro-v1", ModelUrl: "https://wanqing.streamlakeapi.com/api/gateway/coding/v1", ApiKey: ""},
-		{ModelName: "Custom", ModelId: "", ModelUrl: "", ApiKey: "", IsCustom: true},
-		{ModelName: "Custom1", ModelId: "", ModelUrl: "", ApiKey: "", IsCustom: true},
-	}
-	defaultKodeModels := []ModelConfig{
-		{ModelName: "ChatFire", ModelId: "gpt-4o", ModelUrl: "https://api.chatfire.cn/v1", ApiKey: ""},
-		{ModelName: "DeepSeek", ModelId: "deepseek-chat", ModelUrl: "https://api.deepseek.com/v1", ApiKey: ""},
-		{ModelName: "GLM", ModelId: "glm-4.7", ModelUrl: "https://open.bigmodel.cn/api/coding/paas/v4", ApiKey: ""},
-		{ModelName: "Doubao", ModelId: "doubao-seed-code-preview-latest", ModelUrl: "https://ark.cn-beijing.volces.com/api/coding/v3", ApiKey: ""},
-		{ModelName: "Kimi", ModelId: "kimi-for-coding", ModelUrl: "https://api.kimi.com/coding/v1", ApiKey: ""},
-		{ModelName: "MiniMax", ModelId: "MiniMax-M2.1", ModelUrl: "https://api.minimaxi.com/v1", ApiKey: ""},
-		{ModelName: "XiaoMi", ModelId: "mimo-v2-flash", ModelUrl: "https://api.xiaomimimo.com/v1", ApiKey: ""},
-		{ModelName: "摩尔线程", ModelId: "GLM-4.7", ModelUrl: "https://coding-plan-endpoint.kuaecloud.net/v1", ApiKey: ""},
-		{ModelName: "快手", ModelId: "kat-coder-pro-v1", ModelUrl: "https://wanqing.streamlakeapi.com/api/gateway/coding/v1", ApiKey: ""},
-		{ModelName: "Custom", ModelId: "", ModelUrl: "", ApiKey: "", IsCustom: true},
-		{ModelName: "Custom1", ModelId: "", ModelUrl: "", ApiKey: "", IsCustom: true},
-	}
+	catalog := currentCatalog()
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		// Check for old config file for migration
 		home, _ := os.UserHomeDir()
@@ -1736,98 +1627,36 @@ func (a *App) LoadConfig() (AppConfig, error) {
 				}
 				if err := json.Unmarshal(data, &oldConfig); err == nil {
 					config := AppConfig{
-						Claude: ToolConfig{
-							CurrentModel: oldConfig.CurrentModel,
-							Models:       oldConfig.Models,
-						},
-						Gemini: ToolConfig{
-							CurrentModel: "Gemini 1.5 Pro",
-							Models:       defaultGeminiModels,
-						},
-						Codex: ToolConfig{
-							CurrentModel: "Codex",
-							Models:       defaultCodexModels,
-						},
-						Opencode: ToolConfig{
-							CurrentModel: "AiCodeMirror",
-							Models:       defaultOpencodeModels,
-						},
-						CodeBuddy: ToolConfig{
-							CurrentModel: "AiCodeMirror",
-							Models:       defaultOpencodeModels,
-						},
-						Qoder: ToolConfig{
-							CurrentModel: "Original",
-							Models:       defaultQoderModels,
-						},
-						IFlow: ToolConfig{
-							CurrentModel: "Original",
-							Models:       defaultIFlowModels,
-						},
-						Kilo: ToolConfig{
-							CurrentModel: "Original",
-							Models:       defaultKiloModels,
-						},
-					Kode: ToolConfig{
-						CurrentModel: "ChatFire",
-						Models:       defaultKodeModels,
-					},
 						Projects:       oldConfig.Projects,
 						CurrentProject: oldConfig.CurrentProj,
 						ActiveTool:     "claude",
 						ShowGemini:     true,
 						ShowCodex:      true,
 						ShowOpenCode:   true,
-						ShowKode:      true,
+						ShowKode:       true,
 						ShowCodeBuddy:  true,
 						ShowQoder:      true,
 						ShowIFlow:      true,
 						ShowKilo:       true,
 					}
-					a.SaveConfig(config)
+					for _, tool := range toolNames {
+						*getToolConfig(&config, tool) = catalog.defaultToolConfig(tool)
+					}
+					config.Claude = ToolConfig{
+						CurrentModel: oldConfig.CurrentModel,
+						Models:       oldConfig.Models,
+					}
 					// Optional: os.Remove(oldPath)
-					return config, nil
+					if err := a.saveToPath(path, config); err != nil {
+						return config, err
+					}
+					// Read it back to fill in everything the old format lacks
+					return a.readConfig()
 				}
 			}
 		}
 		// Create default config
 		defaultConfig := AppConfig{
-			Claude: ToolConfig{
-				CurrentModel: "GLM",
-				Models:       defaultClaudeModels,
-			},
-			Gemini: ToolConfig{
-				CurrentModel: "Gemini 1.5 Pro",
-				Models:       defaultGeminiModels,
-			},
-			Codex: ToolConfig{
-				CurrentModel: "Codex",
-				Models:       defaultCodexModels,
-			},
-			Opencode: ToolConfig{
-				CurrentModel: "AiCodeMirror",
-				Models:       defaultOpencodeModels,
-			},
-			CodeBuddy: ToolConfig{
-				CurrentModel: "AiCodeMirror",
-				Models:       defaultOpencodeModels,
-			},
-			Qoder: ToolConfig{
-				CurrentModel: "Original",
-				Models:       defaultQoderModels,
-			},
-			IFlow: ToolConfig{
-				CurrentModel: "Original",
-				Models:       defaultIFlowModels,
-			},
-			Kilo: ToolConfig{
-				CurrentModel: "Original",
-				Models:       defaultKiloModels,
-			},
-		Kode: ToolConfig{
-			CurrentModel: "ChatFire",
-			Models:       defaultKodeModels,
-		},
 			Projects: []ProjectConfig{
 				{
 					Id:       "default",
@@ -1836,20 +1665,25 @@ func (a *App) LoadConfig() (AppConfig, error) {
 					YoloMode: false,
 				},
 			},
-			CurrentProject:   "default",
-			ActiveTool:       "claude",
-			ShowGemini:       true,
-			ShowCodex:        true,
-			ShowOpenCode:     true,
-			ShowCodeBuddy:    true,
-			ShowQoder:        true,
-			ShowIFlow:        true,
-			ShowKilo:         true,
-			ShowKode:         true,
-			EnvCheckInterval: 7, // Default to 7 days
+			CurrentProject:     "default",
+			ActiveTool:         "claude",
+			ShowGemini:         true,
+			ShowCodex:          true,
+			ShowOpenCode:       true,
+			ShowCodeBuddy:      true,
+			ShowQoder:          true,
+			ShowIFlow:          true,
+			ShowKilo:           true,
+			ShowKode:           true,
+			EnvCheckInterval:   7,    // Default to 7 days
 			UseWindowsTerminal: true, // Default to true, will only work if Windows Terminal is installed
+			SchemaVersion:      latestSchemaVersion(),
+		}
+		for _, tool := range toolNames {
+			*getToolConfig(&defaultConfig, tool) = catalog.defaultToolConfig(tool)
 		}
-		err = a.SaveConfig(defaultConfig)
+		a.importExistingOnFirstRun(&defaultConfig)
+		err = a.saveToPath(path, defaultConfig)
 		return defaultConfig, err
 	}
 	config := AppConfig{
@@ -1866,26 +1700,41 @@ func (a *App) LoadConfig() (AppConfig, error) {
 	if err != nil {
 		return config, err
 	}
-
-	// Check if this is an old config without the new show_* fields
-	// If show_kilo is not present in JSON, default it to true
-	var rawConfig map[string]interface{}
-	json.Unmarshal(data, &rawConfig)
-	hasShowKilo := false
-	if _, ok := rawConfig["show_kilo"]; ok {
-		hasShowKilo = true
+	// A damaged file, e.g. truncated by a crash, is replaced by the newest good backup
+	if err := json.Unmarshal(data, &AppConfig{}); err != nil {
+		if data, err = a.recoverConfig(path, data, err); err != nil {
+			return config, err
+		}
 	}
-
+	// Show flags missing from older configs keep the defaults above
 	err = json.Unmarshal(data, &config)
 	if err != nil {
 		return config, err
 	}
+	// Secrets are kept out of the file; older configs still hold them in plain text
+	plaintextSecrets := a.secrets().resolve(&config, a.log)
+	a.redactor.setConfig(&config)
+	beforeMigration := cloneConfig(config)
+	migrated := a.migrateConfig(&config, catalog)
 
-	// Set default values for new fields if not present in old configs
-	if !hasShowKilo {
-		config.ShowKilo = true
+	normalizeConfig(&config, catalog)
+	if plaintextSecrets > 0 || migrated {
+		if err := a.saveToPath(path, config); err != nil {
+			a.log("Failed to save the migrated config file: " + err.Error())
+		} else {
+			if migrated {
+				a.recordConfigHistory(configSourceMigration, &beforeMigration, &config)
+			}
+			if plaintextSecrets > 0 {
+				a.log(fmt.Sprintf("Moved %d secrets from the config file to the %s store", plaintextSecrets, a.secrets().primary.name()))
+			}
+		}
 	}
-
+	return config, nil
+}
+// normalizeConfig fills in defaults, applies the catalog and repairs the provider
+// lists and selections, so every config read or saved has the same shape.
+func normalizeConfig(config *AppConfig, catalog *ProviderCatalog) {
 	// Set default values for new fields if not present or invalid
 	if config.EnvCheckInterval < 2 || config.EnvCheckInterval > 30 {
 		config.EnvCheckInterval = 7 // Default to 7 days
@@ -1894,146 +1743,15 @@ func (a *App) LoadConfig() (AppConfig, error) {
 	if config.Claude.CurrentModel == "" && len(config.Claude.Models) > 0 {
 		config.Claude.CurrentModel = config.Claude.Models[0].ModelName
 	}
-	// Helper to ensure a model exists in the list
-	ensureModel := func(models *[]ModelConfig, name, url, id, wireApi string) {
-		for i := range *models {
-			if strings.EqualFold((*models)[i].ModelName, name) {
-				(*models)[i].ModelName = name // Update to canonical casing
-				if url != "" {
-					(*models)[i].ModelUrl = url
-				}
-				// Only set ModelId if user hasn't customized it (empty means not set yet)
-				if id != "" && (*models)[i].ModelId == "" {
-					(*models)[i].ModelId = id
-				}
-				if wireApi != "" {
-					(*models)[i].WireApi = wireApi
-				}
-				return
-			}
-		}
-		*models = append(*models, ModelConfig{ModelName: name, ModelUrl: url, ModelId: id, WireApi: wireApi, ApiKey: ""})
-	}
-	if config.Gemini.Models == nil || len(config.Gemini.Models) == 0 {
-		config.Gemini.Models = defaultGeminiModels
-		config.Gemini.CurrentModel = "AiCodeMirror"
-	}
-	if config.Codex.Models == nil || len(config.Codex.Models) == 0 {
-		config.Codex.Models = defaultCodexModels
-		config.Codex.CurrentModel = "AiCodeMirror"
-	}
-	if config.Opencode.Models == nil || len(config.Opencode.Models) == 0 {
-		config.Opencode.Models = defaultOpencodeModels
-		config.Opencode.CurrentModel = "AiCodeMirror"
-	}
-	if config.CodeBuddy.Models == nil || len(config.CodeBuddy.Models) == 0 {
-		config.CodeBuddy.Models = defaultOpencodeModels
-		config.CodeBuddy.CurrentModel = "AiCodeMirror"
-	}
-	if config.Qoder.Models == nil || len(config.Qoder.Models) == 0 {
-		config.Qoder.Models = defaultQoderModels
-		config.Qoder.CurrentModel = "Original"
-	}
-	if config.IFlow.Models == nil || len(config.IFlow.Models) == 0 {
-		config.IFlow.Models = defaultIFlowModels
-		config.IFlow.CurrentModel = "Original"
-	}
-	if config.Kilo.Models == nil || len(config.Kilo.Models) == 0 {
-		config.Kilo.Models = defaultKiloModels
-		config.Kilo.CurrentModel = "Original"
-	}
-	if config.Kode.Models == nil || len(config.Kode.Models) == 0 {
-		config.Kode.Models = defaultKodeModels
-		config.Kode.CurrentModel = "ChatFire"
-	}
-	ensureModel(&config.Claude.Models, "AiCodeMirror", "https://api.aicodemirror.com/api/claudecode", "sonnet", "")
-	ensureModel(&config.Claude.Models, "Noin.AI", "https://ai.ourines.com/api", "sonnet", "")
-	ensureModel(&config.Claude.Models, "AIgoCode", "https://api.aigocode.com/api", "sonnet", "")
-	ensureModel(&config.Claude.Models, "CodeRelay", "https://api.code-relay.com/", "claude-3-5-sonnet-20241022", "")
-	ensureModel(&config.Claude.Models, "ChatFire", "https://api.chatfire.cn", "sonnet", "")
-	ensureModel(&config.Claude.Models, "GACCode", "https://gaccode.com/claudecode", "sonnet", "")
-	ensureModel(&config.Claude.Models, "DeepSeek", "https://api.deepseek.com/anthropic", "deepseek-chat", "")
-	ensureModel(&config.Claude.Models, "Kimi", "https://api.kimi.com/coding", "kimi-k2-thinking", "")
-	ensureModel(&config.Claude.Models, "Doubao", "https://ark.cn-beijing.volces.com/api/coding", "doubao-seed-code-preview-latest", "")
-	ensureModel(&config.Claude.Models, "GLM", "https://open.bigmodel.cn/api/anthropic", "glm-4.7", "")
-	ensureModel(&config.Claude.Models, "MiniMax", "https://api.minimaxi.com/anthropic", "MiniMax-M2.1", "")
-	ensureModel(&config.Claude.Models, "XiaoMi", "https://api.xiaomimimo.com/anthropic", "mimo-v2-flash", "")
-	ensureModel(&config.Claude.Models, "摩尔线程", "https://coding-plan-endpoint.kuaecloud.net", "GLM-4.7", "")
-	ensureModel(&config.Claude.Models, "快手", "https://wanqing.streamlakeapi.com/api/gateway/coding/kat-coder-pro-v1/claude-code-proxy", "kat-coder-pro-v1", "")
-	// Deduplicate AiCodeMirror for Claude if both AICodeMirror and AiCodeMirror exist
-	dedupeAiCodeMirror := func(models *[]ModelConfig) {
-		var newModels []ModelConfig
-		foundAi := false
-		for _, m := range *models {
-			if strings.EqualFold(m.ModelName, "AiCodeMirror") {
-				if !foundAi {
-					m.ModelName = "AiCodeMirror" // Standardize
-					newModels = append(newModels, m)
-					foundAi = true
-				}
-			} else {
-				newModels = append(newModels, m)
-			}
+	for _, tool := range toolNames {
+		toolCfg := getToolConfig(config, tool)
+		if len(toolCfg.Models) == 0 {
+			*toolCfg = catalog.defaultToolConfig(tool)
 		}
-		*models = newModels
-	}
-	dedupeAiCodeMirror(&config.Claude.Models)
-	ensureModel(&config.Gemini.Models, "AiCodeMirror", "https://api.aicodemirror.com/api/gemini", "gemini-2.0-flash-exp", "")
-	ensureModel(&config.Gemini.Models, "ChatFire", "https://api.chatfire.cn/v1beta/models/gemini-2.5-pro:generateContent", "gemini-2.5-pro", "")
-	ensureModel(&config.Codex.Models, "AiCodeMirror", "https://api.aicodemirror.com/api/codex/backend-api/codex", "gpt-5.2-codex", "responses")
-	ensureModel(&config.Codex.Models, "CodeRelay", "https://api.code-relay.com/v1", "gpt-5.2-codex", "responses")
-	ensureModel(&config.Codex.Models, "ChatFire", "https://api.chatfire.cn/v1", "gpt-5.1-codex-mini", "responses")
-	ensureModel(&config.Codex.Models, "DeepSeek", "https://api.deepseek.com/v1", "deepseek-chat", "")
-	ensureModel(&config.Codex.Models, "GLM", "https://open.bigmodel.cn/api/coding/paas/v4", "glm-4.7", "")
-	ensureModel(&config.Codex.Models, "Doubao", "https://ark.cn-beijing.volces.com/api/coding/v3", "doubao-seed-code-preview-latest", "")
-	ensureModel(&config.Codex.Models, "Kimi", "https://api.kimi.com/coding/v1", "kimi-for-coding", "")
-	ensureModel(&config.Codex.Models, "MiniMax", "https://api.minimaxi.com/v1", "MiniMax-M2.1", "")
-	ensureModel(&config.Codex.Models, "XiaoMi", "https://api.xiaomimimo.com/v1", "mimo-v2-flash", "")
-	ensureModel(&config.Codex.Models, "摩尔线程", "https://coding-plan-endpoint.kuaecloud.net/v1", "GLM-4.7", "")
-	ensureModel(&config.Codex.Models, "快手", "https://wanqing.streamlakeapi.com/api/gateway/coding/v1", "kat-coder-pro-v1", "")
-	ensureModel(&config.Opencode.Models, "DeepSeek", "https://api.deepseek.com/v1", "deepseek-chat", "")
-	ensureModel(&config.Opencode.Models, "ChatFire", "https://api.chatfire.cn/v1", "gpt-4o", "")
-	ensureModel(&config.Opencode.Models, "GLM", "https://open.bigmodel.cn/api/coding/paas/v4", "glm-4.7", "")
-	ensureModel(&config.Opencode.Models, "Doubao", "https://ark.cn-beijing.volces.com/api/coding/v3", "doubao-seed-code-preview-latest", "")
-	ensureModel(&config.Opencode.Models, "Kimi", "https://api.kimi.com/coding/v1", "kimi-for-coding", "")
-	ensureModel(&config.Opencode.Models, "MiniMax", "https://api.minimaxi.com/v1", "MiniMax-M2.1", "")
-	ensureModel(&config.Opencode.Models, "XiaoMi", "https://api.xiaomimimo.com/v1", "mimo-v2-flash", "")
-	ensureModel(&config.Opencode.Models, "摩尔线程", "https://coding-plan-endpoint.kuaecloud.net/v1", "GLM-4.7", "")
-	ensureModel(&config.Opencode.Models, "快手", "https://wanqing.streamlakeapi.com/api/gateway/coding/v1", "kat-coder-pro-v1", "")
-	ensureModel(&config.CodeBuddy.Models, "DeepSeek", "https://api.deepseek.com/v1", "deepseek-chat", "")
-	ensureModel(&config.CodeBuddy.Models, "GLM", "https://open.bigmodel.cn/api/coding/paas/v4", "glm-4.7", "")
-	ensureModel(&config.CodeBuddy.Models, "Doubao", "https://ark.cn-beijing.volces.com/api/coding/v3", "doubao-seed-code-preview-latest", "")
-	ensureModel(&config.CodeBuddy.Models, "Kimi", "https://api.kimi.com/coding/v1", "kimi-for-coding", "")
-	ensureModel(&config.CodeBuddy.Models, "MiniMax", "https://api.minimaxi.com/v1", "MiniMax-M2.1", "")
-	ensureModel(&config.CodeBuddy.Models, "XiaoMi", "https://api.xiaomimimo.com/v1", "mimo-v2-flash", "")
-	ensureModel(&config.CodeBuddy.Models, "摩尔线程", "https://coding-plan-endpoint.kuaecloud.net/v1", "GLM-4.7", "")
-	ensureModel(&config.CodeBuddy.Models, "快手", "https://wanqing.streamlakeapi.com/api/gateway/coding/v1", "kat-coder-pro-v1", "")
-	ensureModel(&config.IFlow.Models, "DeepSeek", "https://api.deepseek.com/v1", "deepseek-chat", "")
-	ensureModel(&config.IFlow.Models, "GLM", "https://open.bigmodel.cn/api/coding/paas/v4", "glm-4.7", "")
-	ensureModel(&config.IFlow.Models, "Doubao", "https://ark.cn-beijing.volces.com/api/coding/v3", "doubao-seed-code-preview-latest", "")
-	ensureModel(&config.IFlow.Models, "Kimi", "https://api.kimi.com/coding/v1", "kimi-for-coding", "")
-	ensureModel(&config.IFlow.Models, "MiniMax", "https://api.minimaxi.com/v1", "MiniMax-M2.1", "")
-	ensureModel(&config.IFlow.Models, "XiaoMi", "https://api.xiaomimimo.com/v1", "mimo-v2-flash", "")
-	ensureModel(&config.IFlow.Models, "摩尔线程", "https://coding-plan-endpoint.kuaecloud.net/v1", "GLM-4.7", "")
-	ensureModel(&config.IFlow.Models, "快手", "https://wanqing.streamlakeapi.com/api/gateway/coding/v1", "kat-coder-pro-v1", "")
-	ensureModel(&config.Kilo.Models, "ChatFire", "https://api.chatfire.cn/v1", "gpt-4o", "")
-	ensureModel(&config.Kilo.Models, "DeepSeek", "https://api.deepseek.com/v1", "deepseek-chat", "")
-	ensureModel(&config.Kilo.Models, "GLM", "https://open.bigmodel.cn/api/coding/paas/v4", "glm-4.7", "")
-	ensureModel(&config.Kilo.Models, "Doubao", "https://ark.cn-beijing.volces.com/api/coding/v3", "doubao-seed-code-preview-latest", "")
-	ensureModel(&config.Kilo.Models, "Kimi", "https://api.kimi.com/coding/v1", "kimi-for-coding", "")
-	ensureModel(&config.Kilo.Models, "MiniMax", "https://api.minimaxi.com/v1", "MiniMax-M2.1", "")
-	ensureModel(&config.Kilo.Models, "XiaoMi", "https://api.xiaomimimo.com/v1", "mimo-v2-flash", "")
-	ensureModel(&config.Kilo.Models, "摩尔线程", "https://coding-plan-endpoint.kuaecloud.net/v1", "GLM-4.7", "")
-	ensureModel(&config.Kilo.Models, "快手", "https://wanqing.streamlakeapi.com/api/gateway/coding/v1", "kat-coder-pro-v1", "")
-	ensureModel(&config.Kode.Models, "ChatFire", "https://api.chatfire.cn/v1", "gpt-4o", "")
-	ensureModel(&config.Kode.Models, "DeepSeek", "https://api.deepseek.com/v1", "deepseek-chat", "")
-	ensureModel(&config.Kode.Models, "GLM", "https://open.bigmodel.cn/api/coding/paas/v4", "glm-4.7", "")
-	ensureModel(&config.Kode.Models, "Doubao", "https://ark.cn-beijing.volces.com/api/coding/v3", "doubao-seed-code-preview-latest", "")
-	ensureModel(&config.Kode.Models, "Kimi", "https://api.kimi.com/coding/v1", "kimi-for-coding", "")
-	ensureModel(&config.Kode.Models, "MiniMax", "https://api.minimaxi.com/v1", "MiniMax-M2.1", "")
-	ensureModel(&config.Kode.Models, "XiaoMi", "https://api.xiaomimimo.com/v1", "mimo-v2-flash", "")
-	ensureModel(&config.Kode.Models, "摩尔线程", "https://coding-plan-endpoint.kuaecloud.net/v1", "GLM-4.7", "")
-	ensureModel(&config.Kode.Models, "快手", "https://wanqing.streamlakeapi.com/api/gateway/coding/v1", "kat-coder-pro-v1", "")
+	}
+	// Add missing catalog providers, refresh their endpoints and drop
+	// providers a tool no longer offers (including duplicates under old aliases)
+	catalog.applyCatalog(config)
 	// Ensure 'Original' is always present and first
 	ensureOriginal := func(models *[]ModelConfig) {
 		found := false
@@ -2047,88 +1765,6 @@ func (a *App) LoadConfig() (AppConfig, error) {
 			*models = append([]ModelConfig{{ModelName: "Original", ModelUrl: "", ApiKey: ""}}, *models...)
 		}
 	}
-	// Opencode does NOT use common relay providers
-	cleanOpencodeModels := func(models *[]ModelConfig) {
-		var newModels []ModelConfig
-		for _, m := range *models {
-			name := strings.ToLower(m.ModelName)
-			if name != "aigocode" && name != "aicodemirror" && name != "coderelay" && name != "chatfire" {
-				newModels = append(newModels, m)
-			}
-		}
-		*models = newModels
-	}
-	ensureOriginal(&config.Claude.Models)
-	ensureOriginal(&config.Gemini.Models)
-	ensureOriginal(&config.Codex.Models)
-	ensureOriginal(&config.Opencode.Models)
-	ensureOriginal(&config.CodeBuddy.Models)
-	ensureOriginal(&config.Qoder.Models)
-	ensureOriginal(&config.IFlow.Models)
-	ensureOriginal(&config.Kilo.Models)
-	cleanOpencodeModels(&config.Opencode.Models)
-	cleanOpencodeModels(&config.CodeBuddy.Models)
-	cleanOpencodeModels(&config.IFlow.Models)
-	// Ensure at least 2 custom models are always present
-	// Custom models are identified by IsCustom flag, not by name
-	ensureCustom := func(models *[]ModelConfig) {
-		customCount := 0
-		for _, m := range *models {
-			if m.IsCustom {
-				customCount++
-			}
-		}
-		// Ensure at least 2 custom models exist
-		for customCount < 2 {
-			customCount++
-			name := "Custom"
-			if customCount > 1 {
-				name = fmt.Sprintf("Custom%d", customCount-1)
-			}
-			*models = append(*models, ModelConfig{ModelName: name, ModelUrl: "", ApiKey: "", IsCustom: true})
-		}
-	}
-	ensureCustom(&config.Claude.Models)
-	ensureCustom(&config.Gemini.Models)
-	ensureCustom(&config.Codex.Models)
-	ensureCustom(&config.Opencode.Models)
-	ensureCustom(&config.CodeBuddy.Models)
-	ensureCustom(&config.IFlow.Models)
-	ensureCustom(&config.Kilo.Models)
-	ensureCustom(&config.Kode.Models)
-	// Qoder only has Original and Qoder
-	// Preserve existing Qoder key if present
-	var existingQoderKey string
-	for _, m := range config.Qoder.Models {
-		if m.ModelName == "Qoder" {
-			existingQoderKey = m.ApiKey
-			break
-		}
-	}
-	config.Qoder.Models = defaultQoderModels
-	if existingQoderKey != "" {
-		for i := range config.Qoder.Models {
-			if config.Qoder.Models[i].ModelName == "Qoder" {
-				config.Qoder.Models[i].ApiKey = existingQoderKey
-				break
-			}
-		}
-	}
-	// Ensure custom models are always last for all tools
-	// Custom models are identified by IsCustom flag, not by name
-	moveCustomToLast := func(models *[]ModelConfig) {
-		var customModels []ModelConfig
-		var newModels []ModelConfig
-		for _, m := range *models {
-			if m.IsCustom {
-				customModels = append(customModels, m)
-			} else {
-				newModels = append(newModels, m)
-			}
-		}
-		// Append all custom models at the end
-		*models = append(newModels, customModels...)
-	}
 	// Ensure 'Original' is always first for all tools
 	ensureOriginalFirst := func(models *[]ModelConfig) {
 		var originalModel *ModelConfig
@@ -2145,77 +1781,49 @@ func (a *App) LoadConfig() (AppConfig, error) {
 			*models = append([]ModelConfig{*originalModel}, newModels...)
 		}
 	}
-	moveCustomToLast(&config.Claude.Models)
-	moveCustomToLast(&config.Gemini.Models)
-	moveCustomToLast(&config.Codex.Models)
-	moveCustomToLast(&config.Opencode.Models)
-	moveCustomToLast(&config.CodeBuddy.Models)
-	moveCustomToLast(&config.IFlow.Models)
-	moveCustomToLast(&config.Kilo.Models)
-	moveCustomToLast(&config.Kode.Models)
-	ensureOriginalFirst(&config.Claude.Models)
-	ensureOriginalFirst(&config.Gemini.Models)
-	ensureOriginalFirst(&config.Codex.Models)
-	ensureOriginalFirst(&config.Opencode.Models)
-	ensureOriginalFirst(&config.CodeBuddy.Models)
-	ensureOriginalFirst(&config.Qoder.Models)
-	ensureOriginalFirst(&config.IFlow.Models)
-	ensureOriginalFirst(&config.Kilo.Models)
-	// Ensure CurrentModel is valid
-	if config.Gemini.CurrentModel == "" {
-		config.Gemini.CurrentModel = "Original"
-	}
-	if config.Codex.CurrentModel == "" {
-		config.Codex.CurrentModel = "Original"
-	}
-	if config.Opencode.CurrentModel == "" {
-		config.Opencode.CurrentModel = "Original"
-	}
-	if config.CodeBuddy.CurrentModel == "" {
-		config.CodeBuddy.CurrentModel = "Original"
-	}
-	if config.Qoder.CurrentModel == "" {
-		config.Qoder.CurrentModel = "Original"
-	}
-	if config.IFlow.CurrentModel == "" {
-		config.IFlow.CurrentModel = "Original"
-	}
-	if config.Kilo.Models == nil || len(config.Kilo.Models) == 0 {
-		config.Kilo.Models = defaultKiloModels
-		config.Kilo.CurrentModel = "Original"
-	}
-	if config.Kilo.CurrentModel == "" {
-		config.Kilo.CurrentModel = "Original"
-	}
-	if config.Kode.Models == nil || len(config.Kode.Models) == 0 {
-		config.Kode.Models = defaultKodeModels
-		config.Kode.CurrentModel = "ChatFire"
-	}
-	if config.Kode.CurrentModel == "" {
-		config.Kode.CurrentModel = "ChatFire"
+	ensureSharedCustomProviders(config)
+	for _, tool := range toolNames {
+		toolCfg := getToolConfig(config, tool)
+		defaults := catalog.defaults(tool)
+		if defaults.Original {
+			ensureOriginal(&toolCfg.Models)
+		}
+		ensureOriginalFirst(&toolCfg.Models)
+		// Ensure CurrentModel is valid
+		if toolCfg.CurrentModel == "" {
+			if defaults.Original {
+				toolCfg.CurrentModel = "Original"
+			} else {
+				toolCfg.CurrentModel = defaults.DefaultProvider
+			}
+		}
 	}
 	if config.ActiveTool == "" {
 		config.ActiveTool = "message"
 	}
 	// Normalize CurrentModel casing for all tools
-	normalizeCurrentModel := func(toolCfg *ToolConfig) {
+	normalizeCurrentModel := func(toolCfg *ToolConfig) bool {
 		for _, m := range toolCfg.Models {
 			if strings.EqualFold(m.ModelName, toolCfg.CurrentModel) {
 				toolCfg.CurrentModel = m.ModelName
-				break
+				return true
+			}
+		}
+		return false
+	}
+	for _, tool := range toolNames {
+		toolCfg := getToolConfig(config, tool)
+		for i := range toolCfg.Models {
+			normalizeApiKeys(&toolCfg.Models[i])
+		}
+		if !normalizeCurrentModel(toolCfg) && len(toolCfg.Models) > 0 {
+			// The selected provider is no longer offered for this tool
+			toolCfg.CurrentModel = toolCfg.Models[0].ModelName
+			if defaults := catalog.defaults(tool); !defaults.Original && defaults.DefaultProvider != "" {
+				toolCfg.CurrentModel = defaults.DefaultProvider
 			}
 		}
 	}
-	normalizeCurrentModel(&config.Claude)
-	normalizeCurrentModel(&config.Gemini)
-	normalizeCurrentModel(&config.Codex)
-	normalizeCurrentModel(&config.Opencode)
-	normalizeCurrentModel(&config.CodeBuddy)
-	normalizeCurrentModel(&config.Qoder)
-	normalizeCurrentModel(&config.IFlow)
-	normalizeCurrentModel(&config.Kilo)
-	normalizeCurrentModel(&config.Kode)
-	return config, nil
 }
 // getProviderModel gets the model for a specific provider name from a tool config
 func getProviderModel(toolConfig *ToolConfig, providerName string) *ModelConfig {
@@ -2226,97 +1834,26 @@ func getProviderModel(toolConfig *ToolConfig, providerName string) *ModelConfig
 	}
 	return nil
 }
-// syncAllProviderApiKeys synchronizes apikeys of all providers (except 'Original' and 'Custom') across all tools
-func syncAllProviderApiKeys(a *App, oldConfig, newConfig *AppConfig) {
-	// Map of tools for easy access
-	tools := map[string]*ToolConfig{
-		"claude":    &newConfig.Claude,
-		"gemini":    &newConfig.Gemini,
-		"codex":     &newConfig.Codex,
-		"opencode":  &newConfig.Opencode,
-		"codebuddy": &newConfig.CodeBuddy,
-		"qoder":     &newConfig.Qoder,
-		"iflow":     &newConfig.IFlow,
-		"kilo":      &newConfig.Kilo,
-		"kode":      &newConfig.Kode,
-	}
-	oldTools := map[string]*ToolConfig{
-		"claude":    &oldConfig.Claude,
-		"gemini":    &oldConfig.Gemini,
-		"codex":     &oldConfig.Codex,
-		"opencode":  &oldConfig.Opencode,
-		"codebuddy": &oldConfig.CodeBuddy,
-		"qoder":     &oldConfig.Qoder,
-		"iflow":     &oldConfig.IFlow,
-		"kilo":      &oldConfig.Kilo,
-		"kode":      &oldConfig.Kode,
-	}
-	// providerName (lower) -> intended API key
-	intentions := make(map[string]string)
-	activeToolName := strings.ToLower(newConfig.ActiveTool)
-	// 1. Detect Intent from Active Tool (Highest Priority)
-	if activeTool, ok := tools[activeToolName]; ok {
-		oldActive := oldTools[activeToolName]
-		if oldActive != nil {
-			for _, m := range activeTool.Models {
-				if strings.EqualFold(m.ModelName, "Original") || strings.EqualFold(m.ModelName, "Custom") || m.IsCustom {
-					continue
-				}
-				oldM := getProviderModel(oldActive, m.ModelName)
-				// If key changed or a new key was added where none existed
-				if (oldM != nil && m.ApiKey != oldM.ApiKey) || (oldM == nil && m.ApiKey != "") {
-					intentions[strings.ToLower(m.ModelName)] = m.ApiKey
-					a.log(fmt.Sprintf("Sync: detected %s intent from active tool %s", m.ModelName, activeToolName))
-				}
-			}
-		}
-	}
-	// 2. Detect Intent from other tools (if not already captured from active tool)
-	for name, tool := range tools {
-		if name == activeToolName {
-			continue
-		}
-		oldTool := oldTools[name]
-		if oldTool == nil {
-			continue
-		}
-		for _, m := range tool.Models {
-			if strings.EqualFold(m.ModelName, "Original") || strings.EqualFold(m.ModelName, "Custom") || m.IsCustom {
-				continue
-			}
-			lowerName := strings.ToLower(m.ModelName)
-			if _, handled := intentions[lowerName]; handled {
-				continue
-			}
-			oldM := getProviderModel(oldTool, m.ModelName)
-			if (oldM != nil && m.ApiKey != oldM.ApiKey) || (oldM == nil && m.ApiKey != "") {
-				intentions[lowerName] = m.ApiKey
-				a.log(fmt.Sprintf("Sync: detected %s intent from tool %s", m.ModelName, name))
+// syncAllProviderApiKeys copies changed API keys to the same provider in the other tools,
+// following each provider's sync policy. Providers whose key was changed to different
+// values in several tools are left alone and reported as conflicts.
+func syncAllProviderApiKeys(a *App, oldConfig, newConfig *AppConfig) KeySyncPlan {
+	plan := planApiKeySync(oldConfig, newConfig)
+	for _, c := range plan.Changes {
+		a.log(fmt.Sprintf("Sync: copying %s key from %s to %s", c.Provider, c.SourceTool, strings.Join(c.Tools, ", ")))
+		for _, tool := range c.Tools {
+			if m := getProviderModel(getToolConfig(newConfig, tool), c.Provider); m != nil {
+				setActiveApiKey(m, c.key)
 			}
 		}
 	}
-	// 3. Propagate all intentions to ALL tools
-	for providerLower, targetKey := range intentions {
-		for _, tool := range tools {
-			for i := range tool.Models {
-				if strings.ToLower(tool.Models[i].ModelName) == providerLower {
-					if tool.Models[i].ApiKey != targetKey {
-						tool.Models[i].ApiKey = targetKey
-					}
-				}
-			}
-		}
+	for _, c := range plan.Conflicts {
+		a.log(fmt.Sprintf("Sync: %s key changed differently in %s, not synchronized", c.Provider, strings.Join(c.Tools, ", ")))
 	}
+	return plan
 }
 func (a *App) SaveConfig(config AppConfig) error {
-	// Sanitize: Ensure Custom models have a name (prevent empty tab button)
-	sanitizeCustomNames := func(models []ModelConfig) {
-		for i := range models {
-			if models[i].IsCustom && strings.TrimSpace(models[i].ModelName) == "" {
-				models[i].ModelName = "Custom"
-			}
-		}
-	}
+	// Sanitize: Ensure Custom models have a unique name (prevent empty tab button)
 	sanitizeCustomNames(config.Claude.Models)
 	sanitizeCustomNames(config.Gemini.Models)
 	sanitizeCustomNames(config.Codex.Models)
@@ -2326,28 +1863,55 @@ func (a *App) SaveConfig(config AppConfig) error {
 	sanitizeCustomNames(config.IFlow.Models)
 	sanitizeCustomNames(config.Kilo.Models)
 	sanitizeCustomNames(config.Kode.Models)
-	// Load old config to compare for sync logic
-	var oldConfig AppConfig
-	path, _ := a.getConfigPath()
-	if data, err := os.ReadFile(path); err == nil {
-		json.Unmarshal(data, &oldConfig)
-	}
-	// Sync all apikeys across all tools before saving
-	syncAllProviderApiKeys(a, &oldConfig, &config)
-	if err := a.saveToPath(path, config); err != nil {
+	// Only what the UI changed is applied, so a change committed meanwhile, e.g. from
+	// the tray, is not undone by the UI's older copy
+	_, err := a.configStore().Save(configSourceUI, config)
+	return err
+}
+// persistConfig writes a change made through the config store. API keys changed
+// in one tool are first copied to the same provider in the others.
+func (a *App) persistConfig(old, next *AppConfig) error {
+	path, err := a.getConfigPath()
+	if err != nil {
 		return err
 	}
-	if OnConfigChanged != nil {
-		OnConfigChanged(config)
+	plan := syncAllProviderApiKeys(a, old, next)
+	if err := a.saveToPath(path, *next); err != nil {
+		return err
+	}
+	if len(plan.Conflicts) > 0 {
+		a.emitEvent("api-key-sync-conflict", plan.Conflicts)
 	}
 	return nil
 }
+// writeConfig persists a change through the config store without key sync, for
+// changes that are not edits, such as the key chosen for a launch.
+func (a *App) writeConfig(old, next *AppConfig) error {
+	path, err := a.getConfigPath()
+	if err != nil {
+		return err
+	}
+	return a.saveToPath(path, *next)
+}
 func (a *App) saveToPath(path string, config AppConfig) error {
-	data, err := json.MarshalIndent(config, "", "  ")
+	config.Revision = 0
+	a.redactor.setConfig(&config)
+	// Only references to the secrets go into the file
+	stored, refs, err := a.secrets().externalize(config, a.log)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+	oldRefs := secretRefsInFile(path)
+	a.backupConfigFile(path, data)
+	if err := writePrivateFile(path, data); err != nil {
+		return err
+	}
+	a.secrets().removeUnused(oldRefs, refs)
+	return nil
 }
 type UpdateResult struct {
 	HasUpdate     bool   `json:"has_update"`
@@ -2359,80 +1923,25 @@ type UpdateResult struct {
 func (a *App) CheckUpdate(currentVersion string) (UpdateResult, error) {
 	// Use GitHub API instead of web scraping
 	// Updated URL: aicoder instead of cceasy
-	url := "https://api.github.com/repos/RapidAI/aicoder/releases/latest"
-	a.log(a.tr("CheckUpdate: Starting check against %s", url))
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		a.log(a.tr("CheckUpdate: Failed to create request: %v", err))
-		return UpdateResult{LatestVersion: "检查失败", ReleaseUrl: ""}, err
-	}
-	req.Header.Set("User-Agent", "AICoder")
-	// Add GitHub token for authentication (helps avoid rate limiting)
-	// Priority: 1) GITHUB_TOKEN environment variable, 2) Built-in default token (base64 encoded 3 times)
-	const defaultGitHubTokenEncoded = "V2pKb2QxZ3hjREJPVmtZeVVXNXNUV0ZZVmtOaFZFSktWbXBuTWxsWVNrOVNhbWhYWTI1a1ZsRlVUbXBWZWtaUVlsWk9TR1IzUFQwPQ=="
-	token := os.Getenv("GITHUB_TOKEN")
-	if token == "" {
-		// Decode the base64 encoded token (3 times)
-		decoded := defaultGitHubTokenEncoded
-		for i := 0; i < 3; i++ {
-			decodedBytes, err := base64.StdEncoding.DecodeString(decoded)
-			if err != nil {
-				a.log(a.tr("CheckUpdate: Failed to decode token at iteration %d: %v", i+1, err))
-				decoded = ""
-				break
-			}
-			decoded = string(decodedBytes)
-		}
-		if decoded != "" {
-			token = decoded
-			a.log(a.tr("CheckUpdate: Using built-in GitHub token for authentication"))
-		}
-	} else {
-		a.log(a.tr("CheckUpdate: Using custom GitHub token from environment variable"))
-	}
-	if token != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
-	}
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	path := "/repos/RapidAI/aicoder/releases/latest"
+	a.log(a.tr("CheckUpdate: Starting check against %s", githubAPIBase+path))
+	body, err := a.githubAPI().get(path, "application/vnd.github+json")
 	if err != nil {
 		a.log(a.tr("CheckUpdate: Failed to fetch GitHub API: %v", err))
-		return UpdateResult{LatestVersion: "网络错误", ReleaseUrl: ""}, err
-	}
-	defer resp.Body.Close()
-	a.log(a.tr("CheckUpdate: HTTP Status: %d", resp.StatusCode))
-	// Log rate limit headers for debugging
-	a.log(a.tr("CheckUpdate: Rate Limit: %s/%s, Reset: %s",
-		resp.Header.Get("X-RateLimit-Remaining"),
-		resp.Header.Get("X-RateLimit-Limit"),
-		resp.Header.Get("X-RateLimit-Reset")))
-	// Check HTTP status
-	if resp.StatusCode != 200 {
-		a.log(a.tr("CheckUpdate: GitHub API returned status %d", resp.StatusCode))
-		bodyText, _ := io.ReadAll(resp.Body)
-		a.log(a.tr("CheckUpdate: Response: %s", string(bodyText[:min(len(bodyText), 200)])))
-		// Provide specific error message for rate limiting
-		if resp.StatusCode == 403 {
-			remaining := resp.Header.Get("X-RateLimit-Remaining")
-			if remaining == "0" {
-				resetTime := resp.Header.Get("X-RateLimit-Reset")
-				a.log(a.tr("CheckUpdate: Rate limit exceeded, resets at: %s", resetTime))
-				return UpdateResult{LatestVersion: "速率限制", ReleaseUrl: ""},
-					fmt.Errorf("github api rate limit exceeded (resets at %s)", resetTime)
-			}
-			return UpdateResult{LatestVersion: "访问受限", ReleaseUrl: ""},
-				fmt.Errorf("github api access forbidden (status 403)")
+		var limited *githubRateLimitError
+		var status *githubStatusError
+		switch {
+		case errors.As(err, &limited):
+			return UpdateResult{LatestVersion: "速率限制", ReleaseUrl: ""}, err
+		case errors.As(err, &status) && status.Code == http.StatusForbidden:
+			return UpdateResult{LatestVersion: "访问受限", ReleaseUrl: ""}, err
+		case errors.As(err, &status):
+			return UpdateResult{LatestVersion: "API错误", ReleaseUrl: ""}, err
 		}
-		return UpdateResult{LatestVersion: "API错误", ReleaseUrl: ""}, fmt.Errorf("github api returned status %d", resp.StatusCode)
-	}
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		a.log(a.tr("CheckUpdate: Failed to read response body: %v", err))
-		return UpdateResult{LatestVersion: "读取失败", ReleaseUrl: ""}, err
+		return UpdateResult{LatestVersion: "网络错误", ReleaseUrl: ""}, err
 	}
 	// Log raw response for debugging
 	a.log(a.tr("CheckUpdate: Raw response length: %d bytes", len(body)))
-	a.log(a.tr("CheckUpdate: Response body: %s", string(body[:min(len(body), 500)])))
 	// Parse JSON response
 	var release map[string]interface{}
 	if err := json.Unmarshal(body, &release); err != nil {
@@ -2719,7 +2228,7 @@ func (a *App) ShowMessage(title, message string) {
 }
 func (a *App) emitEvent(name string, data ...interface{}) {
 	if a.ctx != nil {
-		runtime.EventsEmit(a.ctx, name, data...)
+		runtime.EventsEmit(a.ctx, name, a.redactEventData(name, data)...)
 	}
 }
 func (a *App) ClipboardGetText() (string, error) {
@@ -2740,60 +2249,15 @@ func (a *App) ClipboardGetText() (string, error) {
 	return "", nil
 }
 func (a *App) fetchRemoteMarkdown(repo, file string) (string, error) {
-	// Use GitHub API with timestamp to bypass all caches
-	url := fmt.Sprintf("https://api.github.com/repos/%s/contents/%s?ref=main&t=%d", repo, file, time.Now().UnixNano())
-	// Create a new transport to avoid connection reuse caching
-	transport := &http.Transport{
-		DisableKeepAlives: true,
-		ForceAttemptHTTP2: false,
-	}
-	client := &http.Client{
-		Timeout:   15 * time.Second,
-		Transport: transport,
-	}
-	req, err := http.NewRequest("GET", url, nil)
+	// The client revalidates its cached copy, so an unchanged file costs no rate limit
+	data, err := a.githubAPI().get(fmt.Sprintf("/repos/%s/contents/%s?ref=main", repo, file), "application/vnd.github.v3.raw")
 	if err != nil {
-		return "Failed to create request: " + err.Error(), nil
-	}
-	// GitHub API headers - request raw content directly
-	req.Header.Set("Accept", "application/vnd.github.v3.raw")
-	req.Header.Set("User-Agent", "AICoder-App")
-	req.Header.Set("Cache-Control", "no-cache, no-store")
-	req.Header.Set("Pragma", "no-cache")
-	// Add GitHub token for authentication (helps avoid rate limiting)
-	// Priority: 1) GITHUB_TOKEN environment variable, 2) Built-in default token (base64 encoded 3 times)
-	const defaultGitHubTokenEncoded = "V2pKb2QxZ3hjREJPVmtZeVVXNXNUV0ZZVmtOaFZFSktWbXBuTWxsWVNrOVNhbWhYWTI1a1ZsRlVUbXBWZWtaUVlsWk9TR1IzUFQwPQ=="
-	token := os.Getenv("GITHUB_TOKEN")
-	if token == "" {
-		// Decode the base64 encoded token (3 times)
-		decoded := defaultGitHubTokenEncoded
-		for i := 0; i < 3; i++ {
-			decodedBytes, err := base64.StdEncoding.DecodeString(decoded)
-			if err != nil {
-				decoded = ""
-				break
-			}
-			decoded = string(decodedBytes)
-		}
-		if decoded != "" {
-			token = decoded
+		var status *githubStatusError
+		if errors.As(err, &status) {
+			return fmt.Sprintf("Remote content unavailable (Status: %d %s)", status.Code, status.Status), nil
 		}
-	}
-	if token != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
-	}
-	resp, err := client.Do(req)
-	if err != nil {
 		return "Failed to fetch remote message: " + err.Error(), nil
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Sprintf("Remote content unavailable (Status: %d %s)", resp.StatusCode, resp.Status), nil
-	}
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "Error reading remote content: " + err.Error(), nil
-	}
 	return string(data), nil
 }
 func (a *App) ReadBBS() (string, error) {
@@ -3230,8 +2694,8 @@ func (a *App) PackLog(logContent string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to create file in zip: %w", err)
 	}
-	// Write content
-	_, err = f.Write([]byte(logContent))
+	// Write content, without the secrets, so the zip can be attached to issue reports
+	_, err = f.Write([]byte(a.redactor.redact(logContent)))
 	if err != nil {
 		return "", fmt.Errorf("failed to write content to zip: %w", err)
 	}
@@ -3603,53 +3067,44 @@ func (a *App) InstallDefaultMarketplace() error {
 		return fmt.Errorf("failed to create .claude directory: %v", err)
 	}
 
-	// Read existing settings
-	var settings map[string]interface{}
-	if data, err := os.ReadFile(settingsFile); err == nil {
-		if err := json.Unmarshal(data, &settings); err != nil {
-			settings = make(map[string]interface{})
-		}
-	} else {
-		settings = make(map[string]interface{})
+	// Read existing settings, keeping key order and everything we don't manage
+	settings, err := readOrderedJSONFile(settingsFile)
+	if err != nil {
+		return err
 	}
 
 	// Ensure extraKnownMarketplaces exists
-	marketplaces, ok := settings["extraKnownMarketplaces"].(map[string]interface{})
-	if !ok {
-		marketplaces = make(map[string]interface{})
-	}
+	marketplaces := settings.object("extraKnownMarketplaces")
 
 	changed := false
 
 	// Add anthropic-agent-skills marketplace (anthropics/skills repo)
-	if _, exists := marketplaces["anthropic-agent-skills"]; !exists {
-		marketplaces["anthropic-agent-skills"] = map[string]interface{}{
+	if !marketplaces.has("anthropic-agent-skills") {
+		marketplaces.set("anthropic-agent-skills", map[string]interface{}{
 			"source": map[string]interface{}{
 				"source": "github",
 				"repo":   "anthropics/skills",
 			},
-		}
+		})
 		changed = true
 	}
 
 	// Add superpowers-marketplace (obra/superpowers-marketplace repo)
-	if _, exists := marketplaces["superpowers-marketplace"]; !exists {
-		marketplaces["superpowers-marketplace"] = map[string]interface{}{
+	if !marketplaces.has("superpowers-marketplace") {
+		marketplaces.set("superpowers-marketplace", map[string]interface{}{
 			"source": map[string]interface{}{
 				"source": "github",
 				"repo":   "obra/superpowers-marketplace",
 			},
-		}
+		})
 		changed = true
 	}
 
 	if changed {
-		settings["extraKnownMarketplaces"] = marketplaces
-		data, err := json.MarshalIndent(settings, "", "  ")
-		if err != nil {
+		if err := settings.set("extraKnownMarketplaces", marketplaces); err != nil {
 			return fmt.Errorf("failed to marshal settings: %v", err)
 		}
-		if err := os.WriteFile(settingsFile, data, 0644); err != nil {
+		if err := writeOrderedJSONFile(settingsFile, settings); err != nil {
 			return fmt.Errorf("failed to write settings: %v", err)
 		}
 		a.log("Default marketplaces added to ~/.claude/settings.json")
@@ -3745,25 +3200,16 @@ func (a *App) InstallSkill(name, description, skillType, value, location, projec
 			// Enable plugin in ~/.claude/settings.json
 			home, _ := os.UserHomeDir()
 			settingsFile := filepath.Join(home, ".claude", "settings.json")
-			var settings map[string]interface{}
-			if data, err := os.ReadFile(settingsFile); err == nil {
-				if err := json.Unmarshal(data, &settings); err != nil {
-					settings = make(map[string]interface{})
-				}
-			} else {
-				settings = make(map[string]interface{})
-			}
-			enabledPlugins, ok := settings["enabledPlugins"].(map[string]interface{})
-			if !ok {
-				enabledPlugins = make(map[string]interface{})
-			}
-			enabledPlugins[value] = true
-			settings["enabledPlugins"] = enabledPlugins
-			data, err := json.MarshalIndent(settings, "", "  ")
+			settings, err := readOrderedJSONFile(settingsFile)
 			if err != nil {
+				return err
+			}
+			enabledPlugins := settings.object("enabledPlugins")
+			enabledPlugins.set(value, true)
+			if err := settings.set("enabledPlugins", enabledPlugins); err != nil {
 				return fmt.Errorf("failed to marshal settings: %v", err)
 			}
-			if err := os.WriteFile(settingsFile, data, 0644); err != nil {
+			if err := writeOrderedJSONFile(settingsFile, settings); err != nil {
 				return fmt.Errorf("failed to write settings: %v", err)
 			}
 			a.log(fmt.Sprintf("Plugin %s enabled in settings.json", value))
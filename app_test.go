@@ -0,0 +1,303 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// renderCodexConfig renders config.toml for m over existing, with HOME in a temp dir.
+func renderCodexConfig(t *testing.T, m ModelConfig, existing string) *tomlDocument {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if existing != "" {
+		os.MkdirAll(filepath.Join(home, ".codex"), 0700)
+		if err := os.WriteFile(filepath.Join(home, ".codex", "config.toml"), []byte(existing), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := NewApp().renderCodexSettings(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if strings.HasSuffix(f.Path, "config.toml") {
+			doc, err := parseToml(string(f.Content))
+			if err != nil {
+				t.Fatalf("rendered config.toml does not parse: %v\n%s", err, f.Content)
+			}
+			return doc
+		}
+	}
+	t.Fatal("config.toml not rendered")
+	return nil
+}
+
+func TestRenderCodexSettingsKeepsUserLimits(t *testing.T) {
+	m := ModelConfig{ModelName: "Proxy", IsCustom: true, ModelUrl: "https://proxy.example/v1", ModelId: "m-1", ApiKey: "sk-1"}
+
+	// Set by the user: kept when the provider has no limit
+	doc := renderCodexConfig(t, m, "model_context_window = 64000\n")
+	if v, ok := doc.get(nil, "model_context_window"); !ok || v != int64(64000) {
+		t.Fatalf("user's model_context_window = %v, %v", v, ok)
+	}
+
+	// Written by AICoder for the previous provider: removed
+	doc = renderCodexConfig(t, m, "model_context_window = 200000 "+codexOwnedMark+"\n")
+	if _, ok := doc.get(nil, "model_context_window"); ok {
+		t.Fatal("model_context_window written by AICoder kept")
+	}
+
+	// A provider limit replaces either and is marked as AICoder's
+	m.Params.ContextWindow = 128000
+	doc = renderCodexConfig(t, m, "model_context_window = 64000\n")
+	if raw, _ := doc.rawValue(nil, "model_context_window"); raw != "128000 "+codexOwnedMark {
+		t.Fatalf("model_context_window = %q", raw)
+	}
+	if v, _ := doc.get(nil, "model_context_window"); v != int64(128000) {
+		t.Fatalf("marked value decodes as %v", v)
+	}
+}
+
+func TestRenderCodexSettingsReasoningEffort(t *testing.T) {
+	custom := ModelConfig{ModelName: "Proxy", IsCustom: true, ModelUrl: "https://proxy.example/v1", ModelId: "m-1", ApiKey: "sk-1"}
+	catalog := ModelConfig{ModelName: "CodeRelay", ApiKey: "sk-1"}
+	chosen := custom
+	chosen.Params.ReasoningEffort = "low"
+	for _, c := range []struct {
+		name     string
+		m        ModelConfig
+		existing string
+		want     string
+	}{
+		{"catalog default fills in", catalog, "", `"xhigh" ` + codexOwnedMark},
+		{"catalog default keeps the user's", catalog, "model_reasoning_effort = \"medium\"\n", `"medium"`},
+		{"catalog default replaces AICoder's", catalog, "model_reasoning_effort = \"low\" " + codexOwnedMark + "\n", `"xhigh" ` + codexOwnedMark},
+		{"stale effort replaced on switching", custom, "model_reasoning_effort = \"xhigh\" " + codexOwnedMark + "\n", `"high" ` + codexOwnedMark},
+		{"user's effort kept on switching", custom, "model_reasoning_effort = \"medium\"\n", `"medium"`},
+		{"chosen effort replaces the user's", chosen, "model_reasoning_effort = \"medium\"\n", `"low" ` + codexOwnedMark},
+	} {
+		doc := renderCodexConfig(t, c.m, c.existing)
+		if raw, _ := doc.rawValue(nil, "model_reasoning_effort"); raw != c.want {
+			t.Errorf("%s: model_reasoning_effort = %q, want %q", c.name, raw, c.want)
+		}
+	}
+	// Leaving for the official login removes only AICoder's value
+	for existing, want := range map[string]bool{"model_reasoning_effort = \"low\" " + codexOwnedMark + "\n": false, "model_reasoning_effort = \"low\"\n": true} {
+		doc := mustParseToml(t, existing)
+		setCodexReasoningEffort(doc, "", false)
+		if _, ok := doc.rawValue(nil, "model_reasoning_effort"); ok != want {
+			t.Errorf("%q: kept = %v", existing, ok)
+		}
+	}
+}
+
+// A provider table the user wrote inline or with dotted keys is edited where it
+// is; another [model_providers.<id>] table would make config.toml invalid.
+func TestRenderCodexSettingsProviderNotDefinedTwice(t *testing.T) {
+	m := ModelConfig{ModelName: "Proxy", IsCustom: true, ModelUrl: "https://proxy.example/v1", ModelId: "m-1", ApiKey: "sk-1"}
+	id := resolveProvider("codex", &m).Id
+	for _, existing := range []string{
+		"model_providers." + id + " = { name = \"mine\", base_url = \"https://old.example\", env_key = \"K\" }\n",
+		"model_providers = { " + id + " = { name = \"mine\" }, other = { name = \"o\" } }\n",
+		"[model_providers]\n" + id + ".base_url = \"https://old.example\"\n" + id + ".env_key = \"K\"\n",
+	} {
+		doc := renderCodexConfig(t, m, existing)
+		checkTomlTables(t, doc)
+		table := []string{"model_providers", id}
+		if v, _ := doc.get(table, "base_url"); v != m.ModelUrl {
+			t.Errorf("base_url = %v in\n%s", v, doc)
+		}
+		if strings.Contains(existing, "env_key") {
+			if v, _ := doc.get(table, "env_key"); v != "K" {
+				t.Errorf("user's env_key lost:\n%s", doc)
+			}
+		}
+	}
+}
+
+// claudeApproved renders the Claude settings for m and returns the approved keys
+// of ~/.claude.json, writing the files as a sync would.
+func claudeApproved(t *testing.T, m ModelConfig) []string {
+	t.Helper()
+	files, err := NewApp().renderClaudeSettings(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeRenderedFiles(files); err != nil {
+		t.Fatal(err)
+	}
+	_, _, legacyPath := NewApp().getClaudeConfigPaths()
+	claudeJson, err := readOrderedJSONFile(legacyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var approved []string
+	claudeJson.object("customApiKeyResponses").get("approved", &approved)
+	return approved
+}
+
+func TestRenderClaudeSettingsReplacesApprovedKey(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	legacy := `{"numStartups": 7, "customApiKeyResponses": {"approved": ["sk-users-own"], "rejected": ["sk-b"]}, "projects": {}}`
+	if err := os.WriteFile(filepath.Join(home, ".claude.json"), []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m := ModelConfig{ModelName: "Gateway", IsCustom: true, ModelUrl: "https://gw.example", ModelId: "claude-x", ApiKey: "sk-a"}
+	if got := strings.Join(claudeApproved(t, m), ","); got != "sk-users-own,sk-a" {
+		t.Fatalf("approved = %s", got)
+	}
+	// Switching replaces the key AICoder approved instead of adding another
+	m.ApiKey = "sk-b"
+	if got := strings.Join(claudeApproved(t, m), ","); got != "sk-users-own,sk-b" {
+		t.Fatalf("approved after switching = %s", got)
+	}
+	m.ApiKey = "sk-users-own"
+	if got := strings.Join(claudeApproved(t, m), ","); got != "sk-users-own" {
+		t.Fatalf("approved with the user's key = %s", got)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(home, ".claude.json"))
+	claudeJson, err := parseOrderedJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(claudeJson.keys, ","); got != "numStartups,customApiKeyResponses,projects" {
+		t.Fatalf("~/.claude.json keys = %s", got)
+	}
+	var rejected []string
+	if claudeJson.object("customApiKeyResponses").get("rejected", &rejected); len(rejected) != 0 {
+		t.Fatalf("selected key still rejected: %v", rejected)
+	}
+}
+
+func TestCatalogCodexReasoningEffort(t *testing.T) {
+	for _, name := range []string{"AiCodeMirror", "CodeRelay"} {
+		m := &ModelConfig{ModelName: name}
+		if effort := resolveProvider("codex", m).Params.ReasoningEffort; effort != "xhigh" {
+			t.Errorf("%s reasoning effort = %q", name, effort)
+		}
+	}
+}
+
+func TestLaunchEnviron(t *testing.T) {
+	t.Setenv("ANTHROPIC_AUTH_TOKEN", "sk-stale")
+	t.Setenv("HTTP_PROXY", "http://inherited:3128")
+	t.Setenv("AICODER_TEST_KEEP", "kept")
+	environ := launchEnviron(map[string]string{"OPENAI_API_KEY": "sk-new", "HTTP_PROXY": "http://user:pw@proxy:8080"})
+	got := make(map[string][]string)
+	for _, kv := range environ {
+		k, v, _ := strings.Cut(kv, "=")
+		got[k] = append(got[k], v)
+	}
+	if len(got["ANTHROPIC_AUTH_TOKEN"]) != 0 {
+		t.Fatal("stale key inherited")
+	}
+	if strings.Join(got["HTTP_PROXY"], ",") != "http://user:pw@proxy:8080" || strings.Join(got["OPENAI_API_KEY"], ",") != "sk-new" || strings.Join(got["AICODER_TEST_KEEP"], ",") != "kept" {
+		t.Fatalf("environment = %v", got)
+	}
+	if os.Getenv("OPENAI_API_KEY") == "sk-new" {
+		t.Fatal("launch key set in AICoder's environment")
+	}
+}
+
+// renderedFile returns the content a render function produced for the file named name.
+func renderedFile(t *testing.T, files []renderedConfigFile, err error, name string) []byte {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if filepath.Base(f.Path) == name {
+			return f.Content
+		}
+	}
+	t.Fatalf("%s not rendered", name)
+	return nil
+}
+
+func TestResolveProviderRoles(t *testing.T) {
+	// Unset roles fall back to the catalog entry, then to the main model
+	r := resolveProvider("claude", &ModelConfig{ModelName: "GLM"})
+	if r.Roles.Fast != "glm-4.5-air" || r.Roles.Reasoning != r.ModelId || r.Roles.Compact != r.ModelId {
+		t.Fatalf("GLM roles = %+v, model %s", r.Roles, r.ModelId)
+	}
+	m := ModelConfig{ModelName: "GLM", ModelId: "glm-x", Roles: ModelRoles{Fast: "glm-fast", Compact: "glm-compact"}}
+	if r := resolveProvider("claude", &m); r.Roles != (ModelRoles{Fast: "glm-fast", Reasoning: "glm-x", Compact: "glm-compact"}) {
+		t.Fatalf("roles = %+v", r.Roles)
+	}
+}
+
+// Each tool gets the roles through its own settings.
+func TestRenderRoles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	a := NewApp()
+	m := ModelConfig{ModelName: "Relay", IsCustom: true, ModelUrl: "https://relay.example/v1", ModelId: "main-1", ApiKey: "sk-1",
+		Roles: ModelRoles{Fast: "fast-1", Reasoning: "think-1"}}
+
+	var claude struct {
+		Env map[string]string `json:"env"`
+	}
+	files, err := a.renderClaudeSettings(&m)
+	if err := json.Unmarshal(renderedFile(t, files, err, "settings.json"), &claude); err != nil {
+		t.Fatal(err)
+	}
+	for k, want := range map[string]string{"ANTHROPIC_MODEL": "main-1", "ANTHROPIC_DEFAULT_SONNET_MODEL": "main-1",
+		"ANTHROPIC_DEFAULT_HAIKU_MODEL": "fast-1", "ANTHROPIC_DEFAULT_OPUS_MODEL": "think-1"} {
+		if claude.Env[k] != want {
+			t.Errorf("claude %s = %q, want %q", k, claude.Env[k], want)
+		}
+	}
+
+	files, err = a.renderCodexSettings(&m)
+	doc := mustParseToml(t, string(renderedFile(t, files, err, "config.toml")))
+	if model, _ := doc.get(nil, "model"); model != "main-1" {
+		t.Errorf("codex model = %v", model)
+	}
+	if review, _ := doc.get(nil, "review_model"); review != "think-1" {
+		t.Errorf("codex review_model = %v", review)
+	}
+
+	var opencode struct {
+		SmallModel string `json:"small_model"`
+		Provider   map[string]struct {
+			Models map[string]json.RawMessage `json:"models"`
+		} `json:"provider"`
+	}
+	files, err = a.renderOpencodeSettings(&m)
+	if err := json.Unmarshal(renderedFile(t, files, err, "opencode.json"), &opencode); err != nil {
+		t.Fatal(err)
+	}
+	models := opencode.Provider["myprovider"].Models
+	if opencode.SmallModel != "myprovider/fast-1" || models["main-1"] == nil || models["fast-1"] == nil {
+		t.Errorf("opencode small_model %q, models %v", opencode.SmallModel, models)
+	}
+
+	var kode struct {
+		ModelProfiles []struct {
+			ModelName string `json:"modelName"`
+		} `json:"modelProfiles"`
+		ModelPointers map[string]string `json:"modelPointers"`
+	}
+	files, err = a.renderKodeSettings(&m)
+	if err := json.Unmarshal(renderedFile(t, files, err, ".kode.json"), &kode); err != nil {
+		t.Fatal(err)
+	}
+	// One profile per distinct model; compact falls back to the main model
+	var profiles []string
+	for _, p := range kode.ModelProfiles {
+		profiles = append(profiles, p.ModelName)
+	}
+	if strings.Join(profiles, ",") != "main-1,fast-1,think-1" {
+		t.Errorf("kode profiles %v", profiles)
+	}
+	for role, want := range map[string]string{"main": "main-1", "task": "main-1", "quick": "fast-1", "reasoning": "think-1", "compact": "main-1"} {
+		if kode.ModelPointers[role] != want {
+			t.Errorf("kode %s pointer = %q, want %q", role, kode.ModelPointers[role], want)
+		}
+	}
+}
@@ -64,18 +64,23 @@ if !errorlevel! neq 0 (
     goto :error
 )
 
+REM -- Provider catalog signing key (base64 ed25519); remote refresh is disabled without it --
+if not defined PROVIDER_CATALOG_PUBLIC_KEY if exist "%~dp0build\provider_catalog.pub" set /p PROVIDER_CATALOG_PUBLIC_KEY=<"%~dp0build\provider_catalog.pub"
+if not defined PROVIDER_CATALOG_PUBLIC_KEY echo [WARN] PROVIDER_CATALOG_PUBLIC_KEY is not set and build\provider_catalog.pub is missing; remote provider catalog refresh will be disabled.
+set "CATALOG_LDFLAGS=-X main.providerCatalogPublicKey=%PROVIDER_CATALOG_PUBLIC_KEY%"
+
 REM -- Build Go Binaries --
 echo [Step 6/8] Compiling Go binaries...
 set "GOOS=windows"
 set "CGO_ENABLED=0"
 set "GOARCH=amd64"
-go build -tags desktop,production -ldflags "-s -w -H windowsgui" -o "%OUTPUT_DIR%\%APP_NAME%_amd64.exe"
+go build -tags desktop,production -ldflags "-s -w -H windowsgui %CATALOG_LDFLAGS%" -o "%OUTPUT_DIR%\%APP_NAME%_amd64.exe"
 if !errorlevel! neq 0 (
     echo [ERROR] Go build for amd64 failed.
     goto :error
 )
 set "GOARCH=arm64"
-go build -tags desktop,production -ldflags "-s -w -H windowsgui" -o "%OUTPUT_DIR%\%APP_NAME%_arm64.exe"
+go build -tags desktop,production -ldflags "-s -w -H windowsgui %CATALOG_LDFLAGS%" -o "%OUTPUT_DIR%\%APP_NAME%_arm64.exe"
 if !errorlevel! neq 0 (
     echo [ERROR] Go build for arm64 failed.
     goto :error
@@ -107,12 +112,12 @@ set "GOOS=darwin"
 set "GOARCH=arm64"
 set "CGO_ENABLED=0"
 
-go build -tags desktop,production -ldflags "-s -w" -o "%MAC_APP_DIR%\Contents\MacOS\AICoder" 2>nul
+go build -tags desktop,production -ldflags "-s -w %CATALOG_LDFLAGS%" -o "%MAC_APP_DIR%\Contents\MacOS\AICoder" 2>nul
 if !errorlevel! equ 0 goto mac_built
 
 echo [WARNING] macOS arm64 build failed. Trying amd64...
 set "GOARCH=amd64"
-go build -tags desktop,production -ldflags "-s -w" -o "%MAC_APP_DIR%\Contents\MacOS\AICoder" 2>nul
+go build -tags desktop,production -ldflags "-s -w %CATALOG_LDFLAGS%" -o "%MAC_APP_DIR%\Contents\MacOS\AICoder" 2>nul
 if !errorlevel! equ 0 goto mac_built
 
 echo [WARNING] macOS build failed. Copying Windows binary as a placeholder to avoid empty shell...
@@ -45,16 +45,27 @@ npm install --cache ./.npm_cache
 npm run build
 cd ..
 
+# Public key remote provider catalogs are verified with (base64 ed25519).
+# Without it the app only uses its embedded catalog.
+CATALOG_KEY="${PROVIDER_CATALOG_PUBLIC_KEY:-}"
+if [ -z "$CATALOG_KEY" ] && [ -f "build/provider_catalog.pub" ]; then
+    CATALOG_KEY=$(tr -d '[:space:]' < build/provider_catalog.pub)
+fi
+if [ -z "$CATALOG_KEY" ]; then
+    echo "Warning: PROVIDER_CATALOG_PUBLIC_KEY is not set and build/provider_catalog.pub is missing; remote provider catalog refresh will be disabled."
+fi
+CATALOG_LDFLAGS="-X main.providerCatalogPublicKey=${CATALOG_KEY}"
+
 # Build Binaries
 echo "[2/4] Compiling Go Binaries..."
 
 # Build AMD64
 echo "  - Building for amd64..."
-CGO_ENABLED=1 CGO_LDFLAGS="-weak_framework UniformTypeIdentifiers" GOOS=darwin GOARCH=amd64 go build -tags desktop,production,systray_no_appdelegate -o "${BIN_DIR}/${APP_NAME}_amd64"
+CGO_ENABLED=1 CGO_LDFLAGS="-weak_framework UniformTypeIdentifiers" GOOS=darwin GOARCH=amd64 go build -tags desktop,production,systray_no_appdelegate -ldflags "$CATALOG_LDFLAGS" -o "${BIN_DIR}/${APP_NAME}_amd64"
 
 # Build ARM64
 echo "  - Building for arm64..."
-CGO_ENABLED=1 CGO_LDFLAGS="-weak_framework UniformTypeIdentifiers" GOOS=darwin GOARCH=arm64 go build -tags desktop,production,systray_no_appdelegate -o "${BIN_DIR}/${APP_NAME}_arm64"
+CGO_ENABLED=1 CGO_LDFLAGS="-weak_framework UniformTypeIdentifiers" GOOS=darwin GOARCH=arm64 go build -tags desktop,production,systray_no_appdelegate -ldflags "$CATALOG_LDFLAGS" -o "${BIN_DIR}/${APP_NAME}_arm64"
 
 # Generate Windows Resources
 echo "  - Generating Windows Resources..."
@@ -80,11 +91,11 @@ fi
 
 # Build Windows AMD64
 echo "  - Building for Windows amd64..."
-CGO_ENABLED=0 GOOS=windows GOARCH=amd64 go build -tags desktop,production -ldflags "-s -w -H windowsgui" -o "${BIN_DIR}/${APP_NAME}_amd64.exe"
+CGO_ENABLED=0 GOOS=windows GOARCH=amd64 go build -tags desktop,production -ldflags "-s -w -H windowsgui $CATALOG_LDFLAGS" -o "${BIN_DIR}/${APP_NAME}_amd64.exe"
 
 # Build Windows ARM64
 echo "  - Building for Windows arm64..."
-CGO_ENABLED=0 GOOS=windows GOARCH=arm64 go build -tags desktop,production -ldflags "-s -w -H windowsgui" -o "${BIN_DIR}/${APP_NAME}_arm64.exe"
+CGO_ENABLED=0 GOOS=windows GOARCH=arm64 go build -tags desktop,production -ldflags "-s -w -H windowsgui $CATALOG_LDFLAGS" -o "${BIN_DIR}/${APP_NAME}_arm64.exe"
 
 # Cleanup Windows Resources
 rm -f resource_windows_amd64.syso resource_windows_arm64.syso
@@ -120,9 +131,9 @@ build_linux() {
     # Build binary
     # Note: On Linux/macOS cross-compile, CGO is required for Wails.
     if [ -n "$CC_CMD" ]; then
-        eval $CC_CMD CGO_ENABLED=1 GOOS=linux GOARCH=$ARCH go build -tags desktop,production -o "${BIN_DIR}/${APP_NAME}_${ARCH}_linux"
+        eval $CC_CMD CGO_ENABLED=1 GOOS=linux GOARCH=$ARCH go build -tags desktop,production -ldflags \"$CATALOG_LDFLAGS\" -o "${BIN_DIR}/${APP_NAME}_${ARCH}_linux"
     elif [ "$(uname)" == "Linux" ]; then
-        CGO_ENABLED=1 GOOS=linux GOARCH=$ARCH go build -tags desktop,production -o "${BIN_DIR}/${APP_NAME}_${ARCH}_linux"
+        CGO_ENABLED=1 GOOS=linux GOARCH=$ARCH go build -tags desktop,production -ldflags "$CATALOG_LDFLAGS" -o "${BIN_DIR}/${APP_NAME}_${ARCH}_linux"
     fi
     
     # Package
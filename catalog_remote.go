@@ -0,0 +1,211 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultProviderCatalogUrl is used unless provider_catalog_url is set in the config.
+// The detached signature lives next to it with a ".sig" suffix.
+const defaultProviderCatalogUrl = "https://api.github.com/repos/rapidaicoder/msg/contents/providers.json?ref=main"
+
+// providerCatalogPublicKey is the base64 ed25519 key remote catalogs are signed with.
+// It is set at build time: -ldflags "-X main.providerCatalogPublicKey=<key>".
+// Remote refresh stays disabled while it is empty.
+var providerCatalogPublicKey = ""
+
+var errCatalogSignature = errors.New("provider catalog signature verification failed")
+
+type CatalogStatus struct {
+	Version   int    `json:"version"`
+	Source    string `json:"source"` // "embedded", "cache" or "remote"
+	Providers int    `json:"providers"`
+}
+
+var catalogSource = "embedded"
+
+func setActiveCatalog(c *ProviderCatalog, source string) {
+	catalogMutex.Lock()
+	defer catalogMutex.Unlock()
+	activeCatalog = c
+	catalogSource = source
+}
+
+func decodeCatalogPublicKey(key string) (ed25519.PublicKey, error) {
+	if key == "" {
+		return nil, errors.New("no provider catalog signing key configured")
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid provider catalog signing key size %d", len(raw))
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// catalogSignatureUrl returns where the detached signature for a catalog URL is published.
+func catalogSignatureUrl(catalogUrl string) string {
+	u, err := url.Parse(catalogUrl)
+	if err != nil {
+		return catalogUrl + ".sig"
+	}
+	u.Path += ".sig"
+	return u.String()
+}
+
+// verifyCatalog checks the detached signature (raw or base64) and parses the catalog.
+func verifyCatalog(data, sig []byte, pub ed25519.PublicKey) (*ProviderCatalog, error) {
+	if len(sig) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+		if err != nil {
+			return nil, errCatalogSignature
+		}
+		sig = decoded
+	}
+	if len(sig) != ed25519.SignatureSize || !ed25519.Verify(pub, data, sig) {
+		return nil, errCatalogSignature
+	}
+	return parseCatalog(data)
+}
+
+// fetchCatalogFile downloads a catalog file. Files served by the GitHub API go
+// through the App's GitHub client, so they share its token, cache and rate limit
+// handling; other hosts are fetched directly.
+func (a *App) fetchCatalogFile(fileUrl string) ([]byte, error) {
+	if gh := a.githubAPI(); strings.HasPrefix(fileUrl, gh.baseUrl+"/") {
+		return gh.get(strings.TrimPrefix(fileUrl, gh.baseUrl), "application/vnd.github.v3.raw")
+	}
+	req, err := http.NewRequest("GET", fileUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "AICoder-App")
+	req.Header.Set("Cache-Control", "no-cache")
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: %s", fileUrl, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+}
+
+// fetchSignedCatalog downloads a catalog and its detached signature and returns it only if the signature verifies.
+func (a *App) fetchSignedCatalog(catalogUrl string, pub ed25519.PublicKey) (*ProviderCatalog, []byte, []byte, error) {
+	data, err := a.fetchCatalogFile(catalogUrl)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	sig, err := a.fetchCatalogFile(catalogSignatureUrl(catalogUrl))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	c, err := verifyCatalog(data, sig, pub)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return c, data, sig, nil
+}
+
+func (a *App) getCatalogCachePaths() (string, string) {
+	dir := filepath.Join(a.GetUserHomeDir(), ".cceasy")
+	return filepath.Join(dir, "provider_catalog.json"), filepath.Join(dir, "provider_catalog.json.sig")
+}
+
+// loadCachedCatalog activates the last good remote catalog, re-verifying it first.
+func (a *App) loadCachedCatalog() {
+	pub, err := decodeCatalogPublicKey(providerCatalogPublicKey)
+	if err != nil {
+		return
+	}
+	dataPath, sigPath := a.getCatalogCachePaths()
+	data, err := os.ReadFile(dataPath)
+	if err != nil {
+		return
+	}
+	sig, err := os.ReadFile(sigPath)
+	if err != nil {
+		return
+	}
+	c, err := verifyCatalog(data, sig, pub)
+	if err != nil {
+		a.log("Ignoring cached provider catalog: " + err.Error())
+		return
+	}
+	if c.Version < currentCatalog().Version {
+		return
+	}
+	setActiveCatalog(c, "cache")
+}
+
+func (a *App) GetProviderCatalogStatus() CatalogStatus {
+	catalogMutex.RLock()
+	defer catalogMutex.RUnlock()
+	return CatalogStatus{
+		Version:   activeCatalog.Version,
+		Source:    catalogSource,
+		Providers: len(activeCatalog.Providers),
+	}
+}
+
+// RefreshProviderCatalog fetches the signed remote catalog, caches it and merges it into the config.
+func (a *App) RefreshProviderCatalog() (CatalogStatus, error) {
+	pub, err := decodeCatalogPublicKey(providerCatalogPublicKey)
+	if err != nil {
+		return a.GetProviderCatalogStatus(), err
+	}
+	config, err := a.LoadConfig()
+	if err != nil {
+		return a.GetProviderCatalogStatus(), err
+	}
+	catalogUrl := config.ProviderCatalogUrl
+	if catalogUrl == "" {
+		catalogUrl = defaultProviderCatalogUrl
+	}
+	c, data, sig, err := a.fetchSignedCatalog(catalogUrl, pub)
+	if err != nil {
+		return a.GetProviderCatalogStatus(), err
+	}
+	if c.Version < currentCatalog().Version {
+		return a.GetProviderCatalogStatus(), fmt.Errorf("remote provider catalog version %d is older than %d", c.Version, currentCatalog().Version)
+	}
+	dataPath, sigPath := a.getCatalogCachePaths()
+	// Each file is replaced atomically; if only the catalog gets written, the pair no
+	// longer verifies and the cache is ignored until the next refresh
+	err = os.MkdirAll(filepath.Dir(dataPath), 0755)
+	if err == nil {
+		err = atomicWriteFile(dataPath, data, 0644)
+	}
+	if err == nil {
+		err = atomicWriteFile(sigPath, sig, 0644)
+	}
+	if err != nil {
+		a.log("Provider catalog cache: " + err.Error())
+	}
+	setActiveCatalog(c, "remote")
+	// Reading the config applies the active catalog. applyCatalog keeps every provider
+	// that holds a key, including ones the new catalog drops or renames, and the user's
+	// model ids and endpoints, so a refresh never loses a key.
+	if _, err := a.configStore().Reload(configSourceApp); err != nil {
+		return a.GetProviderCatalogStatus(), err
+	}
+	if _, err := a.modifyConfig(func(*AppConfig) error { return nil }); err != nil {
+		return a.GetProviderCatalogStatus(), err
+	}
+	a.log(fmt.Sprintf("Provider catalog updated to version %d", c.Version))
+	return a.GetProviderCatalogStatus(), nil
+}
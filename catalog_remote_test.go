@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testCatalogPath = "/repos/o/r/contents/providers.json"
+
+// remoteTestCatalog returns the embedded catalog as a newer version without DeepSeek.
+func remoteTestCatalog(t *testing.T) []byte {
+	t.Helper()
+	var catalog map[string]interface{}
+	if err := json.Unmarshal(embeddedCatalog, &catalog); err != nil {
+		t.Fatal(err)
+	}
+	var providers []interface{}
+	for _, p := range catalog["providers"].([]interface{}) {
+		if p.(map[string]interface{})["name"] != "DeepSeek" {
+			providers = append(providers, p)
+		}
+	}
+	catalog["providers"] = providers
+	catalog["version"] = mustParseCatalog(embeddedCatalog).Version + 1
+	data, err := json.Marshal(catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// newCatalogTestApp serves data and its signature by signer from a fake GitHub API
+// and returns an App whose catalog URL points there, trusting the key trusted.
+func newCatalogTestApp(t *testing.T, data []byte, signer ed25519.PrivateKey, trusted ed25519.PublicKey) *App {
+	t.Helper()
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(signer, data))
+	mux := http.NewServeMux()
+	mux.HandleFunc(testCatalogPath, func(w http.ResponseWriter, r *http.Request) { w.Write(data) })
+	mux.HandleFunc(testCatalogPath+".sig", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(sig)) })
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	oldKey := providerCatalogPublicKey
+	providerCatalogPublicKey = base64.StdEncoding.EncodeToString(trusted)
+	t.Cleanup(func() {
+		providerCatalogPublicKey = oldKey
+		setActiveCatalog(mustParseCatalog(embeddedCatalog), "embedded")
+	})
+
+	a := newSecretTestApp(t, newFakeSecretBackend("fake"))
+	t.Setenv("HOME", a.testHomeDir)
+	a.github = newGitHubClient(srv.URL, t.TempDir(), func() string { return "" }, func(s string) { t.Log(s) })
+	a.githubOnce.Do(func() {})
+	config := secretTestConfig()
+	config.Claude.Models = append(config.Claude.Models, ModelConfig{ModelName: "DeepSeek", ApiKey: "sk-deepseek"})
+	config.ProviderCatalogUrl = srv.URL + testCatalogPath + "?ref=main"
+	path, _ := a.getConfigPath()
+	if err := a.saveToPath(path, config); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestRefreshProviderCatalogKeepsKeys(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	data := remoteTestCatalog(t)
+	a := newCatalogTestApp(t, data, priv, pub)
+
+	status, err := a.RefreshProviderCatalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Source != "remote" || status.Version != mustParseCatalog(embeddedCatalog).Version+1 {
+		t.Fatalf("status = %+v", status)
+	}
+	config, err := a.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := getProviderModel(&config.Claude, "DeepSeek"); m == nil || m.ApiKey != "sk-deepseek" {
+		t.Fatalf("DeepSeek, dropped by the catalog, lost its key: %+v", m)
+	}
+	if m := getProviderModel(&config.Claude, "GLM"); m == nil || m.ApiKey != "sk-glm-secret-1" {
+		t.Fatalf("GLM key lost: %+v", m)
+	}
+
+	// The cache verifies and is used on the next start
+	setActiveCatalog(mustParseCatalog(embeddedCatalog), "embedded")
+	a.loadCachedCatalog()
+	if status := a.GetProviderCatalogStatus(); status.Source != "cache" {
+		t.Fatalf("cached catalog not used: %+v", status)
+	}
+}
+
+func TestRefreshProviderCatalogRejectsBadSignature(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	a := newCatalogTestApp(t, remoteTestCatalog(t), other, pub)
+
+	if _, err := a.RefreshProviderCatalog(); !errors.Is(err, errCatalogSignature) {
+		t.Fatalf("err = %v, want %v", err, errCatalogSignature)
+	}
+	if status := a.GetProviderCatalogStatus(); status.Source != "embedded" {
+		t.Fatalf("unverified catalog used: %+v", status)
+	}
+	config, _ := a.LoadConfig()
+	if m := getProviderModel(&config.Claude, "DeepSeek"); m == nil || m.ApiKey != "sk-deepseek" {
+		t.Fatalf("DeepSeek changed: %+v", m)
+	}
+}
+
+func TestCatalogSignatureUrl(t *testing.T) {
+	got := catalogSignatureUrl("https://api.github.com/repos/o/r/contents/providers.json?ref=main")
+	if want := "https://api.github.com/repos/o/r/contents/providers.json.sig?ref=main"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
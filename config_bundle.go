@@ -0,0 +1,553 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	bundleFormat  = "aicoder-bundle"
+	bundleVersion = 1
+)
+
+// configBundle is a shareable setup: providers, projects, proxy defaults and skills.
+// Secrets are never stored in the clear; they are left out or encrypted with a passphrase.
+type configBundle struct {
+	Format          string                `json:"format"`
+	Version         int                   `json:"version"`
+	CreatedAt       string                `json:"created_at"`
+	Tools           map[string]ToolConfig `json:"tools"` // API keys removed
+	Projects        []ProjectConfig       `json:"projects"`
+	Proxy           bundleProxy           `json:"proxy"`
+	ProviderKeySync map[string]string     `json:"provider_key_sync,omitempty"`
+	Skills          []bundleSkill         `json:"skills,omitempty"`
+	Secrets         *bundleSealed         `json:"secrets,omitempty"` // Encrypted bundleSecrets, absent when stripped
+}
+
+type bundleProxy struct {
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Username string `json:"username"`
+}
+
+type bundleSkill struct {
+	Skill
+	Zip []byte `json:"zip,omitempty"` // Package contents for zip skills
+}
+
+// bundleSecrets holds what the passphrase protects.
+type bundleSecrets struct {
+	ApiKeys          map[string]string        `json:"api_keys"`          // "tool/provider" -> key
+	KeyLists         map[string][]ApiKeyEntry `json:"key_lists"`         // "tool/provider" -> key list
+	ProxyPassword    string                   `json:"proxy_password"`    // Global default proxy
+	ProjectPasswords map[string]string        `json:"project_passwords"` // Project id -> proxy password
+}
+
+// bundleSealed is AES-256-GCM ciphertext under a key derived with scrypt.
+type bundleSealed struct {
+	Kdf        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// BundleChange is one thing importing a bundle changes, or leaves alone.
+type BundleChange struct {
+	Section string `json:"section"` // "provider", "project", "proxy", "key_sync" or "skill"
+	Tool    string `json:"tool,omitempty"`
+	Name    string `json:"name"`
+	Action  string `json:"action"` // "add", "update" or "keep"
+	Detail  string `json:"detail"`
+}
+
+type BundlePreview struct {
+	CreatedAt string         `json:"created_at"`
+	HasSecret bool           `json:"has_secrets"` // Whether the bundle carries encrypted keys
+	Changes   []BundleChange `json:"changes"`
+}
+
+// bundleClient is replaced in tests to talk to a local stand-in server.
+var bundleClient = &http.Client{Timeout: 30 * time.Second}
+
+func bundleSecretKey(tool, provider string) string {
+	return tool + "/" + strings.ToLower(provider)
+}
+
+func deriveBundleKey(passphrase string, s *bundleSealed) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), s.Salt, s.N, s.R, s.P, 32)
+}
+
+func sealBundleSecrets(secrets bundleSecrets, passphrase string) (*bundleSealed, error) {
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, err
+	}
+	s := &bundleSealed{Kdf: "scrypt", N: 1 << 15, R: 8, P: 1, Salt: make([]byte, 16), Nonce: make([]byte, 12)}
+	if _, err := rand.Read(s.Salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(s.Nonce); err != nil {
+		return nil, err
+	}
+	key, err := deriveBundleKey(passphrase, s)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newBundleCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	s.Ciphertext = gcm.Seal(nil, s.Nonce, plain, []byte(bundleFormat))
+	return s, nil
+}
+
+func openBundleSecrets(s *bundleSealed, passphrase string) (bundleSecrets, error) {
+	var secrets bundleSecrets
+	if s.Kdf != "scrypt" {
+		return secrets, fmt.Errorf("unsupported key derivation %q", s.Kdf)
+	}
+	key, err := deriveBundleKey(passphrase, s)
+	if err != nil {
+		return secrets, err
+	}
+	gcm, err := newBundleCipher(key)
+	if err != nil {
+		return secrets, err
+	}
+	plain, err := gcm.Open(nil, s.Nonce, s.Ciphertext, []byte(bundleFormat))
+	if err != nil {
+		return secrets, fmt.Errorf("wrong passphrase or damaged bundle")
+	}
+	err = json.Unmarshal(plain, &secrets)
+	return secrets, err
+}
+
+func newBundleCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// buildConfigBundle copies the shareable parts of config. With a passphrase the
+// keys and proxy passwords are sealed into the bundle; without one they are dropped.
+func buildConfigBundle(config AppConfig, skills []bundleSkill, passphrase string) (*configBundle, error) {
+	b := &configBundle{
+		Format:          bundleFormat,
+		Version:         bundleVersion,
+		CreatedAt:       time.Now().Format(time.RFC3339),
+		Tools:           make(map[string]ToolConfig),
+		Proxy:           bundleProxy{Host: config.DefaultProxyHost, Port: config.DefaultProxyPort, Username: config.DefaultProxyUsername},
+		ProviderKeySync: config.ProviderKeySync,
+		Skills:          skills,
+	}
+	secrets := bundleSecrets{
+		ApiKeys:          make(map[string]string),
+		KeyLists:         make(map[string][]ApiKeyEntry),
+		ProxyPassword:    config.DefaultProxyPassword,
+		ProjectPasswords: make(map[string]string),
+	}
+	for _, tool := range toolNames {
+		toolCfg := *getToolConfig(&config, tool)
+		models := make([]ModelConfig, len(toolCfg.Models))
+		for i, m := range toolCfg.Models {
+			if m.ApiKey != "" {
+				secrets.ApiKeys[bundleSecretKey(tool, m.ModelName)] = m.ApiKey
+			}
+			if len(m.ApiKeys) > 0 {
+				secrets.KeyLists[bundleSecretKey(tool, m.ModelName)] = m.ApiKeys
+			}
+			m.ApiKey = ""
+			m.ApiKeys = nil
+			m.ActiveKey = 0
+			models[i] = m
+		}
+		toolCfg.Models = models
+		b.Tools[tool] = toolCfg
+	}
+	for _, p := range config.Projects {
+		if p.ProxyPassword != "" {
+			secrets.ProjectPasswords[p.Id] = p.ProxyPassword
+		}
+		p.ProxyPassword = ""
+		b.Projects = append(b.Projects, p)
+	}
+	if passphrase != "" {
+		sealed, err := sealBundleSecrets(secrets, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		b.Secrets = sealed
+	}
+	return b, nil
+}
+
+// readBundleSource reads a bundle from a local path or an http(s) URL.
+func readBundleSource(source string) ([]byte, error) {
+	source = strings.TrimSpace(source)
+	if !strings.HasPrefix(source, "https://") && !strings.HasPrefix(source, "http://") {
+		return os.ReadFile(source)
+	}
+	req, err := http.NewRequest("GET", source, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "AICoder-App")
+	resp, err := bundleClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", source, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+}
+
+func parseConfigBundle(data []byte) (*configBundle, error) {
+	var b configBundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("not a configuration bundle: %v", err)
+	}
+	if b.Format != bundleFormat {
+		return nil, fmt.Errorf("not a configuration bundle")
+	}
+	if b.Version > bundleVersion {
+		return nil, fmt.Errorf("bundle version %d is newer than this version of AICoder supports", b.Version)
+	}
+	return &b, nil
+}
+
+// loadConfigBundle reads and parses a bundle and unseals its secrets. A bundle with
+// secrets needs the passphrase; without one only the settings are imported.
+func loadConfigBundle(source, passphrase string) (*configBundle, bundleSecrets, error) {
+	var secrets bundleSecrets
+	data, err := readBundleSource(source)
+	if err != nil {
+		return nil, secrets, err
+	}
+	b, err := parseConfigBundle(data)
+	if err != nil {
+		return nil, secrets, err
+	}
+	if b.Secrets != nil && passphrase != "" {
+		if secrets, err = openBundleSecrets(b.Secrets, passphrase); err != nil {
+			return nil, secrets, err
+		}
+	}
+	return b, secrets, nil
+}
+
+// bundleEndpoint describes the endpoint a bundle gives a provider.
+func bundleEndpoint(m ModelConfig) string {
+	if m.WireApi == "" {
+		return m.ModelUrl
+	}
+	return fmt.Sprintf("%s (%s)", m.ModelUrl, m.WireApi)
+}
+
+// mergeConfigBundle merges b into config. Settings from the bundle win, but keys and
+// passwords the user already has are never replaced, nor is the endpoint of a
+// custom provider that holds a key.
+func mergeConfigBundle(config *AppConfig, b *configBundle, secrets bundleSecrets) []BundleChange {
+	var changes []BundleChange
+	for _, tool := range toolNames {
+		bt, ok := b.Tools[tool]
+		if !ok {
+			continue
+		}
+		toolCfg := getToolConfig(config, tool)
+		for _, bm := range bt.Models {
+			if strings.EqualFold(bm.ModelName, "Original") {
+				continue
+			}
+			sk := bundleSecretKey(tool, bm.ModelName)
+			key, keyList := secrets.ApiKeys[sk], secrets.KeyLists[sk]
+			m := getProviderModel(toolCfg, bm.ModelName)
+			if m == nil {
+				if !bm.IsCustom || !toolTakesCustom(tool) ||
+					validateCustomName(config, []string{tool}, bm.ModelName, "") != nil {
+					changes = append(changes, BundleChange{Section: "provider", Tool: tool, Name: bm.ModelName, Action: "keep", Detail: "not available for this tool"})
+					continue
+				}
+				toolCfg.Models = append(toolCfg.Models, bm)
+				m = &toolCfg.Models[len(toolCfg.Models)-1]
+				changes = append(changes, BundleChange{Section: "provider", Tool: tool, Name: bm.ModelName, Action: "add", Detail: "new custom provider"})
+			} else {
+				var updated []string
+				update := func(field string, dst *string, src string) {
+					if src != "" && *dst != src {
+						*dst = src
+						updated = append(updated, field)
+					}
+				}
+				if m.IsCustom && hasApiKey(*m) {
+					// The bundle must not point a key the user already has at another
+					// endpoint; it is listed for the user to change by hand
+					if (bm.ModelUrl != "" && bm.ModelUrl != m.ModelUrl) || (bm.WireApi != "" && bm.WireApi != m.WireApi) {
+						if bm.ModelUrl == "" {
+							bm.ModelUrl = m.ModelUrl
+						}
+						changes = append(changes, BundleChange{Section: "provider", Tool: tool, Name: m.ModelName, Action: "keep",
+							Detail: fmt.Sprintf("endpoint not changed to %s, as it would receive the existing API key", bundleEndpoint(bm))})
+					}
+				} else if m.IsCustom {
+					update("base URL", &m.ModelUrl, bm.ModelUrl)
+					update("wire API", &m.WireApi, bm.WireApi)
+				}
+				update("model", &m.ModelId, bm.ModelId)
+				update("fast model", &m.Roles.Fast, bm.Roles.Fast)
+				update("reasoning model", &m.Roles.Reasoning, bm.Roles.Reasoning)
+				update("compact model", &m.Roles.Compact, bm.Roles.Compact)
+				update("key policy", &m.KeyPolicy, bm.KeyPolicy)
+				if bm.Params != (ModelParams{}) && m.Params != bm.Params {
+					m.Params = bm.Params
+					updated = append(updated, "parameters")
+				}
+				if len(updated) > 0 {
+					changes = append(changes, BundleChange{Section: "provider", Tool: tool, Name: m.ModelName, Action: "update", Detail: strings.Join(updated, ", ")})
+				}
+			}
+			if key == "" && len(keyList) == 0 {
+				continue
+			}
+			if m.ApiKey != "" || len(m.ApiKeys) > 0 {
+				changes = append(changes, BundleChange{Section: "provider", Tool: tool, Name: m.ModelName, Action: "keep", Detail: "existing API key kept"})
+				continue
+			}
+			m.ApiKeys = keyList
+			m.ApiKey = key
+			normalizeApiKeys(m)
+			changes = append(changes, BundleChange{Section: "provider", Tool: tool, Name: m.ModelName, Action: "add", Detail: "API key"})
+		}
+		// Take over the team's default provider unless one is already set up
+		if cur := getProviderModel(toolCfg, toolCfg.CurrentModel); bt.CurrentModel != "" && (cur == nil || cur.ApiKey == "") {
+			if m := getProviderModel(toolCfg, bt.CurrentModel); m != nil && m.ModelName != toolCfg.CurrentModel {
+				toolCfg.CurrentModel = m.ModelName
+				changes = append(changes, BundleChange{Section: "provider", Tool: tool, Name: m.ModelName, Action: "update", Detail: "selected provider"})
+			}
+		}
+	}
+	for _, bp := range b.Projects {
+		exists := false
+		for _, p := range config.Projects {
+			if p.Id == bp.Id || p.Path == bp.Path {
+				exists = true
+				break
+			}
+		}
+		if exists {
+			changes = append(changes, BundleChange{Section: "project", Name: bp.Name, Action: "keep", Detail: "project already exists"})
+			continue
+		}
+		bp.ProxyPassword = secrets.ProjectPasswords[bp.Id]
+		config.Projects = append(config.Projects, bp)
+		changes = append(changes, BundleChange{Section: "project", Name: bp.Name, Action: "add", Detail: bp.Path})
+	}
+	if b.Proxy.Host != "" {
+		if config.DefaultProxyHost == "" {
+			config.DefaultProxyHost = b.Proxy.Host
+			config.DefaultProxyPort = b.Proxy.Port
+			config.DefaultProxyUsername = b.Proxy.Username
+			config.DefaultProxyPassword = secrets.ProxyPassword
+			changes = append(changes, BundleChange{Section: "proxy", Name: b.Proxy.Host + ":" + b.Proxy.Port, Action: "add", Detail: "default proxy"})
+		} else {
+			changes = append(changes, BundleChange{Section: "proxy", Name: config.DefaultProxyHost + ":" + config.DefaultProxyPort, Action: "keep", Detail: "default proxy already set"})
+		}
+	}
+	for name, policy := range b.ProviderKeySync {
+		if _, set := config.ProviderKeySync[name]; set {
+			continue
+		}
+		if config.ProviderKeySync == nil {
+			config.ProviderKeySync = make(map[string]string)
+		}
+		config.ProviderKeySync[name] = policy
+		changes = append(changes, BundleChange{Section: "key_sync", Name: name, Action: "add", Detail: policy})
+	}
+	return changes
+}
+
+// readBundleSkills collects the user's own skills, with zip packages inlined.
+func (a *App) readBundleSkills() []bundleSkill {
+	skillsDir := a.GetSkillsDir("")
+	var skills []Skill
+	if data, err := os.ReadFile(filepath.Join(skillsDir, "metadata.json")); err == nil {
+		json.Unmarshal(data, &skills)
+	}
+	var out []bundleSkill
+	for _, s := range skills {
+		bs := bundleSkill{Skill: s}
+		bs.Installed = false
+		if s.Type == "zip" {
+			data, err := os.ReadFile(filepath.Join(skillsDir, filepath.Base(s.Value)))
+			if err != nil {
+				a.log(fmt.Sprintf("Bundle: skipped skill %s: %v", s.Name, err))
+				continue
+			}
+			bs.Zip = data
+		}
+		out = append(out, bs)
+	}
+	return out
+}
+
+// mergeBundleSkills adds the bundle's skills the user does not have yet. With apply
+// false it only reports what would be added.
+func (a *App) mergeBundleSkills(skills []bundleSkill, apply bool) ([]BundleChange, error) {
+	skillsDir := a.GetSkillsDir("")
+	metadataPath := filepath.Join(skillsDir, "metadata.json")
+	var existing []Skill
+	if data, err := os.ReadFile(metadataPath); err == nil {
+		json.Unmarshal(data, &existing)
+	}
+	var changes []BundleChange
+	added := false
+	for _, bs := range skills {
+		have := false
+		for _, s := range existing {
+			if s.Name == bs.Name {
+				have = true
+				break
+			}
+		}
+		if have {
+			changes = append(changes, BundleChange{Section: "skill", Name: bs.Name, Action: "keep", Detail: "skill already exists"})
+			continue
+		}
+		changes = append(changes, BundleChange{Section: "skill", Name: bs.Name, Action: "add", Detail: bs.Type})
+		if !apply {
+			continue
+		}
+		s := bs.Skill
+		if s.Type == "zip" {
+			s.Value = filepath.Base(s.Value)
+			if err := os.MkdirAll(skillsDir, 0755); err != nil {
+				return changes, err
+			}
+			if err := os.WriteFile(filepath.Join(skillsDir, s.Value), bs.Zip, 0644); err != nil {
+				return changes, err
+			}
+		}
+		existing = append(existing, s)
+		added = true
+	}
+	if added {
+		data, err := json.MarshalIndent(existing, "", "  ")
+		if err != nil {
+			return changes, err
+		}
+		if err := os.WriteFile(metadataPath, data, 0644); err != nil {
+			return changes, err
+		}
+	}
+	return changes, nil
+}
+
+// ExportConfigBundle writes the providers, projects, proxy defaults and skills to path.
+// With a passphrase, API keys and proxy passwords are included encrypted; without one
+// they are left out.
+func (a *App) ExportConfigBundle(path string, passphrase string) error {
+	config, err := a.LoadConfig()
+	if err != nil {
+		return err
+	}
+	b, err := buildConfigBundle(config, a.readBundleSkills(), passphrase)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	// The bundle may hold encrypted keys; keep it private like any credential file.
+	// The directory is the user's choice, e.g. Downloads, so its mode is left alone.
+	if err := atomicWriteFile(path, data, privateFileMode); err != nil {
+		return err
+	}
+	a.log(fmt.Sprintf("Exported configuration bundle to %s (secrets included: %v)", path, b.Secrets != nil))
+	return nil
+}
+
+// PreviewConfigBundle reports what importing a bundle from a file or URL would change.
+func (a *App) PreviewConfigBundle(source string, passphrase string) (BundlePreview, error) {
+	b, secrets, err := loadConfigBundle(source, passphrase)
+	if err != nil {
+		return BundlePreview{}, err
+	}
+	config, err := a.LoadConfig()
+	if err != nil {
+		return BundlePreview{}, err
+	}
+	preview := BundlePreview{CreatedAt: b.CreatedAt, HasSecret: b.Secrets != nil}
+	preview.Changes = mergeConfigBundle(&config, b, secrets)
+	skillChanges, err := a.mergeBundleSkills(b.Skills, false)
+	preview.Changes = append(preview.Changes, skillChanges...)
+	return preview, err
+}
+
+// ImportConfigBundle merges a bundle from a file or URL into the config and returns
+// what was changed.
+func (a *App) ImportConfigBundle(source string, passphrase string) (BundlePreview, error) {
+	b, secrets, err := loadConfigBundle(source, passphrase)
+	if err != nil {
+		return BundlePreview{}, err
+	}
+	preview := BundlePreview{CreatedAt: b.CreatedAt, HasSecret: b.Secrets != nil}
+	_, err = a.modifyConfig(func(config *AppConfig) error {
+		preview.Changes = mergeConfigBundle(config, b, secrets)
+		return nil
+	})
+	if err != nil {
+		return preview, err
+	}
+	skillChanges, err := a.mergeBundleSkills(b.Skills, true)
+	preview.Changes = append(preview.Changes, skillChanges...)
+	a.log(fmt.Sprintf("Imported configuration bundle from %s", source))
+	return preview, err
+}
+
+// SelectBundleExportPath asks where to save a bundle.
+func (a *App) SelectBundleExportPath() string {
+	selection, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "Export Configuration Bundle",
+		DefaultFilename: "aicoder-bundle.json",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "AICoder Bundle", Pattern: "*.json"},
+		},
+	})
+	if err != nil {
+		return ""
+	}
+	return selection
+}
+
+// SelectBundleFile asks for a bundle to import.
+func (a *App) SelectBundleFile() string {
+	selection, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "Select Configuration Bundle",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "AICoder Bundle", Pattern: "*.json"},
+		},
+	})
+	if err != nil {
+		return ""
+	}
+	return selection
+}
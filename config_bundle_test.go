@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
+	"testing"
+)
+
+func bundleTestConfig() AppConfig {
+	return AppConfig{Codex: ToolConfig{CurrentModel: "Keyed", Models: []ModelConfig{
+		{ModelName: "Original"},
+		{ModelName: "Keyed", IsCustom: true, ModelUrl: "https://mine.example/v1", WireApi: "chat", ApiKey: "sk-mine"},
+		{ModelName: "Keyless", IsCustom: true, ModelUrl: "https://old.example/v1"},
+	}}}
+}
+
+func findBundleChange(changes []BundleChange, name, action string) *BundleChange {
+	for i := range changes {
+		if changes[i].Name == name && changes[i].Action == action {
+			return &changes[i]
+		}
+	}
+	return nil
+}
+
+func TestMergeConfigBundleKeepsEndpointOfKeyedProvider(t *testing.T) {
+	config := bundleTestConfig()
+	b := &configBundle{Tools: map[string]ToolConfig{"codex": {Models: []ModelConfig{
+		{ModelName: "Keyed", IsCustom: true, ModelUrl: "https://theirs.example/v1", WireApi: "responses", ModelId: "m-2"},
+		{ModelName: "Keyless", IsCustom: true, ModelUrl: "https://new.example/v1"},
+	}}}}
+	changes := mergeConfigBundle(&config, b, bundleSecrets{})
+
+	m := getProviderModel(&config.Codex, "Keyed")
+	if m.ModelUrl != "https://mine.example/v1" || m.WireApi != "chat" || m.ApiKey != "sk-mine" {
+		t.Fatalf("keyed provider redirected: %+v", m)
+	}
+	if m.ModelId != "m-2" {
+		t.Fatalf("model not updated: %q", m.ModelId)
+	}
+	c := findBundleChange(changes, "Keyed", "keep")
+	if c == nil || !strings.Contains(c.Detail, "https://theirs.example/v1 (responses)") {
+		t.Fatalf("endpoint change not reported: %+v", changes)
+	}
+	if m := getProviderModel(&config.Codex, "Keyless"); m.ModelUrl != "https://new.example/v1" {
+		t.Fatalf("keyless provider endpoint = %q", m.ModelUrl)
+	}
+	if c := findBundleChange(changes, "Keyless", "update"); c == nil || !strings.Contains(c.Detail, "base URL") {
+		t.Fatalf("keyless update not reported: %+v", changes)
+	}
+}
+
+func TestMergeConfigBundleAddsKeysOnlyWhereMissing(t *testing.T) {
+	config := bundleTestConfig()
+	b := &configBundle{Tools: map[string]ToolConfig{"codex": {Models: []ModelConfig{
+		{ModelName: "Keyed", IsCustom: true},
+		{ModelName: "Keyless", IsCustom: true},
+	}}}}
+	secrets := bundleSecrets{ApiKeys: map[string]string{"codex/keyed": "sk-theirs", "codex/keyless": "sk-new"}}
+	mergeConfigBundle(&config, b, secrets)
+	if m := getProviderModel(&config.Codex, "Keyed"); m.ApiKey != "sk-mine" {
+		t.Fatalf("existing key replaced: %q", m.ApiKey)
+	}
+	if m := getProviderModel(&config.Codex, "Keyless"); m.ApiKey != "sk-new" {
+		t.Fatalf("missing key not added: %q", m.ApiKey)
+	}
+}
+
+func TestExportConfigBundle(t *testing.T) {
+	a := newSecretTestApp(t, newFakeSecretBackend("fake"))
+	t.Setenv("HOME", a.testHomeDir)
+	path, _ := a.getConfigPath()
+	if err := a.saveToPath(path, secretTestConfig()); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "bundle.json")
+	// An existing file with a looser mode is replaced with a private one
+	os.WriteFile(out, []byte("{}"), 0644)
+	if err := a.ExportConfigBundle(out, "passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	if goruntime.GOOS != "windows" {
+		if info, err := os.Stat(out); err != nil || info.Mode().Perm() != privateFileMode {
+			t.Fatalf("bundle mode = %v, %v", info.Mode().Perm(), err)
+		}
+	}
+	data, _ := os.ReadFile(out)
+	if strings.Contains(string(data), "sk-glm-secret-1") {
+		t.Fatal("API key exported in the clear")
+	}
+	b, secrets, err := loadConfigBundle(out, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secrets.ApiKeys["claude/glm"] != "sk-glm-secret-1" || b.Secrets == nil {
+		t.Fatalf("sealed keys = %v", secrets.ApiKeys)
+	}
+	if _, _, err := loadConfigBundle(out, "wrong"); err == nil {
+		t.Fatal("wrong passphrase accepted")
+	}
+}
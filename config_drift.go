@@ -0,0 +1,451 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// renderedConfigFile is the content a sync writer would put in one tool config file.
+// The writers render first and then write, so drift detection can compare against
+// exactly what a sync would produce.
+type renderedConfigFile struct {
+	Path    string
+	Format  string // "json" or "toml"
+	Content []byte
+	Fields  map[string]string // Dotted key path -> ModelConfig field (json name) the value comes from
+}
+
+// writeRenderedFiles writes every file whose content differs from what is on disk.
+func writeRenderedFiles(files []renderedConfigFile) error {
+	for _, f := range files {
+		if existing, err := os.ReadFile(f.Path); err == nil && bytes.Equal(existing, f.Content) {
+			continue
+		}
+		if err := writePrivateFile(f.Path, f.Content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// driftTools lists the tools whose config files CheckConfigDrift inspects.
+// Gemini only gets an auth type and CodeBuddy is not synced at launch.
+var driftTools = []string{"claude", "codex", "opencode", "iflow", "kilo", "kode", "qoder"}
+
+// DriftItem is one key whose value on disk differs from what AICoder would write.
+type DriftItem struct {
+	Path      []string `json:"path"`
+	Key       string   `json:"key"`        // Path joined with dots, for display
+	Ours      string   `json:"ours"`       // Value AICoder would write
+	Theirs    string   `json:"theirs"`     // Value currently in the file
+	OursSet   bool     `json:"ours_set"`   // False if AICoder would remove the key
+	TheirsSet bool     `json:"theirs_set"` // False if the key is missing from the file
+	Field     string   `json:"field"`      // ModelConfig field the external value can be adopted into, empty if none
+}
+
+type DriftFile struct {
+	Tool  string      `json:"tool"`
+	Path  string      `json:"path"`
+	Items []DriftItem `json:"items"`
+}
+
+type DriftReport struct {
+	Files  []DriftFile `json:"files"`  // Only files that differ; files a tool has not been launched with yet are skipped
+	Errors []string    `json:"errors"` // Tools or files that could not be checked
+}
+
+// currentModelConfig returns the selected provider of a tool, pointing into its Models slice.
+func currentModelConfig(toolCfg *ToolConfig) *ModelConfig {
+	for i := range toolCfg.Models {
+		if toolCfg.Models[i].ModelName == toolCfg.CurrentModel {
+			return &toolCfg.Models[i]
+		}
+	}
+	return nil
+}
+
+// renderToolConfig renders the files syncing a tool would write. Original mode
+// manages nothing and renders no files.
+func (a *App) renderToolConfig(tool string, config AppConfig) ([]renderedConfigFile, error) {
+	toolCfg := getToolConfig(&config, tool)
+	if toolCfg == nil {
+		return nil, fmt.Errorf("unknown tool %s", tool)
+	}
+	m := currentModelConfig(toolCfg)
+	if m == nil {
+		return nil, fmt.Errorf("selected %s model not found", tool)
+	}
+	if strings.EqualFold(m.ModelName, "original") {
+		return nil, nil
+	}
+	switch tool {
+	case "claude":
+		return a.renderClaudeSettings(m)
+	case "codex":
+		return a.renderCodexSettings(m)
+	case "opencode":
+		return a.renderOpencodeSettings(m)
+	case "iflow":
+		return a.renderIFlowSettings(m)
+	case "kilo":
+		return a.renderKiloSettings(m)
+	case "kode":
+		return a.renderKodeSettings(m)
+	case "qoder", "codebuddy":
+		projectPath := a.GetCurrentProjectPath()
+		if projectPath == "" {
+			return nil, nil
+		}
+		return renderModelsFile(tool, *toolCfg, projectPath)
+	}
+	return nil, fmt.Errorf("%s has no config file to check", tool)
+}
+
+// adoptableField reports the ModelConfig field an external value can be adopted into.
+// Endpoints of catalog providers follow the catalog, so only custom ones can be adopted.
+func adoptableField(tool string, m *ModelConfig, field string) string {
+	if (field == "model_url" || field == "wire_api") && resolveProvider(tool, m).Known {
+		return ""
+	}
+	return field
+}
+
+// CheckConfigDrift compares every tool config file with what syncing the current
+// configuration would write and reports the keys that differ.
+func (a *App) CheckConfigDrift() (DriftReport, error) {
+	report := DriftReport{Files: []DriftFile{}, Errors: []string{}}
+	config, err := a.LoadConfig()
+	if err != nil {
+		return report, err
+	}
+	for _, tool := range driftTools {
+		files, err := a.renderToolConfig(tool, config)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", tool, err))
+			continue
+		}
+		m := currentModelConfig(getToolConfig(&config, tool))
+		for _, f := range files {
+			df, err := diffRenderedFile(tool, f)
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", tool, err))
+				continue
+			}
+			for i := range df.Items {
+				df.Items[i].Field = adoptableField(tool, m, f.Fields[df.Items[i].Key])
+			}
+			if len(df.Items) > 0 {
+				report.Files = append(report.Files, df)
+			}
+		}
+	}
+	return report, nil
+}
+
+// ResolveConfigDrift settles one reported difference. "adopt" copies the value from
+// the file into the AICoder configuration; "reapply" writes AICoder's value back
+// into the file, leaving every other key alone. An empty path with "reapply"
+// rewrites the whole file.
+func (a *App) ResolveConfigDrift(tool string, file string, path []string, action string) error {
+	config, err := a.LoadConfig()
+	if err != nil {
+		return err
+	}
+	files, err := a.renderToolConfig(tool, config)
+	if err != nil {
+		return err
+	}
+	var f *renderedConfigFile
+	for i := range files {
+		if files[i].Path == file {
+			f = &files[i]
+			break
+		}
+	}
+	if f == nil {
+		return fmt.Errorf("%s is not managed for %s", file, tool)
+	}
+	key := strings.Join(path, ".")
+	switch action {
+	case "reapply":
+		data, err := os.ReadFile(f.Path)
+		if len(path) == 0 || os.IsNotExist(err) {
+			if err := writeRenderedFiles([]renderedConfigFile{*f}); err != nil {
+				return err
+			}
+			a.log(fmt.Sprintf("Re-applied AICoder settings to %s", f.Path))
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		patched, err := reapplyDriftValue(*f, data, path)
+		if err != nil {
+			return err
+		}
+		if err := writePrivateFile(f.Path, patched); err != nil {
+			return err
+		}
+		a.log(fmt.Sprintf("Re-applied %s in %s", key, f.Path))
+		return nil
+	case "adopt":
+		m := currentModelConfig(getToolConfig(&config, tool))
+		field := adoptableField(tool, m, f.Fields[key])
+		if field == "" {
+			return fmt.Errorf("%s cannot be adopted into the AICoder configuration", key)
+		}
+		data, err := os.ReadFile(f.Path)
+		if err != nil {
+			return err
+		}
+		value, err := driftValueString(*f, data, path)
+		if err != nil {
+			return err
+		}
+		_, err = a.modifyConfig(func(config *AppConfig) error {
+			m := currentModelConfig(getToolConfig(config, tool))
+			if m == nil {
+				return fmt.Errorf("selected %s model not found", tool)
+			}
+			switch field {
+			case "api_key":
+				setActiveApiKey(m, value)
+			case "model_id":
+				m.ModelId = value
+			case "model_url":
+				// models.json stores the full completions endpoint
+				m.ModelUrl = strings.TrimSuffix(value, "/chat/completions")
+			case "wire_api":
+				m.WireApi = value
+			case "roles.fast":
+				m.Roles.Fast = value
+			case "roles.reasoning":
+				m.Roles.Reasoning = value
+			case "roles.compact":
+				m.Roles.Compact = value
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		a.log(fmt.Sprintf("Adopted %s from %s", key, f.Path))
+		return nil
+	}
+	return fmt.Errorf("unknown drift action %q", action)
+}
+
+// diffRenderedFile compares a rendered file with the one on disk.
+func diffRenderedFile(tool string, f renderedConfigFile) (DriftFile, error) {
+	df := DriftFile{Tool: tool, Path: f.Path, Items: []DriftItem{}}
+	data, err := os.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return df, nil
+	}
+	if err != nil {
+		return df, err
+	}
+	if f.Format == "toml" {
+		ours, err := parseToml(string(f.Content))
+		if err != nil {
+			return df, err
+		}
+		theirs, err := parseToml(string(data))
+		if err != nil {
+			return df, fmt.Errorf("%s is not valid TOML: %v", f.Path, err)
+		}
+		df.Items = diffTomlDocuments(ours, theirs)
+		return df, nil
+	}
+	if !json.Valid(data) {
+		return df, fmt.Errorf("%s is not valid JSON", f.Path)
+	}
+	diffJSONValues(nil, f.Content, data, true, true, &df.Items)
+	return df, nil
+}
+
+func newDriftItem(path []string) DriftItem {
+	return DriftItem{Path: path, Key: strings.Join(path, ".")}
+}
+
+// diffJSONValues walks objects by key and arrays by index and records every value
+// that differs or exists on one side only.
+func diffJSONValues(path []string, ours, theirs json.RawMessage, haveOurs, haveTheirs bool, items *[]DriftItem) {
+	if haveOurs && haveTheirs {
+		oursObj, errOurs := parseOrderedJSON(ours)
+		theirsObj, errTheirs := parseOrderedJSON(theirs)
+		if errOurs == nil && errTheirs == nil {
+			for _, k := range oursObj.keys {
+				child := append(append([]string{}, path...), k)
+				diffJSONValues(child, oursObj.values[k], theirsObj.values[k], true, theirsObj.has(k), items)
+			}
+			for _, k := range theirsObj.keys {
+				if !oursObj.has(k) {
+					child := append(append([]string{}, path...), k)
+					diffJSONValues(child, nil, theirsObj.values[k], false, true, items)
+				}
+			}
+			return
+		}
+		var oursArr, theirsArr []json.RawMessage
+		if isJSONArray(ours) && isJSONArray(theirs) && json.Unmarshal(ours, &oursArr) == nil && json.Unmarshal(theirs, &theirsArr) == nil {
+			n := len(oursArr)
+			if len(theirsArr) > n {
+				n = len(theirsArr)
+			}
+			for i := 0; i < n; i++ {
+				child := append(append([]string{}, path...), fmt.Sprint(i))
+				var o, t json.RawMessage
+				if i < len(oursArr) {
+					o = oursArr[i]
+				}
+				if i < len(theirsArr) {
+					t = theirsArr[i]
+				}
+				diffJSONValues(child, o, t, i < len(oursArr), i < len(theirsArr), items)
+			}
+			return
+		}
+		var o, t interface{}
+		json.Unmarshal(ours, &o)
+		json.Unmarshal(theirs, &t)
+		if reflect.DeepEqual(o, t) {
+			return
+		}
+	}
+	item := newDriftItem(path)
+	if haveOurs {
+		item.Ours, item.OursSet = displayJSONValue(ours), true
+	}
+	if haveTheirs {
+		item.Theirs, item.TheirsSet = displayJSONValue(theirs), true
+	}
+	*items = append(*items, item)
+}
+
+// displayJSONValue shows strings without quotes and everything else as compact JSON.
+func displayJSONValue(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var buf bytes.Buffer
+	if json.Compact(&buf, raw) != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
+
+func diffTomlDocuments(ours, theirs *tomlDocument) []DriftItem {
+	items := []DriftItem{}
+	theirsLeaves := make(map[string]tomlLeaf)
+	for _, l := range theirs.leaves() {
+		theirsLeaves[strings.Join(l.path, "\x00")] = l
+	}
+	oursKeys := make(map[string]bool)
+	for _, o := range ours.leaves() {
+		id := strings.Join(o.path, "\x00")
+		oursKeys[id] = true
+		item := newDriftItem(o.path)
+		item.Ours, item.OursSet = fmt.Sprint(decodeTomlValue(o.value)), true
+		if t, ok := theirsLeaves[id]; ok {
+			if reflect.DeepEqual(decodeTomlValue(o.value), decodeTomlValue(t.value)) {
+				continue
+			}
+			item.Theirs, item.TheirsSet = fmt.Sprint(decodeTomlValue(t.value)), true
+		}
+		items = append(items, item)
+	}
+	for _, t := range theirs.leaves() {
+		if !oursKeys[strings.Join(t.path, "\x00")] {
+			item := newDriftItem(t.path)
+			item.Theirs, item.TheirsSet = fmt.Sprint(decodeTomlValue(t.value)), true
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func findTomlLeaf(doc *tomlDocument, path []string) (tomlLeaf, bool) {
+	for _, l := range doc.leaves() {
+		if sameTomlPath(l.path, path) {
+			return l, true
+		}
+	}
+	return tomlLeaf{}, false
+}
+
+// driftValueString returns the value at path in the file on disk, for adopting it.
+func driftValueString(f renderedConfigFile, data []byte, path []string) (string, error) {
+	key := strings.Join(path, ".")
+	if f.Format == "toml" {
+		doc, err := parseToml(string(data))
+		if err != nil {
+			return "", err
+		}
+		l, ok := findTomlLeaf(doc, path)
+		if !ok {
+			return "", fmt.Errorf("%s is not set in %s", key, f.Path)
+		}
+		s, ok := decodeTomlValue(l.value).(string)
+		if !ok {
+			return "", fmt.Errorf("%s in %s is not a string", key, f.Path)
+		}
+		return s, nil
+	}
+	raw, ok := jsonPathValue(data, path)
+	if !ok {
+		return "", fmt.Errorf("%s is not set in %s", key, f.Path)
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return "", fmt.Errorf("%s in %s is not a string", key, f.Path)
+	}
+	return s, nil
+}
+
+// reapplyDriftValue puts AICoder's value for one key back into the file content,
+// or removes the key if AICoder would not write it.
+func reapplyDriftValue(f renderedConfigFile, data []byte, path []string) ([]byte, error) {
+	key := strings.Join(path, ".")
+	if f.Format == "toml" {
+		ours, err := parseToml(string(f.Content))
+		if err != nil {
+			return nil, err
+		}
+		theirs, err := parseToml(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s is not valid TOML: %v", f.Path, err)
+		}
+		o, haveOurs := findTomlLeaf(ours, path)
+		t, haveTheirs := findTomlLeaf(theirs, path)
+		if (haveOurs && o.inArray) || (haveTheirs && t.inArray) {
+			return nil, fmt.Errorf("%s cannot be edited in place, re-apply the whole file instead", key)
+		}
+		if haveOurs {
+			theirs.set(o.parent(), o.key[len(o.key)-1], o.value)
+		} else if haveTheirs {
+			theirs.remove(t.parent(), t.key[len(t.key)-1])
+		}
+		return []byte(theirs.String()), nil
+	}
+	var patched json.RawMessage
+	var err error
+	if value, ok := jsonPathValue(f.Content, path); ok {
+		patched, err = setJSONPath(data, path, value)
+	} else {
+		patched, err = removeJSONPath(data, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, patched, "", "  "); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// driftKeys lists the drifted keys as key=ours/theirs, with - for a missing side.
+func driftKeys(items []DriftItem) string {
+	var out []string
+	for _, it := range items {
+		ours, theirs := "-", "-"
+		if it.OursSet {
+			ours = it.Ours
+		}
+		if it.TheirsSet {
+			theirs = it.Theirs
+		}
+		out = append(out, it.Key+"="+ours+"/"+theirs)
+	}
+	sort.Strings(out)
+	return strings.Join(out, " ")
+}
+
+func TestDiffRenderedFileJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	f := renderedConfigFile{Path: path, Format: "json", Content: []byte(`{"env":{"A":"1","B":"2"},"list":[1,2],"n":1}`)}
+	if df, err := diffRenderedFile("claude", f); err != nil || len(df.Items) != 0 {
+		t.Fatalf("missing file = %+v, %v", df, err)
+	}
+	os.WriteFile(path, []byte(`{"n":1.0,"list":[1,3,4],"env":{"A":"changed","C":"mine"}}`), 0600)
+	df, err := diffRenderedFile("claude", f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := driftKeys(df.Items), "env.A=1/changed env.B=2/- env.C=-/mine list.1=2/3 list.2=-/4"; got != want {
+		t.Fatalf("drift = %s, want %s", got, want)
+	}
+	os.WriteFile(path, []byte(`{"env":`), 0600)
+	if _, err := diffRenderedFile("claude", f); err == nil {
+		t.Fatal("invalid JSON compared")
+	}
+}
+
+func TestDiffRenderedFileToml(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	f := renderedConfigFile{Path: path, Format: "toml", Content: []byte("model = \"m-1\"\n\n[model_providers.p]\nbase_url = \"https://a\"\nname = \"p\"\n")}
+	os.WriteFile(path, []byte("model = \"m-1\" # mine\nmodel_providers.p = { base_url = \"https://b\" }\n\n[mcp_servers.docs]\ncommand = \"npx\"\n"), 0600)
+	df, err := diffRenderedFile("codex", f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "mcp_servers.docs.command=-/npx model_providers.p.base_url=https://a/https://b model_providers.p.name=p/-"
+	if got := driftKeys(df.Items); got != want {
+		t.Fatalf("drift = %s, want %s", got, want)
+	}
+}
+
+func TestReapplyDriftValue(t *testing.T) {
+	jsonFile := renderedConfigFile{Path: "settings.json", Format: "json", Content: []byte(`{"env":{"A":"1","B":"2"}}`)}
+	for _, c := range []struct {
+		path []string
+		want string
+	}{
+		{[]string{"env", "A"}, `{"env":{"A":"1","B":"mine","C":"mine"},"hooks":{}}`},
+		{[]string{"env", "C"}, `{"env":{"A":"changed","B":"mine"},"hooks":{}}`},
+	} {
+		out, err := reapplyDriftValue(jsonFile, []byte(`{"env":{"A":"changed","B":"mine","C":"mine"},"hooks":{}}`), c.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(strings.Fields(string(out)), ""); got != c.want {
+			t.Errorf("reapply %v = %s, want %s", c.path, got, c.want)
+		}
+	}
+
+	tomlFile := renderedConfigFile{Path: "config.toml", Format: "toml", Content: []byte("[model_providers.p]\nbase_url = \"https://a\"\n")}
+	data := []byte("# mine\nmodel_providers.p = { base_url = \"https://b\", extra = 1 }\n")
+	out, err := reapplyDriftValue(tomlFile, data, []string{"model_providers", "p", "base_url"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(out), "# mine\nmodel_providers.p.base_url = \"https://a\"\nmodel_providers.p.extra = 1\n"; got != want {
+		t.Fatalf("reapply TOML =\n%s\nwant\n%s", got, want)
+	}
+	out, err = reapplyDriftValue(tomlFile, out, []string{"model_providers", "p", "extra"})
+	if err != nil || strings.Contains(string(out), "extra") {
+		t.Fatalf("key AICoder does not write kept:\n%s %v", out, err)
+	}
+	if _, err := reapplyDriftValue(tomlFile, []byte("[[profiles]]\nname = \"a\"\n"), []string{"profiles", "0", "name"}); err == nil {
+		t.Fatal("array of tables edited in place")
+	}
+}
+
+// newDriftTestApp returns an App whose Claude provider has two keys with the second
+// in use, and whose settings have been synced.
+func newDriftTestApp(t *testing.T) (*App, string) {
+	t.Helper()
+	a := newSecretTestApp(t, newFakeSecretBackend("fake"))
+	t.Setenv("HOME", a.testHomeDir)
+	config := secretTestConfig()
+	m := ModelConfig{ModelName: "Gateway", IsCustom: true, ModelUrl: "https://gw.example", ModelId: "claude-x",
+		ApiKeys: []ApiKeyEntry{{Label: "personal", Key: "sk-personal"}, {Label: "team", Key: "sk-team"}}, ActiveKey: 1}
+	normalizeApiKeys(&m)
+	config.Claude = ToolConfig{CurrentModel: "Gateway", Models: []ModelConfig{{ModelName: "Original"}, m}}
+	path, _ := a.getConfigPath()
+	if err := a.saveToPath(path, config); err != nil {
+		t.Fatal(err)
+	}
+	config, err := a.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.syncToClaudeSettings(config); err != nil {
+		t.Fatal(err)
+	}
+	_, settingsPath, _ := a.getClaudeConfigPaths()
+	return a, settingsPath
+}
+
+// claudeDrift returns the drift CheckConfigDrift reports for settings.json.
+func claudeDrift(t *testing.T, a *App, settingsPath string) []DriftItem {
+	t.Helper()
+	report, err := a.CheckConfigDrift()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range report.Files {
+		if f.Path == settingsPath {
+			return f.Items
+		}
+	}
+	return nil
+}
+
+func TestCheckConfigDriftAndAdopt(t *testing.T) {
+	a, settingsPath := newDriftTestApp(t)
+	if items := claudeDrift(t, a, settingsPath); len(items) != 0 {
+		t.Fatalf("drift right after a sync: %s", driftKeys(items))
+	}
+
+	settings, err := readOrderedJSONFile(settingsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := settings.object("env")
+	env.set("ANTHROPIC_AUTH_TOKEN", "sk-rotated")
+	env.remove("ANTHROPIC_MODEL")
+	settings.set("env", env)
+	if err := writeOrderedJSONFile(settingsPath, settings); err != nil {
+		t.Fatal(err)
+	}
+	items := claudeDrift(t, a, settingsPath)
+	if got, want := driftKeys(items), "env.ANTHROPIC_AUTH_TOKEN=sk-team/sk-rotated env.ANTHROPIC_MODEL=claude-x/-"; got != want {
+		t.Fatalf("drift = %s, want %s", got, want)
+	}
+	for _, it := range items {
+		if it.Key == "env.ANTHROPIC_AUTH_TOKEN" && it.Field != "api_key" {
+			t.Fatalf("token adoptable into %q", it.Field)
+		}
+	}
+
+	if err := a.ResolveConfigDrift("claude", settingsPath, []string{"env", "ANTHROPIC_AUTH_TOKEN"}, "adopt"); err != nil {
+		t.Fatal(err)
+	}
+	// The active entry of the key list gets the new key, or reading the config
+	// back would restore the old one from it
+	config, err := a.readConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := getProviderModel(&config.Claude, "Gateway")
+	if m.ApiKey != "sk-rotated" || m.ActiveKey != 1 || m.ApiKeys[1].Key != "sk-rotated" || m.ApiKeys[1].Label != "team" || m.ApiKeys[0].Key != "sk-personal" {
+		t.Fatalf("adopted key: %q, active %d, %+v", m.ApiKey, m.ActiveKey, m.ApiKeys)
+	}
+	if got := driftKeys(claudeDrift(t, a, settingsPath)); got != "env.ANTHROPIC_MODEL=claude-x/-" {
+		t.Fatalf("drift after adopting = %s", got)
+	}
+	if err := a.ResolveConfigDrift("claude", settingsPath, []string{"env", "CLAUDE_CODE_USE_COLORS"}, "adopt"); err == nil {
+		t.Fatal("key without a ModelConfig field adopted")
+	}
+}
+
+func TestResolveConfigDriftReapply(t *testing.T) {
+	a, settingsPath := newDriftTestApp(t)
+	settings, err := readOrderedJSONFile(settingsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := settings.object("env")
+	env.set("ANTHROPIC_MODEL", "claude-y")
+	env.set("ANTHROPIC_BASE_URL", "https://other.example")
+	env.set("MY_SETTING", "1")
+	settings.set("env", env)
+	if err := writeOrderedJSONFile(settingsPath, settings); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.ResolveConfigDrift("claude", settingsPath, []string{"env", "ANTHROPIC_MODEL"}, "reapply"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := driftKeys(claudeDrift(t, a, settingsPath)), "env.ANTHROPIC_BASE_URL=https://gw.example/https://other.example"; got != want {
+		t.Fatalf("drift after re-applying one key = %s, want %s", got, want)
+	}
+	// The whole file goes back to what a sync writes
+	if err := a.ResolveConfigDrift("claude", settingsPath, nil, "reapply"); err != nil {
+		t.Fatal(err)
+	}
+	if items := claudeDrift(t, a, settingsPath); len(items) != 0 {
+		t.Fatalf("drift after re-applying the file = %s", driftKeys(items))
+	}
+	// Keys AICoder does not manage are not drift and survive a re-apply
+	settings, err = readOrderedJSONFile(settingsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mine string
+	if settings.object("env").get("MY_SETTING", &mine); mine != "1" {
+		t.Fatalf("MY_SETTING = %q after re-applying", mine)
+	}
+	if err := a.ResolveConfigDrift("claude", filepath.Join(a.testHomeDir, "other.json"), nil, "reapply"); err == nil {
+		t.Fatal("unmanaged file written")
+	}
+	if err := a.ResolveConfigDrift("claude", settingsPath, nil, "merge"); err == nil {
+		t.Fatal("unknown action accepted")
+	}
+}
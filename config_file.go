@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// configBackupCount is how many earlier versions of the config file are kept.
+const configBackupCount = 10
+
+// atomicWriteFile replaces path with data so that readers see either the old or the
+// new content, never a truncated file: the data goes to a temporary file in the same
+// directory, is synced and then renamed over path. A symlink at path is followed, so
+// files kept in a dotfiles repository stay linked.
+func atomicWriteFile(path string, data []byte, perm os.FileMode) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	fail := func(err error) error {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return fail(err)
+	}
+	if err := f.Chmod(perm); err != nil {
+		return fail(err)
+	}
+	if err := f.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	// Make the rename itself durable; directories cannot be synced on Windows
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+func (a *App) configBackupDir() string {
+	return filepath.Join(a.GetUserHomeDir(), ".cceasy", "config_backups")
+}
+
+// configBackups returns the backups of the config file, newest first.
+func (a *App) configBackups() []string {
+	entries, err := os.ReadDir(a.configBackupDir())
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), "aicoder_config-") && strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, e.Name())
+		}
+	}
+	// The timestamp in the name sorts chronologically
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	return names
+}
+
+// backupConfigFile keeps a copy of the config file at path before it is replaced
+// by next. Only a file that parses is kept, and the oldest backups are dropped.
+func (a *App) backupConfigFile(path string, next []byte) {
+	current, err := os.ReadFile(path)
+	if err != nil || bytes.Equal(current, next) || json.Unmarshal(current, &AppConfig{}) != nil {
+		return
+	}
+	name := "aicoder_config-" + time.Now().Format("20060102-150405.000") + ".json"
+	if err := writePrivateFile(filepath.Join(a.configBackupDir(), name), current); err != nil {
+		a.log("Config backup failed: " + err.Error())
+		return
+	}
+	backups := a.configBackups()
+	for i := configBackupCount; i < len(backups); i++ {
+		os.Remove(filepath.Join(a.configBackupDir(), backups[i]))
+	}
+}
+
+// ConfigRecovery describes a damaged config file that was replaced by a backup.
+type ConfigRecovery struct {
+	Error       string `json:"error"`        // Why the config file could not be read
+	Backup      string `json:"backup"`       // Backup that was restored
+	CorruptCopy string `json:"corrupt_copy"` // Where the damaged file was kept
+	Time        string `json:"time"`
+}
+
+// recoverConfig replaces a config file that does not parse with the newest backup
+// that does, keeping the damaged file next to it. It returns the restored content.
+func (a *App) recoverConfig(path string, damaged []byte, cause error) ([]byte, error) {
+	for _, name := range a.configBackups() {
+		data, err := os.ReadFile(filepath.Join(a.configBackupDir(), name))
+		if err != nil || json.Unmarshal(data, &AppConfig{}) != nil {
+			continue
+		}
+		now := time.Now()
+		corrupt := path + ".corrupt-" + now.Format("20060102-150405")
+		if err := writePrivateFile(corrupt, damaged); err != nil {
+			return nil, err
+		}
+		if err := writePrivateFile(path, data); err != nil {
+			return nil, err
+		}
+		rec := ConfigRecovery{Error: cause.Error(), Backup: name, CorruptCopy: corrupt, Time: now.Format(time.RFC3339)}
+		a.configRecovery = &rec
+		a.log(fmt.Sprintf("Config file was damaged (%v); restored backup %s, damaged file kept as %s", cause, name, corrupt))
+		a.emitEvent("config-recovered", rec)
+		return data, nil
+	}
+	return nil, fmt.Errorf("config file is damaged (%v) and no usable backup was found", cause)
+}
+
+// GetConfigRecovery returns the last recovery from a damaged config file in this
+// session, or nil. The UI asks at start, as the event may fire before it listens.
+func (a *App) GetConfigRecovery() *ConfigRecovery {
+	return a.configRecovery
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfigBackup adds a backup of the config file with the given timestamp.
+func writeConfigBackup(t *testing.T, a *App, stamp, content string) {
+	t.Helper()
+	dir := a.configBackupDir()
+	os.MkdirAll(dir, 0700)
+	if err := os.WriteFile(filepath.Join(dir, "aicoder_config-"+stamp+".json"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadConfigRecoversFromNewestGoodBackup(t *testing.T) {
+	a, path := newFixtureApp(t, "config_current")
+	damaged := `{"claude": {"current_model": "Ki`
+	os.WriteFile(path, []byte(damaged), 0600)
+	writeConfigBackup(t, a, "20260101-100000.000", string(readConfigFixture(t, "config_current")))
+	// Newer, but damaged as well
+	writeConfigBackup(t, a, "20260102-100000.000", `{"claude": [`)
+
+	config, err := a.readConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := getProviderModel(&config.Claude, "Kimi"); m == nil || m.ApiKey != "sk-cur-kimi" {
+		t.Fatalf("restored Kimi = %+v", m)
+	}
+	rec := a.GetConfigRecovery()
+	if rec == nil || rec.Backup != "aicoder_config-20260101-100000.000.json" {
+		t.Fatalf("recovery = %+v", rec)
+	}
+	if kept, err := os.ReadFile(rec.CorruptCopy); err != nil || string(kept) != damaged {
+		t.Fatalf("damaged file not kept: %q, %v", kept, err)
+	}
+	if !strings.HasPrefix(rec.CorruptCopy, path+".corrupt-") {
+		t.Fatalf("damaged copy at %s", rec.CorruptCopy)
+	}
+}
+
+func TestReadConfigWithoutUsableBackup(t *testing.T) {
+	a, path := newFixtureApp(t, "config_current")
+	os.WriteFile(path, []byte("{"), 0600)
+	writeConfigBackup(t, a, "20260101-100000.000", "not json")
+
+	if _, err := a.readConfig(); err == nil || !strings.Contains(err.Error(), "no usable backup") {
+		t.Fatalf("err = %v", err)
+	}
+	if a.GetConfigRecovery() != nil {
+		t.Fatal("recovery reported without a backup")
+	}
+	// The damaged file is left for the user
+	if data, _ := os.ReadFile(path); string(data) != "{" {
+		t.Fatalf("damaged file replaced with %q", data)
+	}
+}
+
+func TestBackupConfigFileKeepsOnlyGoodCopies(t *testing.T) {
+	a, path := newFixtureApp(t, "config_current")
+	a.backupConfigFile(path, []byte("{}"))
+	if backups := a.configBackups(); len(backups) != 1 {
+		t.Fatalf("backups = %v", backups)
+	}
+	// An unchanged or damaged file is not backed up
+	current, _ := os.ReadFile(path)
+	a.backupConfigFile(path, current)
+	os.WriteFile(path, []byte("{"), 0600)
+	a.backupConfigFile(path, []byte("{}"))
+	if backups := a.configBackups(); len(backups) != 1 {
+		t.Fatalf("backups = %v", backups)
+	}
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// configHistoryLimit is how many config changes the history keeps.
+const configHistoryLimit = 100
+
+// configHistorySlack is how far the history may grow past configHistoryLimit before
+// the oldest entries are dropped, so the index is not rewritten on every change.
+const configHistorySlack = 20
+
+// historyKeyAccount names the key sealing the config snapshots in the history.
+const historyKeyAccount = "history/key"
+
+// ConfigHistoryEntry is one saved change of the config.
+type ConfigHistoryEntry struct {
+	Id      string `json:"id"`
+	Time    string `json:"time"`
+	Source  string `json:"source"`  // ui, tray, watcher, migration, app or revert
+	Summary string `json:"summary"` // The first settings changed
+	Changed int    `json:"changed"` // How many settings changed
+}
+
+// ConfigChange is one setting changed by a history entry. Values are JSON, and
+// empty when the setting was added or removed. Secrets only show as redacted.
+type ConfigChange struct {
+	Path string `json:"path"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// configHistoryRecord is a line of the history index. The config after the change,
+// secrets included, is kept compressed and sealed in a snapshot file of its own.
+type configHistoryRecord struct {
+	ConfigHistoryEntry
+	Changes []ConfigChange `json:"changes"`
+}
+
+func (a *App) configHistoryPath() string {
+	return filepath.Join(a.GetUserHomeDir(), ".cceasy", "config_history.jsonl")
+}
+
+// configSnapshotPath is where the config saved with history entry id is kept.
+func (a *App) configSnapshotPath(id string) string {
+	return filepath.Join(a.GetUserHomeDir(), ".cceasy", "config_history", id+".snapshot")
+}
+
+// redactedSecrets replaces the secrets in copies of old and next: a secret shows as
+// redacted, and as changed in next when it differs from the one in old.
+func redactedSecrets(old, next AppConfig) (AppConfig, AppConfig) {
+	old, next = cloneConfig(old), cloneConfig(next)
+	before := make(map[string]string)
+	forEachSecret(&old, func(account string, value *string) {
+		before[account] = *value
+		if *value != "" {
+			*value = redactedMark
+		}
+	})
+	forEachSecret(&next, func(account string, value *string) {
+		switch {
+		case *value == "":
+		case *value == before[account]:
+			*value = redactedMark
+		default:
+			*value = redactedMark + " (changed)"
+		}
+	})
+	return old, next
+}
+
+// flattenConfig maps the path of every value in config to its JSON. List items are
+// named by model_name or id where they have one, so reordering is not a change.
+func flattenConfig(config AppConfig) map[string]string {
+	values := make(map[string]string)
+	data, err := json.Marshal(config)
+	if err != nil {
+		return values
+	}
+	var tree interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return values
+	}
+	var walk func(path string, v interface{})
+	walk = func(path string, v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for k, child := range v {
+				if path == "" {
+					walk(k, child)
+				} else {
+					walk(path+"."+k, child)
+				}
+			}
+		case []interface{}:
+			for i, child := range v {
+				name := strconv.Itoa(i)
+				if obj, ok := child.(map[string]interface{}); ok {
+					if s, ok := obj["model_name"].(string); ok && s != "" {
+						name = s
+					} else if s, ok := obj["id"].(string); ok && s != "" {
+						name = s
+					}
+				}
+				walk(path+"["+name+"]", child)
+			}
+		default:
+			leaf, _ := json.Marshal(v)
+			values[path] = string(leaf)
+		}
+	}
+	walk("", tree)
+	return values
+}
+
+// diffConfigs lists the settings that differ between old and next, secrets redacted.
+func (a *App) diffConfigs(old, next AppConfig) []ConfigChange {
+	old, next = redactedSecrets(old, next)
+	old.Revision, next.Revision = 0, 0
+	before, after := flattenConfig(old), flattenConfig(next)
+	var changes []ConfigChange
+	for path, v := range before {
+		if after[path] != v {
+			changes = append(changes, ConfigChange{Path: path, Old: a.redactor.redact(v), New: a.redactor.redact(after[path])})
+		}
+	}
+	for path, v := range after {
+		if _, ok := before[path]; !ok {
+			changes = append(changes, ConfigChange{Path: path, New: a.redactor.redact(v)})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+func summarizeChanges(changes []ConfigChange) string {
+	var paths []string
+	for i, c := range changes {
+		if i == 3 {
+			paths = append(paths, fmt.Sprintf("and %d more", len(changes)-3))
+			break
+		}
+		paths = append(paths, c.Path)
+	}
+	return strings.Join(paths, ", ")
+}
+
+// historyCipherKey returns the key sealing the snapshots, kept in the secret store.
+func (a *App) historyCipherKey() ([]byte, error) {
+	encoded, err := a.secrets().secret(historyKeyAccount, func() (string, error) {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString(key), nil
+	}, a.log)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(encoded)
+}
+
+// sealSnapshot compresses and encrypts config for the entry id. The nonce comes first.
+func (a *App) sealSnapshot(id string, config AppConfig) ([]byte, error) {
+	plain, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(plain)
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	key, err := a.historyCipherKey()
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newBundleCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, buf.Bytes(), []byte(id)), nil
+}
+
+// openSnapshot returns the config saved with history entry id.
+func (a *App) openSnapshot(id string) (AppConfig, error) {
+	var config AppConfig
+	sealed, err := os.ReadFile(a.configSnapshotPath(id))
+	if os.IsNotExist(err) {
+		return config, fmt.Errorf("the config saved with history entry %s is missing", id)
+	}
+	if err != nil {
+		return config, err
+	}
+	key, err := a.historyCipherKey()
+	if err != nil {
+		return config, err
+	}
+	gcm, err := newBundleCipher(key)
+	if err != nil {
+		return config, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return config, fmt.Errorf("the config saved with history entry %s is damaged", id)
+	}
+	packed, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(id))
+	if err != nil {
+		return config, fmt.Errorf("the config saved with history entry %s cannot be decrypted", id)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(packed))
+	if err != nil {
+		return config, err
+	}
+	plain, err := io.ReadAll(zr)
+	if err != nil {
+		return config, err
+	}
+	err = json.Unmarshal(plain, &config)
+	return config, err
+}
+
+// readConfigHistory returns the records in the history file, oldest first. Lines
+// that do not parse, e.g. one cut short by a crash, are skipped.
+func (a *App) readConfigHistory() ([]configHistoryRecord, error) {
+	f, err := os.Open(a.configHistoryPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var records []configHistoryRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		var rec configHistoryRecord
+		if json.Unmarshal(scanner.Bytes(), &rec) == nil && rec.Id != "" {
+			records = append(records, rec)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// recordConfigHistory appends the change from old to next to the history. The
+// config store calls it for every committed change; a failure is only logged, as
+// the change itself has been saved.
+func (a *App) recordConfigHistory(source string, old, next *AppConfig) {
+	changes := a.diffConfigs(*old, *next)
+	if len(changes) == 0 {
+		return
+	}
+	a.historyMu.Lock()
+	defer a.historyMu.Unlock()
+	now := time.Now()
+	rec := configHistoryRecord{
+		ConfigHistoryEntry: ConfigHistoryEntry{
+			Id:      now.Format("20060102-150405.000000"),
+			Time:    now.Format(time.RFC3339),
+			Source:  source,
+			Summary: summarizeChanges(changes),
+			Changed: len(changes),
+		},
+		Changes: changes,
+	}
+	if err := a.appendConfigHistory(rec, *next); err != nil {
+		a.log("Config history: " + err.Error())
+	}
+}
+
+// appendConfigHistory writes the snapshot and the index line of rec, and drops the
+// oldest entries once there are configHistorySlack more than configHistoryLimit.
+// The index is only read to count its entries the first time. a.historyMu must be held.
+func (a *App) appendConfigHistory(rec configHistoryRecord, config AppConfig) error {
+	if !a.historyCounted {
+		records, err := a.readConfigHistory()
+		if err != nil {
+			return err
+		}
+		a.historyCount, a.historyCounted = len(records), true
+	}
+	sealed, err := a.sealSnapshot(rec.Id, config)
+	if err != nil {
+		return err
+	}
+	if err := writePrivateFile(a.configSnapshotPath(rec.Id), sealed); err != nil {
+		return err
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if err := appendPrivateFile(a.configHistoryPath(), append(line, '\n')); err != nil {
+		return err
+	}
+	a.historyCount++
+	if a.historyCount <= configHistoryLimit+configHistorySlack {
+		return nil
+	}
+	records, err := a.readConfigHistory()
+	if err != nil {
+		return err
+	}
+	if len(records) <= configHistoryLimit {
+		a.historyCount = len(records)
+		return nil
+	}
+	dropped, kept := records[:len(records)-configHistoryLimit], records[len(records)-configHistoryLimit:]
+	var buf bytes.Buffer
+	for _, r := range kept {
+		data, _ := json.Marshal(r)
+		buf.Write(append(data, '\n'))
+	}
+	if err := writePrivateFile(a.configHistoryPath(), buf.Bytes()); err != nil {
+		return err
+	}
+	a.historyCount = len(kept)
+	for _, r := range dropped {
+		os.Remove(a.configSnapshotPath(r.Id))
+	}
+	return nil
+}
+
+func (a *App) findConfigHistory(id string) (*configHistoryRecord, error) {
+	a.historyMu.Lock()
+	records, err := a.readConfigHistory()
+	a.historyMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		if records[i].Id == id {
+			return &records[i], nil
+		}
+	}
+	return nil, fmt.Errorf("history entry %s not found", id)
+}
+
+// ListConfigHistory returns the saved changes of the config, newest first.
+func (a *App) ListConfigHistory() ([]ConfigHistoryEntry, error) {
+	a.historyMu.Lock()
+	records, err := a.readConfigHistory()
+	a.historyMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]ConfigHistoryEntry, 0, len(records))
+	for i := len(records) - 1; i >= 0; i-- {
+		entries = append(entries, records[i].ConfigHistoryEntry)
+	}
+	return entries, nil
+}
+
+// GetConfigHistoryDiff returns the settings a history entry changed.
+func (a *App) GetConfigHistoryDiff(id string) ([]ConfigChange, error) {
+	rec, err := a.findConfigHistory(id)
+	if err != nil {
+		return nil, err
+	}
+	return rec.Changes, nil
+}
+
+// RevertConfig restores the whole config, secrets included, as it was right after
+// the given history entry. The revert is itself recorded, so it can be undone.
+func (a *App) RevertConfig(id string) (AppConfig, error) {
+	rec, err := a.findConfigHistory(id)
+	if err != nil {
+		return AppConfig{}, err
+	}
+	snapshot, err := a.openSnapshot(rec.Id)
+	if err != nil {
+		return AppConfig{}, err
+	}
+	// A snapshot from before an upgrade gets the migrations a config file would;
+	// the store normalizes it like any other change
+	a.migrateConfig(&snapshot, currentCatalog())
+	// Restored as a whole, without copying the keys it changes to other tools
+	config, err := a.configStore().update(configSourceRevert, func(c *AppConfig) error {
+		*c = snapshot
+		return nil
+	}, a.writeConfig)
+	if err != nil {
+		return config, err
+	}
+	a.log(fmt.Sprintf("Config reverted to history entry %s (%s)", id, rec.Time))
+	a.emitEvent("config-updated", config)
+	return config, nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newHistoryTestApp returns an App with secretTestConfig saved and loaded.
+func newHistoryTestApp(t *testing.T) *App {
+	t.Helper()
+	a := newSecretTestApp(t, newFakeSecretBackend("fake"))
+	t.Setenv("HOME", a.testHomeDir)
+	path, _ := a.getConfigPath()
+	if err := a.saveToPath(path, secretTestConfig()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestConfigHistoryRecordsRedactedChanges(t *testing.T) {
+	a := newHistoryTestApp(t)
+	if _, err := a.modifyConfig(func(c *AppConfig) error {
+		c.DefaultProxyPassword = "proxy-password-2"
+		c.EnvCheckInterval = 9
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	a.modifyConfigFrom(configSourceTray, func(c *AppConfig) error {
+		c.Claude.CurrentModel = "Original"
+		return nil
+	})
+	// A change that changes nothing is not recorded
+	a.modifyConfig(func(c *AppConfig) error { return nil })
+
+	entries, err := a.ListConfigHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Source != configSourceTray || entries[1].Source != configSourceUI || entries[1].Changed != 2 {
+		t.Fatalf("entries = %+v", entries)
+	}
+	changes, err := a.GetConfigHistoryDiff(entries[1].Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.Path+": "+c.Old+" -> "+c.New)
+	}
+	want := `default_proxy_password: "` + redactedMark + `" -> "` + redactedMark + ` (changed)"` + "\n" + `env_check_interval: 7 -> 9`
+	if strings.Join(got, "\n") != want {
+		t.Fatalf("changes:\n%s\nwant\n%s", strings.Join(got, "\n"), want)
+	}
+	if _, err := a.GetConfigHistoryDiff("missing"); err == nil {
+		t.Fatal("unknown entry found")
+	}
+
+	// Neither the index nor the sealed snapshots hold a secret in the clear
+	files := []string{a.configHistoryPath()}
+	snapshots, _ := filepath.Glob(filepath.Join(filepath.Dir(a.configSnapshotPath("x")), "*.snapshot"))
+	if len(snapshots) != 2 {
+		t.Fatalf("%d snapshots, want 2", len(snapshots))
+	}
+	for _, f := range append(files, snapshots...) {
+		data, _ := os.ReadFile(f)
+		if strings.Contains(string(data), "sk-glm-secret") || strings.Contains(string(data), "proxy-password") {
+			t.Fatalf("%s holds a secret", f)
+		}
+	}
+}
+
+func TestRevertConfig(t *testing.T) {
+	a := newHistoryTestApp(t)
+	a.modifyConfig(func(c *AppConfig) error {
+		getProviderModel(&c.Claude, "GLM").ApiKey = "sk-glm-secret-2"
+		return nil
+	})
+	a.modifyConfig(func(c *AppConfig) error {
+		getProviderModel(&c.Claude, "GLM").ApiKey = "sk-glm-secret-3"
+		c.EnvCheckInterval = 20
+		return nil
+	})
+	entries, _ := a.ListConfigHistory()
+	config, err := a.RevertConfig(entries[1].Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := getProviderModel(&config.Claude, "GLM"); m.ApiKey != "sk-glm-secret-2" || config.EnvCheckInterval != 7 {
+		t.Fatalf("reverted to key %q, interval %d", m.ApiKey, config.EnvCheckInterval)
+	}
+	// Saved with its secret, and recorded so the revert can be undone
+	saved, err := a.readConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := getProviderModel(&saved.Claude, "GLM"); m.ApiKey != "sk-glm-secret-2" {
+		t.Fatalf("saved key %q", m.ApiKey)
+	}
+	if entries, _ := a.ListConfigHistory(); len(entries) != 3 || entries[0].Source != configSourceRevert {
+		t.Fatalf("entries after revert = %+v", entries)
+	}
+	if _, err := a.RevertConfig("missing"); err == nil {
+		t.Fatal("reverted to an unknown entry")
+	}
+}
+
+// A snapshot taken before an upgrade is migrated and normalized like a config file.
+func TestRevertConfigMigratesSnapshot(t *testing.T) {
+	a := newHistoryTestApp(t)
+	var v0 AppConfig
+	if err := json.Unmarshal(readConfigFixture(t, "config_v0"), &v0); err != nil {
+		t.Fatal(err)
+	}
+	current, _ := a.LoadConfig()
+	a.recordConfigHistory(configSourceApp, &current, &v0)
+	entries, _ := a.ListConfigHistory()
+	config, err := a.RevertConfig(entries[0].Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.SchemaVersion != latestSchemaVersion() {
+		t.Fatalf("schema_version = %d", config.SchemaVersion)
+	}
+	var qoder []string
+	for _, m := range config.Qoder.Models {
+		qoder = append(qoder, m.ModelName)
+	}
+	if strings.Join(qoder, ",") != "Original,Qoder" || getProviderModel(&config.Qoder, "Qoder").ApiKey != "sk-v0-qoder" {
+		t.Fatalf("qoder providers %v", qoder)
+	}
+	if config.Claude.Models[0].ModelName != "Original" || config.Codex.CurrentModel != "Kimi" {
+		t.Fatalf("not normalized: %s first, codex %q", config.Claude.Models[0].ModelName, config.Codex.CurrentModel)
+	}
+	if m := getProviderModel(&config.Claude, "DeepSeek"); m == nil || m.CatalogUrl == "" {
+		t.Fatalf("catalog endpoints not recorded: %+v", m)
+	}
+}
+
+func TestConfigHistoryTrims(t *testing.T) {
+	a := newHistoryTestApp(t)
+	old, _ := a.LoadConfig()
+	var first string
+	for i := 0; i <= configHistoryLimit+configHistorySlack; i++ {
+		next := cloneConfig(old)
+		next.EnvCheckInterval = 2 + i%29
+		next.CurrentProject = strings.Repeat("p", i+1)
+		a.recordConfigHistory(configSourceApp, &old, &next)
+		if i == 0 {
+			entries, _ := a.ListConfigHistory()
+			first = entries[0].Id
+		}
+		old = next
+	}
+	entries, err := a.ListConfigHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != configHistoryLimit {
+		t.Fatalf("%d entries, want %d", len(entries), configHistoryLimit)
+	}
+	if _, err := os.Stat(a.configSnapshotPath(first)); !os.IsNotExist(err) {
+		t.Fatal("snapshot of a dropped entry kept")
+	}
+	if _, err := a.openSnapshot(entries[len(entries)-1].Id); err != nil {
+		t.Fatalf("oldest kept snapshot: %v", err)
+	}
+	snapshots, _ := filepath.Glob(filepath.Join(filepath.Dir(a.configSnapshotPath("x")), "*.snapshot"))
+	if len(snapshots) != configHistoryLimit {
+		t.Fatalf("%d snapshots, want %d", len(snapshots), configHistoryLimit)
+	}
+}
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ImportCandidate is a provider found in a tool's own config files or in the
+// environment, proposed as a ModelConfig entry.
+type ImportCandidate struct {
+	Tool     string     `json:"tool"`
+	Source   string     `json:"source"`   // Config file it was read from, or "environment"
+	Provider string     `json:"provider"` // Catalog provider name, or the proposed custom provider name
+	Known    bool       `json:"known"`    // Matched a catalog provider by base URL
+	BaseUrl  string     `json:"base_url"`
+	ModelId  string     `json:"model_id"`
+	WireApi  string     `json:"wire_api"`
+	Roles    ModelRoles `json:"roles"`
+	ApiKey   string     `json:"api_key"`
+	Current  bool       `json:"current"` // The tool is set up to use it right now
+	Status   string     `json:"status"`  // "new", "same" (already configured) or "differs" (would replace a key)
+}
+
+// normalizeBaseUrl strips what tools add or leave off a base URL, so that the
+// same endpoint compares equal however it was written.
+func normalizeBaseUrl(u string) string {
+	u = strings.ToLower(strings.TrimSpace(u))
+	u = strings.TrimRight(u, "/")
+	u = strings.TrimSuffix(u, "/chat/completions")
+	u = strings.TrimSuffix(u, "/v1")
+	return strings.TrimRight(u, "/")
+}
+
+func baseUrlHost(u string) string {
+	parsed, err := url.Parse(strings.TrimSpace(u))
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+// matchCatalogProvider finds the catalog provider a tool reaches at baseUrl: an exact
+// endpoint match first, then a provider of the tool on the same host.
+func matchCatalogProvider(tool, baseUrl string) *CatalogProvider {
+	catalog := currentCatalog()
+	want := normalizeBaseUrl(baseUrl)
+	host := baseUrlHost(baseUrl)
+	var byHost *CatalogProvider
+	for i := range catalog.Providers {
+		p := &catalog.Providers[i]
+		e, ok := p.Tools[tool]
+		if !ok || e.BaseUrl == "" {
+			continue
+		}
+		if normalizeBaseUrl(e.BaseUrl) == want {
+			return p
+		}
+		if byHost == nil && host != "" && baseUrlHost(e.BaseUrl) == host {
+			byHost = p
+		}
+	}
+	return byHost
+}
+
+// isOfficialEndpoint reports whether baseUrl is the vendor's own API, which the
+// tool's Original mode already covers.
+func isOfficialEndpoint(tool, baseUrl string) bool {
+	if baseUrl == "" {
+		return true
+	}
+	switch baseUrlHost(baseUrl) {
+	case "api.anthropic.com":
+		return tool == "claude"
+	case "api.openai.com":
+		return tool == "codex"
+	}
+	return false
+}
+
+// customNameForUrl proposes a custom provider name from a config's own label or the host.
+func customNameForUrl(label, baseUrl string) string {
+	name := strings.TrimSpace(label)
+	if name == "" {
+		host := baseUrlHost(baseUrl)
+		if host == "localhost" || net.ParseIP(host) != nil {
+			return "Local"
+		}
+		name = strings.Split(strings.TrimPrefix(host, "api."), ".")[0]
+	}
+	if name == "" {
+		return "Custom"
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// scanClaudeSettings reads the env block of ~/.claude/settings.json.
+func (a *App) scanClaudeSettings() []ImportCandidate {
+	_, settingsPath, _ := a.getClaudeConfigPaths()
+	data, err := os.ReadFile(settingsPath)
+	if err != nil {
+		return nil
+	}
+	var settings struct {
+		Env map[string]string `json:"env"`
+	}
+	if json.Unmarshal(data, &settings) != nil {
+		return nil
+	}
+	return claudeCandidates(settingsPath, settings.Env)
+}
+
+func claudeCandidates(source string, env map[string]string) []ImportCandidate {
+	key := env["ANTHROPIC_AUTH_TOKEN"]
+	if key == "" {
+		key = env["ANTHROPIC_API_KEY"]
+	}
+	baseUrl := env["ANTHROPIC_BASE_URL"]
+	if key == "" || isOfficialEndpoint("claude", baseUrl) {
+		return nil
+	}
+	return []ImportCandidate{{
+		Tool:    "claude",
+		Source:  source,
+		BaseUrl: baseUrl,
+		ModelId: env["ANTHROPIC_MODEL"],
+		Roles: ModelRoles{
+			Fast:      env["ANTHROPIC_DEFAULT_HAIKU_MODEL"],
+			Reasoning: env["ANTHROPIC_DEFAULT_OPUS_MODEL"],
+		},
+		ApiKey:  key,
+		Current: true,
+	}}
+}
+
+// scanCodexConfig reads every [model_providers.<id>] table of ~/.codex/config.toml.
+// The selected provider gets the key from auth.json; others get theirs from env_key.
+func (a *App) scanCodexConfig() []ImportCandidate {
+	dir, authPath := a.getCodexConfigPaths()
+	configPath := filepath.Join(dir, "config.toml")
+	doc, err := readTomlFile(configPath)
+	if err != nil {
+		return nil
+	}
+	var authKey string
+	if auth, err := readOrderedJSONFile(authPath); err == nil {
+		auth.get("OPENAI_API_KEY", &authKey)
+	}
+	selected, _ := doc.get(nil, "model_provider")
+	model, _ := doc.get(nil, "model")
+	reviewModel, _ := doc.get(nil, "review_model")
+	tables := make(map[string]map[string]string)
+	var ids []string
+	for _, leaf := range doc.leaves() {
+		if len(leaf.path) != 3 || leaf.path[0] != "model_providers" {
+			continue
+		}
+		id := leaf.path[1]
+		if tables[id] == nil {
+			tables[id] = make(map[string]string)
+			ids = append(ids, id)
+		}
+		if s, ok := decodeTomlValue(leaf.value).(string); ok {
+			tables[id][leaf.path[2]] = s
+		}
+	}
+	var candidates []ImportCandidate
+	for _, id := range ids {
+		t := tables[id]
+		current := selected == id
+		key := os.Getenv(t["env_key"])
+		if current && authKey != "" {
+			key = authKey
+		}
+		if key == "" || isOfficialEndpoint("codex", t["base_url"]) {
+			continue
+		}
+		c := ImportCandidate{
+			Tool:     "codex",
+			Source:   configPath,
+			Provider: t["name"],
+			BaseUrl:  t["base_url"],
+			WireApi:  t["wire_api"],
+			ApiKey:   key,
+			Current:  current,
+		}
+		if current {
+			c.ModelId, _ = model.(string)
+			c.Roles.Reasoning, _ = reviewModel.(string)
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates
+}
+
+// scanOpencodeConfig reads the providers of ~/.config/opencode/opencode.json.
+func (a *App) scanOpencodeConfig() []ImportCandidate {
+	_, configPath := a.getOpencodeConfigPaths()
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil
+	}
+	var cfg struct {
+		Model      string `json:"model"`
+		SmallModel string `json:"small_model"`
+		Provider   map[string]struct {
+			Name    string `json:"name"`
+			Options struct {
+				BaseURL string `json:"baseURL"`
+				ApiKey  string `json:"apiKey"`
+			} `json:"options"`
+			Models map[string]json.RawMessage `json:"models"`
+		} `json:"provider"`
+	}
+	if json.Unmarshal(data, &cfg) != nil {
+		return nil
+	}
+	ids := make([]string, 0, len(cfg.Provider))
+	for id := range cfg.Provider {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var candidates []ImportCandidate
+	for _, id := range ids {
+		p := cfg.Provider[id]
+		if p.Options.ApiKey == "" || p.Options.BaseURL == "" {
+			continue
+		}
+		c := ImportCandidate{
+			Tool:     "opencode",
+			Source:   configPath,
+			Provider: p.Name,
+			BaseUrl:  p.Options.BaseURL,
+			ApiKey:   p.Options.ApiKey,
+			Current:  len(cfg.Provider) == 1 || strings.HasPrefix(cfg.Model, id+"/"),
+		}
+		if strings.HasPrefix(cfg.Model, id+"/") {
+			c.ModelId = strings.TrimPrefix(cfg.Model, id+"/")
+		} else if len(p.Models) == 1 {
+			for m := range p.Models {
+				c.ModelId = m
+			}
+		}
+		if strings.HasPrefix(cfg.SmallModel, id+"/") {
+			c.Roles.Fast = strings.TrimPrefix(cfg.SmallModel, id+"/")
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates
+}
+
+// scanEnvironment reads exported ANTHROPIC_* and OPENAI_* variables.
+func scanEnvironment() []ImportCandidate {
+	env := make(map[string]string)
+	for _, k := range []string{"ANTHROPIC_AUTH_TOKEN", "ANTHROPIC_API_KEY", "ANTHROPIC_BASE_URL", "ANTHROPIC_MODEL",
+		"ANTHROPIC_DEFAULT_HAIKU_MODEL", "ANTHROPIC_DEFAULT_OPUS_MODEL"} {
+		env[k] = os.Getenv(k)
+	}
+	candidates := claudeCandidates("environment", env)
+	if key, baseUrl := os.Getenv("OPENAI_API_KEY"), os.Getenv("OPENAI_BASE_URL"); key != "" && !isOfficialEndpoint("codex", baseUrl) {
+		candidates = append(candidates, ImportCandidate{
+			Tool:    "codex",
+			Source:  "environment",
+			BaseUrl: baseUrl,
+			ModelId: os.Getenv("OPENAI_MODEL"),
+			ApiKey:  key,
+			Current: true,
+		})
+	}
+	return candidates
+}
+
+// findImportTarget returns the provider of toolCfg a candidate maps onto, if any:
+// the catalog provider by name, or a custom provider with the same endpoint.
+func findImportTarget(toolCfg *ToolConfig, c ImportCandidate) *ModelConfig {
+	if c.Known {
+		return getProviderModel(toolCfg, c.Provider)
+	}
+	for i := range toolCfg.Models {
+		m := &toolCfg.Models[i]
+		if m.IsCustom && m.ModelUrl != "" && normalizeBaseUrl(m.ModelUrl) == normalizeBaseUrl(c.BaseUrl) {
+			return m
+		}
+	}
+	return nil
+}
+
+// scanExistingConfigs collects candidates from every tool's files and then the
+// environment, maps them onto catalog or custom providers of config and marks
+// how they compare with what config already holds.
+func (a *App) scanExistingConfigs(config *AppConfig) []ImportCandidate {
+	var found []ImportCandidate
+	found = append(found, a.scanClaudeSettings()...)
+	found = append(found, a.scanCodexConfig()...)
+	found = append(found, a.scanOpencodeConfig()...)
+	fromFiles := len(found)
+	found = append(found, scanEnvironment()...)
+
+	var candidates []ImportCandidate
+	hasCurrent := make(map[string]bool)
+	seen := make(map[string]bool)
+	for i, c := range found {
+		toolCfg := getToolConfig(config, c.Tool)
+		if p := matchCatalogProvider(c.Tool, c.BaseUrl); p != nil {
+			c.Provider = p.Name
+			c.Known = true
+		} else if !toolTakesCustom(c.Tool) {
+			continue
+		} else if m := findImportTarget(toolCfg, c); m != nil {
+			c.Provider = m.ModelName
+		} else if prev := findProposedCustom(candidates, c); prev != nil {
+			// The same endpoint found twice gets the same name, and only the first counts
+			c.Provider = prev.Provider
+		} else {
+			c.Provider = uniqueCustomName(config, c.Tool, customNameForUrl(c.Provider, c.BaseUrl), candidates)
+		}
+		// Files come first; the environment only adds what they did not have
+		id := c.Tool + "|" + strings.ToLower(c.Provider)
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if i >= fromFiles && hasCurrent[c.Tool] {
+			c.Current = false
+		}
+		if c.Current {
+			hasCurrent[c.Tool] = true
+		}
+		c.Status = "new"
+		if m := findImportTarget(toolCfg, c); m != nil && m.ApiKey != "" {
+			c.Status = "differs"
+			if m.ApiKey == c.ApiKey {
+				c.Status = "same"
+			}
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates
+}
+
+// findProposedCustom returns the new custom provider already proposed for the tool
+// and endpoint of c, if any.
+func findProposedCustom(proposed []ImportCandidate, c ImportCandidate) *ImportCandidate {
+	for i := range proposed {
+		p := &proposed[i]
+		if p.Tool == c.Tool && !p.Known && normalizeBaseUrl(p.BaseUrl) == normalizeBaseUrl(c.BaseUrl) {
+			return p
+		}
+	}
+	return nil
+}
+
+// uniqueCustomName makes name usable for a new custom provider of tool, taking
+// names already proposed to other candidates into account.
+func uniqueCustomName(config *AppConfig, tool, name string, proposed []ImportCandidate) string {
+	taken := func(n string) bool {
+		if validateCustomName(config, []string{tool}, n, "") != nil {
+			return true
+		}
+		for _, c := range proposed {
+			if c.Tool == tool && strings.EqualFold(c.Provider, n) {
+				return true
+			}
+		}
+		return false
+	}
+	candidate := name
+	for i := 2; taken(candidate); i++ {
+		candidate = fmt.Sprintf("%s %d", name, i)
+	}
+	return candidate
+}
+
+// applyImportCandidate writes a candidate into config. Custom providers fill an
+// unused custom slot before a new one is added.
+func applyImportCandidate(config *AppConfig, c ImportCandidate) error {
+	toolCfg := getToolConfig(config, c.Tool)
+	if toolCfg == nil {
+		return fmt.Errorf("unknown tool %s", c.Tool)
+	}
+	m := findImportTarget(toolCfg, c)
+	if m == nil && c.Known {
+		return fmt.Errorf("%s does not offer %s", c.Tool, c.Provider)
+	}
+	if m == nil {
+		if !toolTakesCustom(c.Tool) {
+			return fmt.Errorf("%s does not take custom providers", c.Tool)
+		}
+		for i := range toolCfg.Models {
+			slot := &toolCfg.Models[i]
+			if slot.IsCustom && !slot.Shared && slot.ModelUrl == "" && slot.ApiKey == "" && len(slot.ApiKeys) == 0 {
+				m = slot
+				break
+			}
+		}
+		except := ""
+		if m != nil {
+			except = m.ModelName
+		}
+		if err := validateCustomName(config, []string{c.Tool}, c.Provider, except); err != nil {
+			return err
+		}
+		if m == nil {
+			toolCfg.Models = append(toolCfg.Models, ModelConfig{IsCustom: true})
+			m = &toolCfg.Models[len(toolCfg.Models)-1]
+		}
+		m.ModelName = c.Provider
+		m.ModelUrl = c.BaseUrl
+		m.WireApi = c.WireApi
+	}
+	m.ApiKey = c.ApiKey
+	if len(m.ApiKeys) > 0 {
+		m.ApiKeys[m.ActiveKey].Key = c.ApiKey
+	}
+	if c.ModelId != "" {
+		m.ModelId = c.ModelId
+	}
+	if c.Roles.Fast != "" {
+		m.Roles.Fast = c.Roles.Fast
+	}
+	if c.Roles.Reasoning != "" {
+		m.Roles.Reasoning = c.Roles.Reasoning
+	}
+	if c.Current {
+		toolCfg.CurrentModel = m.ModelName
+	}
+	return nil
+}
+
+// importExistingOnFirstRun takes over the providers already configured in each tool,
+// so the first launch writes them back instead of replacing them.
+func (a *App) importExistingOnFirstRun(config *AppConfig) {
+	for _, c := range a.scanExistingConfigs(config) {
+		if err := applyImportCandidate(config, c); err != nil {
+			a.log(fmt.Sprintf("Import: skipped %s for %s: %v", c.Provider, c.Tool, err))
+			continue
+		}
+		a.log(fmt.Sprintf("Import: took over %s for %s from %s", c.Provider, c.Tool, c.Source))
+	}
+}
+
+// ImportExistingConfigs scans the tools' own config files and the environment for
+// providers set up outside AICoder and proposes them as entries. Nothing is changed
+// until the chosen candidates are passed to ApplyConfigImports.
+func (a *App) ImportExistingConfigs() ([]ImportCandidate, error) {
+	config, err := a.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return a.scanExistingConfigs(&config), nil
+}
+
+// ApplyConfigImports adds the chosen import candidates to the config.
+func (a *App) ApplyConfigImports(candidates []ImportCandidate) (AppConfig, error) {
+	return a.modifyConfig(func(config *AppConfig) error {
+		for _, c := range candidates {
+			if err := applyImportCandidate(config, c); err != nil {
+				return fmt.Errorf("%s for %s: %v", c.Provider, c.Tool, err)
+			}
+		}
+		return nil
+	})
+}
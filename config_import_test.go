@@ -0,0 +1,192 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newImportTestApp returns an App whose tools were set up outside AICoder: Claude
+// reaches GLM, Codex a relay and another provider, opencode a local server.
+func newImportTestApp(t *testing.T) *App {
+	t.Helper()
+	a := newSecretTestApp(t, newFakeSecretBackend("fake"))
+	home := a.testHomeDir
+	t.Setenv("HOME", home)
+	for _, k := range []string{"ANTHROPIC_AUTH_TOKEN", "ANTHROPIC_API_KEY", "ANTHROPIC_BASE_URL", "OPENAI_API_KEY", "OPENAI_BASE_URL", "OPENAI_MODEL"} {
+		t.Setenv(k, "")
+	}
+	config := secretTestConfig()
+	config.Codex = ToolConfig{Models: []ModelConfig{{ModelName: "Relay", IsCustom: true, ModelUrl: "https://old-relay.example/v1", ApiKey: "sk-old-relay"}}}
+	path, _ := a.getConfigPath()
+	if err := a.saveToPath(path, config); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		".claude/settings.json": `{"env":{"ANTHROPIC_AUTH_TOKEN":"sk-glm-2","ANTHROPIC_BASE_URL":"https://open.bigmodel.cn/api/anthropic/","ANTHROPIC_MODEL":"glm-4.6","ANTHROPIC_DEFAULT_HAIKU_MODEL":"glm-4.5-air"}}`,
+		".codex/config.toml": `model_provider = "relay"
+model = "gpt-5"
+review_model = "gpt-5-high"
+
+[model_providers.relay]
+name = "Relay"
+base_url = "https://relay.example/v1"
+wire_api = "responses"
+env_key = "RELAY_KEY"
+
+[model_providers.other]
+base_url = "https://api.other.example/v1"
+env_key = "OTHER_KEY"
+
+[model_providers.nokey]
+base_url = "https://nokey.example/v1"
+env_key = "NO_SUCH_KEY"
+
+[model_providers.openai]
+base_url = "https://api.openai.com/v1"
+env_key = "OTHER_KEY"
+`,
+		".codex/auth.json":               `{"OPENAI_API_KEY":"sk-relay"}`,
+		".config/opencode/opencode.json": `{"model":"local/qwen3","provider":{"local":{"options":{"baseURL":"http://127.0.0.1:8080/v1","apiKey":"sk-local"},"models":{"qwen3":{}}}}}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(home, name)
+		os.MkdirAll(filepath.Dir(path), 0700)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("OTHER_KEY", "sk-other")
+	return a
+}
+
+// importList lists candidates as tool/provider=status, with * for the current one.
+func importList(candidates []ImportCandidate) string {
+	var out []string
+	for _, c := range candidates {
+		s := c.Tool + "/" + c.Provider + "=" + c.Status
+		if c.Current {
+			s += "*"
+		}
+		out = append(out, s)
+	}
+	return strings.Join(out, " ")
+}
+
+func TestImportExistingConfigs(t *testing.T) {
+	a := newImportTestApp(t)
+	// The environment repeats the Codex relay and adds nothing new
+	t.Setenv("OPENAI_API_KEY", "sk-relay-env")
+	t.Setenv("OPENAI_BASE_URL", "https://relay.example/v1")
+	candidates, err := a.ImportExistingConfigs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// GLM already has another key; the Codex relay does not take the name of the
+	// custom provider at another URL
+	want := "claude/GLM=differs* codex/Relay 2=new* codex/Other=new opencode/Local=new*"
+	if got := importList(candidates); got != want {
+		t.Fatalf("candidates = %s, want %s", got, want)
+	}
+	glm, relay := candidates[0], candidates[1]
+	if !glm.Known || glm.ModelId != "glm-4.6" || glm.Roles.Fast != "glm-4.5-air" || glm.ApiKey != "sk-glm-2" {
+		t.Fatalf("GLM = %+v", glm)
+	}
+	if relay.Known || relay.ApiKey != "sk-relay" || relay.ModelId != "gpt-5" || relay.Roles.Reasoning != "gpt-5-high" || relay.WireApi != "responses" {
+		t.Fatalf("relay = %+v", relay)
+	}
+	if candidates[2].ApiKey != "sk-other" || candidates[2].Current || candidates[3].ModelId != "qwen3" {
+		t.Fatalf("candidates = %+v", candidates[2:])
+	}
+
+	config, err := a.ApplyConfigImports(candidates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := getProviderModel(&config.Claude, "GLM"); m.ApiKey != "sk-glm-2" || m.ModelId != "glm-4.6" || config.Claude.CurrentModel != "GLM" {
+		t.Fatalf("claude GLM = %+v, current %q", m, config.Claude.CurrentModel)
+	}
+	if m := getProviderModel(&config.Codex, "Relay"); m.ModelUrl != "https://old-relay.example/v1" || m.ApiKey != "sk-old-relay" {
+		t.Fatalf("existing Relay changed: %+v", m)
+	}
+	m := getProviderModel(&config.Codex, "Relay 2")
+	if m == nil || !m.IsCustom || m.ModelUrl != "https://relay.example/v1" || m.ApiKey != "sk-relay" || config.Codex.CurrentModel != "Relay 2" {
+		t.Fatalf("codex Relay 2 = %+v, current %q", m, config.Codex.CurrentModel)
+	}
+	if m := getProviderModel(&config.Opencode, "Local"); m == nil || m.ApiKey != "sk-local" || config.Opencode.CurrentModel != "Local" {
+		t.Fatalf("opencode Local = %+v", m)
+	}
+
+	// Once applied, the same files propose nothing new
+	candidates, _ = a.ImportExistingConfigs()
+	if got := importList(candidates); !strings.HasPrefix(got, "claude/GLM=same* codex/Relay 2=same* codex/Other=same") {
+		t.Fatalf("candidates after applying = %s", got)
+	}
+}
+
+func TestImportFromEnvironment(t *testing.T) {
+	a := newImportTestApp(t)
+	os.Remove(filepath.Join(a.testHomeDir, ".claude", "settings.json"))
+	t.Setenv("ANTHROPIC_API_KEY", "sk-kimi")
+	t.Setenv("ANTHROPIC_BASE_URL", "https://api.kimi.com/coding")
+	candidates, err := a.ImportExistingConfigs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := candidates[0]; c.Tool != "codex" || c.Source == "environment" {
+		t.Fatalf("first candidate = %+v, files come first", c)
+	}
+	var kimi *ImportCandidate
+	for i := range candidates {
+		if candidates[i].Tool == "claude" {
+			kimi = &candidates[i]
+		}
+	}
+	if kimi == nil || kimi.Provider != "Kimi" || kimi.Source != "environment" || kimi.Status != "new" || !kimi.Current {
+		t.Fatalf("claude candidate = %+v", kimi)
+	}
+
+	// The vendor's own API is what Original mode is for
+	t.Setenv("ANTHROPIC_BASE_URL", "https://api.anthropic.com")
+	if candidates := scanEnvironment(); len(candidates) != 0 {
+		t.Fatalf("official endpoint proposed: %+v", candidates)
+	}
+}
+
+func TestApplyImportCandidateErrors(t *testing.T) {
+	config := secretTestConfig()
+	for _, c := range []ImportCandidate{
+		{Tool: "nosuchtool", Provider: "X"},
+		{Tool: "claude", Provider: "NoSuchProvider", Known: true},
+		{Tool: "claude", Provider: "Original", BaseUrl: "https://x.example"},
+	} {
+		if err := applyImportCandidate(&config, c); err == nil {
+			t.Errorf("%+v applied", c)
+		}
+	}
+}
+
+func TestMatchCatalogProvider(t *testing.T) {
+	for _, c := range []struct{ tool, url, want string }{
+		{"claude", "https://open.bigmodel.cn/api/anthropic/", "GLM"},
+		{"codex", "HTTPS://api.kimi.com/coding/v1/chat/completions", "Kimi"},
+		{"codex", "https://api.kimi.com/other", "Kimi"},
+		{"codex", "https://unknown.example/v1", ""},
+	} {
+		got := ""
+		if p := matchCatalogProvider(c.tool, c.url); p != nil {
+			got = p.Name
+		}
+		if got != c.want {
+			t.Errorf("%s %s matched %q, want %q", c.tool, c.url, got, c.want)
+		}
+	}
+	if name := customNameForUrl("", "http://localhost:11434"); name != "Local" {
+		t.Errorf("local server named %q", name)
+	}
+	if name := customNameForUrl("", "https://api.relay.example/v1"); name != "Relay" {
+		t.Errorf("relay named %q", name)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// configMigration upgrades configs written by older versions. Migrations run in
+// order and each exactly once: schema_version in the file counts those applied.
+// Append new migrations at the end; never reorder or remove one.
+type configMigration struct {
+	name string
+	run  func(config *AppConfig, catalog *ProviderCatalog)
+}
+
+var configMigrations = []configMigration{
+	{"reset Qoder to its fixed provider list", migrateQoderProviders},
+	{"record the catalog endpoints of built-in providers", migrateCatalogUrls},
+}
+
+// latestSchemaVersion is the schema_version of configs written by this version.
+func latestSchemaVersion() int {
+	return len(configMigrations)
+}
+
+// migrateConfig runs the migrations config has not had yet and reports whether any ran.
+// A config from a newer version is left alone.
+func (a *App) migrateConfig(config *AppConfig, catalog *ProviderCatalog) bool {
+	if config.SchemaVersion > latestSchemaVersion() {
+		a.log(fmt.Sprintf("Config schema version %d is newer than this version supports (%d)", config.SchemaVersion, latestSchemaVersion()))
+		return false
+	}
+	if config.SchemaVersion == latestSchemaVersion() {
+		return false
+	}
+	for i := config.SchemaVersion; i < latestSchemaVersion(); i++ {
+		a.log(fmt.Sprintf("Config migration %d: %s", i+1, configMigrations[i].name))
+		configMigrations[i].run(config, catalog)
+	}
+	config.SchemaVersion = latestSchemaVersion()
+	return true
+}
+
+// migrateQoderProviders drops the providers Qoder used to list. Qoder only has
+// Original and its own service; an existing Qoder key is kept.
+func migrateQoderProviders(config *AppConfig, catalog *ProviderCatalog) {
+	var existingQoderKey string
+	for _, m := range config.Qoder.Models {
+		if m.ModelName == "Qoder" {
+			existingQoderKey = m.ApiKey
+			break
+		}
+	}
+	config.Qoder.Models = catalog.defaultModels("qoder")
+	if existingQoderKey != "" {
+		for i := range config.Qoder.Models {
+			if config.Qoder.Models[i].ModelName == "Qoder" {
+				config.Qoder.Models[i].ApiKey = existingQoderKey
+				break
+			}
+		}
+	}
+}
+
+// legacyForcedUrls are the endpoints older versions reset on every load, by tool
+// and provider name. Providers not listed here kept whatever URL they had.
+var legacyForcedUrls = map[string]map[string]string{
+	"claude": {
+		"AiCodeMirror": "https://api.aicodemirror.com/api/claudecode",
+		"Noin.AI":      "https://ai.ourines.com/api",
+		"AIgoCode":     "https://api.aigocode.com/api",
+		"CodeRelay":    "https://api.code-relay.com/",
+		"ChatFire":     "https://api.chatfire.cn",
+		"GACCode":      "https://gaccode.com/claudecode",
+		"DeepSeek":     "https://api.deepseek.com/anthropic",
+		"Kimi":         "https://api.kimi.com/coding",
+		"Doubao":       "https://ark.cn-beijing.volces.com/api/coding",
+		"GLM":          "https://open.bigmodel.cn/api/anthropic",
+		"MiniMax":      "https://api.minimaxi.com/anthropic",
+		"XiaoMi":       "https://api.xiaomimimo.com/anthropic",
+		"摩尔线程":         "https://coding-plan-endpoint.kuaecloud.net",
+		"快手":           "https://wanqing.streamlakeapi.com/api/gateway/coding/kat-coder-pro-v1/claude-code-proxy",
+	},
+	"gemini": {
+		"AiCodeMirror": "https://api.aicodemirror.com/api/gemini",
+		"ChatFire":     "https://api.chatfire.cn/v1beta/models/gemini-2.5-pro:generateContent",
+	},
+	"codex": {
+		"AiCodeMirror": "https://api.aicodemirror.com/api/codex/backend-api/codex",
+		"CodeRelay":    "https://api.code-relay.com/v1",
+		"ChatFire":     "https://api.chatfire.cn/v1",
+		"DeepSeek":     "https://api.deepseek.com/v1",
+		"GLM":          "https://open.bigmodel.cn/api/coding/paas/v4",
+		"Doubao":       "https://ark.cn-beijing.volces.com/api/coding/v3",
+		"Kimi":         "https://api.kimi.com/coding/v1",
+		"MiniMax":      "https://api.minimaxi.com/v1",
+		"XiaoMi":       "https://api.xiaomimimo.com/v1",
+		"摩尔线程":         "https://coding-plan-endpoint.kuaecloud.net/v1",
+		"快手":           "https://wanqing.streamlakeapi.com/api/gateway/coding/v1",
+	},
+	"opencode": {
+		"DeepSeek": "https://api.deepseek.com/v1",
+		"ChatFire": "https://api.chatfire.cn/v1",
+		"GLM":      "https://open.bigmodel.cn/api/coding/paas/v4",
+		"Doubao":   "https://ark.cn-beijing.volces.com/api/coding/v3",
+		"Kimi":     "https://api.kimi.com/coding/v1",
+		"MiniMax":  "https://api.minimaxi.com/v1",
+		"XiaoMi":   "https://api.xiaomimimo.com/v1",
+		"摩尔线程":     "https://coding-plan-endpoint.kuaecloud.net/v1",
+		"快手":       "https://wanqing.streamlakeapi.com/api/gateway/coding/v1",
+	},
+	"codebuddy": {
+		"DeepSeek": "https://api.deepseek.com/v1",
+		"GLM":      "https://open.bigmodel.cn/api/coding/paas/v4",
+		"Doubao":   "https://ark.cn-beijing.volces.com/api/coding/v3",
+		"Kimi":     "https://api.kimi.com/coding/v1",
+		"MiniMax":  "https://api.minimaxi.com/v1",
+		"XiaoMi":   "https://api.xiaomimimo.com/v1",
+		"摩尔线程":     "https://coding-plan-endpoint.kuaecloud.net/v1",
+		"快手":       "https://wanqing.streamlakeapi.com/api/gateway/coding/v1",
+	},
+	"iflow": {
+		"DeepSeek": "https://api.deepseek.com/v1",
+		"GLM":      "https://open.bigmodel.cn/api/coding/paas/v4",
+		"Doubao":   "https://ark.cn-beijing.volces.com/api/coding/v3",
+		"Kimi":     "https://api.kimi.com/coding/v1",
+		"MiniMax":  "https://api.minimaxi.com/v1",
+		"XiaoMi":   "https://api.xiaomimimo.com/v1",
+		"摩尔线程":     "https://coding-plan-endpoint.kuaecloud.net/v1",
+		"快手":       "https://wanqing.streamlakeapi.com/api/gateway/coding/v1",
+	},
+	"kilo": {
+		"ChatFire": "https://api.chatfire.cn/v1",
+		"DeepSeek": "https://api.deepseek.com/v1",
+		"GLM":      "https://open.bigmodel.cn/api/coding/paas/v4",
+		"Doubao":   "https://ark.cn-beijing.volces.com/api/coding/v3",
+		"Kimi":     "https://api.kimi.com/coding/v1",
+		"MiniMax":  "https://api.minimaxi.com/v1",
+		"XiaoMi":   "https://api.xiaomimimo.com/v1",
+		"摩尔线程":     "https://coding-plan-endpoint.kuaecloud.net/v1",
+		"快手":       "https://wanqing.streamlakeapi.com/api/gateway/coding/v1",
+	},
+	"kode": {
+		"ChatFire": "https://api.chatfire.cn/v1",
+		"DeepSeek": "https://api.deepseek.com/v1",
+		"GLM":      "https://open.bigmodel.cn/api/coding/paas/v4",
+		"Doubao":   "https://ark.cn-beijing.volces.com/api/coding/v3",
+		"Kimi":     "https://api.kimi.com/coding/v1",
+		"MiniMax":  "https://api.minimaxi.com/v1",
+		"XiaoMi":   "https://api.xiaomimimo.com/v1",
+		"摩尔线程":     "https://coding-plan-endpoint.kuaecloud.net/v1",
+		"快手":       "https://wanqing.streamlakeapi.com/api/gateway/coding/v1",
+	},
+}
+
+// migrateCatalogUrls marks the endpoint of a built-in provider as set by the
+// catalog when it still is the catalog's, or one older versions forced on every
+// load. Any other URL is the user's and stays out of catalog updates.
+func migrateCatalogUrls(config *AppConfig, catalog *ProviderCatalog) {
+	for _, tool := range toolNames {
+		toolCfg := getToolConfig(config, tool)
+		for i := range toolCfg.Models {
+			m := &toolCfg.Models[i]
+			if m.IsCustom || m.ModelUrl == "" || strings.EqualFold(m.ModelName, "Original") {
+				continue
+			}
+			p := catalog.lookup(m.ModelName)
+			if p == nil {
+				continue
+			}
+			if m.ModelUrl == p.Tools[tool].BaseUrl || m.ModelUrl == legacyForcedUrl(tool, m.ModelName) {
+				m.CatalogUrl = m.ModelUrl
+			}
+		}
+	}
+}
+
+// legacyForcedUrl returns the endpoint older versions forced on a provider, matching
+// names without regard to case as they did.
+func legacyForcedUrl(tool, name string) string {
+	for n, u := range legacyForcedUrls[tool] {
+		if strings.EqualFold(n, name) {
+			return u
+		}
+	}
+	return ""
+}
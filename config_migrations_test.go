@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// configFixtures are configs in the shapes older versions wrote. None has a
+// schema_version: every release before the migrations wrote version 0.
+var configFixtures = []string{
+	"config_v0",                        // Keys in plain text, old Qoder providers, an edited endpoint
+	"config_v0_before_kilo",            // Written before Kilo existed: no show_kilo, fewer tools
+	"config_v0_duplicate_aicodemirror", // AICodeMirror and AiCodeMirror side by side
+	"config_v0_glm_alias",              // GLM listed under its old model name
+}
+
+func readConfigFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// Each fixture read the way the app reads it must give its golden file. Run
+// with -update after an intended change.
+func TestConfigMigrationsGolden(t *testing.T) {
+	for _, name := range configFixtures {
+		t.Run(name, func(t *testing.T) {
+			a, _ := newFixtureApp(t, name)
+			config, err := a.readConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.MarshalIndent(config, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+			golden := filepath.Join("testdata", name+".golden.json")
+			if *updateGolden {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("%s read as\n%s\nnot as in %s", name, got, golden)
+			}
+		})
+	}
+}
+
+func TestMigrateConfigLeavesCurrentAndNewerAlone(t *testing.T) {
+	catalog := mustParseCatalog(embeddedCatalog)
+	for _, version := range []int{latestSchemaVersion(), latestSchemaVersion() + 1} {
+		config := AppConfig{SchemaVersion: version, Qoder: ToolConfig{Models: []ModelConfig{{ModelName: "GLM", ApiKey: "k"}}}}
+		if NewApp().migrateConfig(&config, catalog) {
+			t.Errorf("schema %d migrated", version)
+		}
+		if config.SchemaVersion != version || len(config.Qoder.Models) != 1 {
+			t.Errorf("schema %d changed: %+v", version, config)
+		}
+	}
+}
+
+// newFixtureApp returns an App with a fixture as its config file.
+func newFixtureApp(t *testing.T, name string) (*App, string) {
+	t.Helper()
+	a := newSecretTestApp(t, newFakeSecretBackend("fake"))
+	t.Setenv("HOME", a.testHomeDir)
+	path, _ := a.getConfigPath()
+	if err := os.WriteFile(path, readConfigFixture(t, name), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return a, path
+}
+
+// The whole read pipeline on an old config: migrations, defaults, catalog and
+// secrets, saved once and recorded in the history.
+func TestReadConfigMigratesFixtures(t *testing.T) {
+	for _, name := range configFixtures {
+		t.Run(name, func(t *testing.T) {
+			a, path := newFixtureApp(t, name)
+			config, err := a.readConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.SchemaVersion != latestSchemaVersion() {
+				t.Fatalf("schema_version = %d", config.SchemaVersion)
+			}
+			if config.EnvCheckInterval < 2 || config.EnvCheckInterval > 30 {
+				t.Fatalf("env_check_interval = %d", config.EnvCheckInterval)
+			}
+			data, _ := os.ReadFile(path)
+			if strings.Contains(string(data), `"sk-`) {
+				t.Fatal("API keys left in the config file")
+			}
+			history, err := a.ListConfigHistory()
+			if err != nil || len(history) != 1 || history[0].Source != configSourceMigration {
+				t.Fatalf("history = %+v, %v", history, err)
+			}
+			// Reading again changes nothing
+			if _, err := a.readConfig(); err != nil {
+				t.Fatal(err)
+			}
+			if again, _ := os.ReadFile(path); !bytes.Equal(again, data) {
+				t.Fatal("second read rewrote the config file")
+			}
+		})
+	}
+}
+
+func TestReadConfigV0KeepsKeys(t *testing.T) {
+	a, _ := newFixtureApp(t, "config_v0")
+	config, err := a.readConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct{ tool, provider, key string }{
+		{"claude", "GLM", "sk-v0-glm"},
+		{"claude", "My proxy", "sk-v0-proxy"},
+		{"codex", "Kimi", "sk-v0-kimi"},
+		{"qoder", "Qoder", "sk-v0-qoder"},
+	} {
+		m := getProviderModel(getToolConfig(&config, c.tool), c.provider)
+		if m == nil || m.ApiKey != c.key {
+			t.Errorf("%s/%s = %+v", c.tool, c.provider, m)
+		}
+	}
+	if config.Codex.CurrentModel != "Kimi" {
+		t.Errorf("codex current model casing = %q", config.Codex.CurrentModel)
+	}
+	if config.Claude.Models[0].ModelName != "Original" {
+		t.Errorf("Original is not first: %s", config.Claude.Models[0].ModelName)
+	}
+	if config.DefaultProxyPassword != "pw-v0" || config.ShowGemini {
+		t.Errorf("proxy password %q, show_gemini %v", config.DefaultProxyPassword, config.ShowGemini)
+	}
+}
+
+// Older versions reset the endpoints of the providers they listed on every load,
+// but not Gemini's and Codex' AIgoCode: an edit there is the user's and stays.
+func TestReadConfigV0KeepsEditedEndpoints(t *testing.T) {
+	a, _ := newFixtureApp(t, "config_v0")
+	config, err := a.readConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	codex := getProviderModel(&config.Codex, "AIgoCode")
+	if codex.ModelUrl != "https://eu.aigocode.example/openai" || codex.CatalogUrl != "" {
+		t.Fatalf("edited Codex AIgoCode = %s (catalog %q)", codex.ModelUrl, codex.CatalogUrl)
+	}
+	if m := getProviderModel(&config.Gemini, "AIgoCode"); m.CatalogUrl != m.ModelUrl {
+		t.Fatalf("unedited Gemini AIgoCode not marked: %+v", m)
+	}
+	if m := getProviderModel(&config.Claude, "DeepSeek"); m.CatalogUrl != m.ModelUrl {
+		t.Fatalf("forced Claude DeepSeek not marked: %+v", m)
+	}
+
+	// A catalog update moves the endpoints nobody changed, and only those
+	catalog := mustParseCatalog(embeddedCatalog)
+	for i := range catalog.Providers {
+		p := &catalog.Providers[i]
+		for tool, e := range p.Tools {
+			e.BaseUrl += "/moved"
+			p.Tools[tool] = e
+		}
+	}
+	catalog.applyCatalog(&config)
+	if m := getProviderModel(&config.Codex, "AIgoCode"); m.ModelUrl != "https://eu.aigocode.example/openai" {
+		t.Fatalf("catalog update replaced the user's endpoint with %s", m.ModelUrl)
+	}
+	if m := getProviderModel(&config.Claude, "DeepSeek"); !strings.HasSuffix(m.ModelUrl, "/moved") {
+		t.Fatalf("catalog endpoint not updated: %s", m.ModelUrl)
+	}
+}
+
+func TestReadConfigBeforeKilo(t *testing.T) {
+	a, _ := newFixtureApp(t, "config_v0_before_kilo")
+	config, err := a.readConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.ShowKilo || !config.ShowKode || config.ShowQoder {
+		t.Fatalf("show_kilo %v, show_kode %v, show_qoder %v", config.ShowKilo, config.ShowKode, config.ShowQoder)
+	}
+	if len(config.Kilo.Models) == 0 || config.EnvCheckInterval != 7 {
+		t.Fatalf("kilo providers %d, env_check_interval %d", len(config.Kilo.Models), config.EnvCheckInterval)
+	}
+	// show_kilo written as false is not taken for a missing one
+	a, _ = newFixtureApp(t, "config_v0")
+	if config, _ = a.readConfig(); config.ShowKilo {
+		t.Fatal("show_kilo: false turned on")
+	}
+}
+
+func TestReadConfigMergesOldProviderNames(t *testing.T) {
+	a, _ := newFixtureApp(t, "config_v0_duplicate_aicodemirror")
+	config, err := a.readConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mirrors []ModelConfig
+	for _, m := range config.Claude.Models {
+		if strings.EqualFold(m.ModelName, "AiCodeMirror") {
+			mirrors = append(mirrors, m)
+		}
+	}
+	if len(mirrors) != 1 || mirrors[0].ModelName != "AiCodeMirror" || mirrors[0].ApiKey != "sk-dup-mirror" || mirrors[0].ModelId != "opus" {
+		t.Fatalf("AiCodeMirror entries = %+v", mirrors)
+	}
+	if config.Claude.CurrentModel != "AiCodeMirror" {
+		t.Fatalf("current model = %q", config.Claude.CurrentModel)
+	}
+
+	a, _ = newFixtureApp(t, "config_v0_glm_alias")
+	if config, err = a.readConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if m := getProviderModel(&config.Opencode, "GLM"); m == nil || m.ApiKey != "sk-alias-glm" {
+		t.Fatalf("GLM = %+v", m)
+	}
+	if getProviderModel(&config.Opencode, "glm-4.7") != nil || config.Opencode.CurrentModel != "GLM" {
+		t.Fatalf("alias kept: current %q", config.Opencode.CurrentModel)
+	}
+}
+
+func TestReadConfigImportsLegacyFile(t *testing.T) {
+	a := newSecretTestApp(t, newFakeSecretBackend("fake"))
+	t.Setenv("HOME", a.testHomeDir)
+	legacy := filepath.Join(a.testHomeDir, ".claude_model_config.json")
+	if err := os.WriteFile(legacy, readConfigFixture(t, "claude_model_config"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config, err := a.readConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := getProviderModel(&config.Claude, "GLM"); m == nil || m.ApiKey != "sk-legacy-glm" || m.ModelId != "glm-4.5" {
+		t.Fatalf("legacy GLM = %+v", m)
+	}
+	if config.CurrentProject != "p1" || len(config.Projects) != 1 {
+		t.Fatalf("projects = %+v, current %q", config.Projects, config.CurrentProject)
+	}
+	if config.SchemaVersion != latestSchemaVersion() || len(config.Codex.Models) == 0 {
+		t.Fatalf("legacy config not completed: schema %d, %d codex providers", config.SchemaVersion, len(config.Codex.Models))
+	}
+}
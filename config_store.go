@@ -0,0 +1,409 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// configStoreRecent is how many committed configs the store keeps for Save.
+const configStoreRecent = 32
+
+// errStaleConfig is returned by Save for a copy too old to tell what it changed.
+var errStaleConfig = errors.New("the configuration was changed meanwhile; reload it and try again")
+
+// ConfigStore keeps the parsed configuration in memory. Readers get a copy from Get;
+// writers go through Update, which runs one change at a time and persists it before
+// anyone else sees it, so concurrent saves from the UI, the tray and background work
+// no longer overwrite each other. Listeners hear about every committed change.
+// Each change names its source, e.g. configSourceUI, for the history.
+type ConfigStore struct {
+	mu        sync.Mutex // Serializes loads and updates
+	dataMu    sync.RWMutex
+	config    *AppConfig // nil until first loaded
+	load      func() (AppConfig, error)
+	persist   func(old, next *AppConfig) error
+	normalize func(old, next *AppConfig)                // Applied to every change before it is persisted; may be nil
+	record    func(source string, old, next *AppConfig) // Called after s.mu is released, in commit order; may be nil
+	recordMu  sync.Mutex                                // Held while recording, so records keep the commit order
+
+	revision int64        // Revision of the config last committed; guarded by mu
+	recent   []*AppConfig // The configs last committed, oldest first; guarded by mu
+
+	listenersMu sync.Mutex
+	listeners   map[int]func(AppConfig)
+	nextId      int
+}
+
+// newConfigStore returns a store that reads the config with load and writes it with persist.
+func newConfigStore(load func() (AppConfig, error), persist func(old, next *AppConfig) error) *ConfigStore {
+	return &ConfigStore{load: load, persist: persist, listeners: make(map[int]func(AppConfig))}
+}
+
+// cloneConfig returns a deep copy, so callers cannot change the stored config by accident.
+func cloneConfig(config AppConfig) AppConfig {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return config
+	}
+	var c AppConfig
+	if err := json.Unmarshal(data, &c); err != nil {
+		return config
+	}
+	return c
+}
+
+func (s *ConfigStore) current() *AppConfig {
+	s.dataMu.RLock()
+	defer s.dataMu.RUnlock()
+	return s.config
+}
+
+func (s *ConfigStore) set(config *AppConfig) {
+	s.dataMu.Lock()
+	s.config = config
+	s.dataMu.Unlock()
+}
+
+// commit makes config the current one under the next revision. s.mu must be held.
+func (s *ConfigStore) commit(config *AppConfig) {
+	s.revision++
+	config.Revision = s.revision
+	s.set(config)
+	s.recent = append(s.recent, config)
+	if len(s.recent) > configStoreRecent {
+		s.recent = s.recent[len(s.recent)-configStoreRecent:]
+	}
+}
+
+// committed returns the config committed as revision, if it is still kept. s.mu
+// must be held.
+func (s *ConfigStore) committed(revision int64) *AppConfig {
+	for _, c := range s.recent {
+		if c.Revision == revision {
+			return c
+		}
+	}
+	return nil
+}
+
+// ensureLoaded reads the config unless it is in memory already. s.mu must be held.
+func (s *ConfigStore) ensureLoaded() (*AppConfig, error) {
+	if c := s.current(); c != nil {
+		return c, nil
+	}
+	config, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	s.commit(&config)
+	return &config, nil
+}
+
+// Get returns a copy of the current config, reading it from disk the first time.
+func (s *ConfigStore) Get() (AppConfig, error) {
+	if c := s.current(); c != nil {
+		return cloneConfig(*c), nil
+	}
+	s.mu.Lock()
+	c, err := s.ensureLoaded()
+	s.mu.Unlock()
+	if err != nil {
+		return AppConfig{}, err
+	}
+	return cloneConfig(*c), nil
+}
+
+// Update applies fn to a copy of the config and persists the result. If fn or the
+// write fails, nothing changes. fn must not call Update itself.
+func (s *ConfigStore) Update(source string, fn func(config *AppConfig) error) (AppConfig, error) {
+	return s.update(source, fn, s.persist)
+}
+
+// update is Update with a different way of persisting the change.
+func (s *ConfigStore) update(source string, fn func(config *AppConfig) error, persist func(old, next *AppConfig) error) (AppConfig, error) {
+	s.mu.Lock()
+	old, err := s.ensureLoaded()
+	if err != nil {
+		s.mu.Unlock()
+		return AppConfig{}, err
+	}
+	next := cloneConfig(*old)
+	if err := fn(&next); err != nil {
+		s.mu.Unlock()
+		return cloneConfig(*old), err
+	}
+	if s.normalize != nil {
+		s.normalize(old, &next)
+	}
+	if err := persist(old, &next); err != nil {
+		s.mu.Unlock()
+		return cloneConfig(*old), err
+	}
+	s.commit(&next)
+	s.unlockAndRecord(source, old, &next)
+	s.notify(next)
+	return cloneConfig(next), nil
+}
+
+// unlockAndRecord releases s.mu and records the change just committed. Recording
+// may be slow, e.g. reading a secret, so it runs outside the store lock; recordMu is
+// taken first so that a later change cannot be recorded before this one.
+func (s *ConfigStore) unlockAndRecord(source string, old, next *AppConfig) {
+	if s.record == nil || old == nil {
+		s.mu.Unlock()
+		return
+	}
+	s.recordMu.Lock()
+	defer s.recordMu.Unlock()
+	s.mu.Unlock()
+	s.record(source, old, next)
+}
+
+// Save replaces the config with edited, a copy from Get the caller changed. Changes
+// committed since that copy was taken are kept: only the values the caller changed
+// are applied over them. A copy older than the configs the store keeps is refused.
+func (s *ConfigStore) Save(source string, edited AppConfig) (AppConfig, error) {
+	return s.Update(source, func(config *AppConfig) error {
+		if edited.Revision == config.Revision {
+			*config = edited
+			return nil
+		}
+		base := s.committed(edited.Revision)
+		if base == nil {
+			return errStaleConfig
+		}
+		merged, err := mergeConfigEdits(*config, *base, edited)
+		if err != nil {
+			return err
+		}
+		*config = merged
+		return nil
+	})
+}
+
+// mergeConfigEdits applies what edited changed relative to base onto current. Values
+// edited left as they were in base keep what current has. Lists of providers and
+// projects are merged item by item, matched by name or id.
+func mergeConfigEdits(current, base, edited AppConfig) (AppConfig, error) {
+	var trees [3]interface{}
+	for i, c := range []AppConfig{current, base, edited} {
+		c.Revision = 0
+		data, err := json.Marshal(c)
+		if err != nil {
+			return current, err
+		}
+		if err := json.Unmarshal(data, &trees[i]); err != nil {
+			return current, err
+		}
+	}
+	data, err := json.Marshal(mergeJSONEdits(trees[0], trees[1], trees[2]))
+	if err != nil {
+		return current, err
+	}
+	var merged AppConfig
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return current, err
+	}
+	merged.Revision = current.Revision
+	return merged, nil
+}
+
+// mergeJSONEdits is the three-way merge of mergeConfigEdits on decoded JSON.
+func mergeJSONEdits(current, base, edited interface{}) interface{} {
+	if reflect.DeepEqual(base, edited) {
+		return current
+	}
+	if reflect.DeepEqual(base, current) {
+		return edited
+	}
+	switch e := edited.(type) {
+	case map[string]interface{}:
+		c, ok := current.(map[string]interface{})
+		b, okBase := base.(map[string]interface{})
+		if !ok || !okBase {
+			return edited
+		}
+		merged := make(map[string]interface{}, len(c))
+		for k, v := range c {
+			merged[k] = v
+		}
+		for k, v := range e {
+			if v, keep := mergeJSONItem(c, b, k, v); keep {
+				merged[k] = v
+			} else {
+				delete(merged, k)
+			}
+		}
+		for k := range b {
+			if _, kept := e[k]; !kept {
+				delete(merged, k)
+			}
+		}
+		return merged
+	case []interface{}:
+		c, ok := current.([]interface{})
+		b, okBase := base.([]interface{})
+		ck, bk, ek := jsonListKeys(c), jsonListKeys(b), jsonListKeys(e)
+		if !ok || !okBase || ck == nil || bk == nil || ek == nil {
+			// Not a list of named items: the edited list as a whole
+			return edited
+		}
+		byKey := func(items []interface{}, keys []string) map[string]interface{} {
+			m := make(map[string]interface{}, len(items))
+			for i, v := range items {
+				m[keys[i]] = v
+			}
+			return m
+		}
+		cm, bm, em := byKey(c, ck), byKey(b, bk), byKey(e, ek)
+		merged := []interface{}{}
+		for i, k := range ek {
+			if v, keep := mergeJSONItem(cm, bm, k, e[i]); keep {
+				merged = append(merged, v)
+			}
+		}
+		// Items added meanwhile
+		for i, k := range ck {
+			if _, inBase := bm[k]; !inBase {
+				if _, inEdited := em[k]; !inEdited {
+					merged = append(merged, c[i])
+				}
+			}
+		}
+		return merged
+	}
+	return edited
+}
+
+// mergeJSONItem merges the item k of edited with those of current and base. An item
+// removed meanwhile that the editor did not change is not kept.
+func mergeJSONItem(current, base map[string]interface{}, k string, edited interface{}) (interface{}, bool) {
+	b, inBase := base[k]
+	c, inCurrent := current[k]
+	switch {
+	case !inBase:
+		return edited, true
+	case !inCurrent:
+		return edited, !reflect.DeepEqual(b, edited)
+	}
+	return mergeJSONEdits(c, b, edited), true
+}
+
+// jsonListKeys returns the model_name or id of every item of a list, or nil unless
+// every item is an object with its own.
+func jsonListKeys(items []interface{}) []string {
+	keys := make([]string, 0, len(items))
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		key, _ := obj["model_name"].(string)
+		if key == "" {
+			key, _ = obj["id"].(string)
+		}
+		key = strings.ToLower(key)
+		if key == "" || seen[key] {
+			return nil
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Reload drops the config in memory and reads it again, e.g. after the file was
+// edited outside AICoder. Listeners are told about the result.
+func (s *ConfigStore) Reload(source string) (AppConfig, error) {
+	s.mu.Lock()
+	config, err := s.load()
+	if err != nil {
+		s.mu.Unlock()
+		return config, err
+	}
+	old := s.current()
+	s.commit(&config)
+	s.unlockAndRecord(source, old, &config)
+	s.notify(config)
+	return cloneConfig(config), nil
+}
+
+// Subscribe registers fn to be called with every committed config. Listeners run on
+// the updating goroutine after the store is unlocked. It returns a function that
+// removes the listener.
+func (s *ConfigStore) Subscribe(fn func(AppConfig)) func() {
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+	id := s.nextId
+	s.nextId++
+	s.listeners[id] = fn
+	return func() {
+		s.listenersMu.Lock()
+		delete(s.listeners, id)
+		s.listenersMu.Unlock()
+	}
+}
+
+func (s *ConfigStore) notify(config AppConfig) {
+	s.listenersMu.Lock()
+	fns := make([]func(AppConfig), 0, len(s.listeners))
+	for _, fn := range s.listeners {
+		fns = append(fns, fn)
+	}
+	s.listenersMu.Unlock()
+	for _, fn := range fns {
+		fn(cloneConfig(config))
+	}
+}
+
+// Sources of config changes, as shown in the history.
+const (
+	configSourceUI        = "ui"
+	configSourceTray      = "tray"
+	configSourceWatcher   = "watcher"   // The config file was edited outside AICoder
+	configSourceMigration = "migration" // An older config was upgraded on load
+	configSourceApp       = "app"       // Background work, e.g. the environment check
+	configSourceRevert    = "revert"
+)
+
+// configStore returns the App's config store. The tray's OnConfigChanged hook is
+// its first listener, and every change is recorded in the history.
+func (a *App) configStore() *ConfigStore {
+	a.storeOnce.Do(func() {
+		if a.store != nil {
+			return
+		}
+		a.store = newConfigStore(a.readConfig, a.persistConfig)
+		a.store.normalize = func(old, next *AppConfig) {
+			syncSharedCustomProviders(old, next)
+			normalizeConfig(next, currentCatalog())
+		}
+		a.store.record = a.recordConfigHistory
+		a.store.Subscribe(func(config AppConfig) {
+			if OnConfigChanged != nil {
+				OnConfigChanged(config)
+			}
+		})
+	})
+	return a.store
+}
+
+// modifyConfig changes the config on behalf of the UI through the store and tells
+// the UI.
+func (a *App) modifyConfig(fn func(config *AppConfig) error) (AppConfig, error) {
+	return a.modifyConfigFrom(configSourceUI, fn)
+}
+
+// modifyConfigFrom is modifyConfig for a change from another source, e.g. the tray.
+func (a *App) modifyConfigFrom(source string, fn func(config *AppConfig) error) (AppConfig, error) {
+	config, err := a.configStore().Update(source, fn)
+	if err != nil {
+		return config, err
+	}
+	a.emitEvent("config-updated", config)
+	return config, nil
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newMemoryConfigStore returns a store over config that persists nowhere.
+func newMemoryConfigStore(config AppConfig) *ConfigStore {
+	return newConfigStore(func() (AppConfig, error) { return cloneConfig(config), nil },
+		func(old, next *AppConfig) error { return nil })
+}
+
+func storeTestConfig() AppConfig {
+	return AppConfig{
+		Claude: ToolConfig{CurrentModel: "GLM", Models: []ModelConfig{
+			{ModelName: "Original"},
+			{ModelName: "GLM", ApiKey: "sk-glm"},
+			{ModelName: "Kimi"},
+		}},
+		Projects:         []ProjectConfig{{Id: "a", Name: "A"}, {Id: "b", Name: "B"}},
+		EnvCheckInterval: 7,
+	}
+}
+
+func TestConfigStoreSaveKeepsConcurrentChanges(t *testing.T) {
+	s := newMemoryConfigStore(storeTestConfig())
+	ui, _ := s.Get()
+
+	// The tray and background work change the config while the UI holds its copy
+	s.Update(configSourceTray, func(c *AppConfig) error {
+		c.Claude.CurrentModel = "Kimi"
+		getProviderModel(&c.Claude, "Kimi").ApiKey = "sk-kimi"
+		c.Projects = append(c.Projects, ProjectConfig{Id: "c", Name: "C"})
+		return nil
+	})
+
+	ui.EnvCheckInterval = 14
+	getProviderModel(&ui.Claude, "GLM").ModelId = "glm-5"
+	ui.Claude.Models = append(ui.Claude.Models, ModelConfig{ModelName: "Custom", IsCustom: true})
+	ui.Projects = ui.Projects[1:] // Removes A
+	saved, err := s.Save(configSourceUI, ui)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if saved.EnvCheckInterval != 14 || getProviderModel(&saved.Claude, "GLM").ModelId != "glm-5" {
+		t.Fatalf("UI edits lost: %+v", saved)
+	}
+	if getProviderModel(&saved.Claude, "Custom") == nil {
+		t.Fatal("provider added by the UI lost")
+	}
+	if saved.Claude.CurrentModel != "Kimi" || getProviderModel(&saved.Claude, "Kimi").ApiKey != "sk-kimi" {
+		t.Fatalf("tray change undone: current %q", saved.Claude.CurrentModel)
+	}
+	var ids []string
+	for _, p := range saved.Projects {
+		ids = append(ids, p.Id)
+	}
+	if strings.Join(ids, ",") != "b,c" {
+		t.Fatalf("projects = %v, want b,c", ids)
+	}
+	if getProviderModel(&saved.Claude, "GLM").ApiKey != "sk-glm" {
+		t.Fatal("untouched value changed")
+	}
+}
+
+func TestConfigStoreSaveEditedWins(t *testing.T) {
+	s := newMemoryConfigStore(storeTestConfig())
+	ui, _ := s.Get()
+	s.Update(configSourceTray, func(c *AppConfig) error {
+		c.Claude.CurrentModel = "Kimi"
+		return nil
+	})
+	// Both changed the same value: the save is the later change
+	ui.Claude.CurrentModel = "Original"
+	saved, err := s.Save(configSourceUI, ui)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Claude.CurrentModel != "Original" {
+		t.Fatalf("current model = %q", saved.Claude.CurrentModel)
+	}
+}
+
+func TestConfigStoreSaveRemovedMeanwhile(t *testing.T) {
+	s := newMemoryConfigStore(storeTestConfig())
+	ui, _ := s.Get()
+	s.Update(configSourceTray, func(c *AppConfig) error {
+		c.Projects = c.Projects[:1] // Removes B
+		return nil
+	})
+	ui.EnvCheckInterval = 3
+	saved, err := s.Save(configSourceUI, ui)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Projects) != 1 || saved.Projects[0].Id != "a" {
+		t.Fatalf("project removed meanwhile came back: %+v", saved.Projects)
+	}
+}
+
+func TestConfigStoreSaveRefusesUnknownRevision(t *testing.T) {
+	s := newMemoryConfigStore(storeTestConfig())
+	old, _ := s.Get()
+	for i := 0; i <= configStoreRecent; i++ {
+		s.Update(configSourceApp, func(c *AppConfig) error {
+			c.EnvCheckInterval = 2 + i%20
+			return nil
+		})
+	}
+	old.EnvCheckInterval = 30
+	if _, err := s.Save(configSourceUI, old); !errors.Is(err, errStaleConfig) {
+		t.Fatalf("err = %v, want %v", err, errStaleConfig)
+	}
+	current, _ := s.Get()
+	current.EnvCheckInterval = 30
+	if saved, err := s.Save(configSourceUI, current); err != nil || saved.EnvCheckInterval != 30 {
+		t.Fatalf("save of the current copy: %v", err)
+	}
+}
+
+func TestRevisionIsNotSaved(t *testing.T) {
+	a := newSecretTestApp(t, newFakeSecretBackend("fake"))
+	t.Setenv("HOME", a.testHomeDir)
+	path, _ := a.getConfigPath()
+	if err := a.saveToPath(path, secretTestConfig()); err != nil {
+		t.Fatal(err)
+	}
+	config, err := a.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.EnvCheckInterval = 12
+	if err := a.SaveConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "revision") {
+		t.Fatal("revision written to the config file")
+	}
+	changes, _ := a.ListConfigHistory()
+	if len(changes) != 1 || changes[0].Changed != 1 {
+		t.Fatalf("history = %+v", changes)
+	}
+}
+
+func TestConfigStoreRecordsOutsideTheLock(t *testing.T) {
+	s := newMemoryConfigStore(storeTestConfig())
+	recording := make(chan int, 2)
+	release := make(chan struct{})
+	var recorded []int
+	s.record = func(source string, old, next *AppConfig) {
+		recording <- next.EnvCheckInterval
+		if next.EnvCheckInterval == 10 {
+			<-release
+		}
+		recorded = append(recorded, next.EnvCheckInterval)
+	}
+	change := func(n int) func(c *AppConfig) error {
+		return func(c *AppConfig) error {
+			c.EnvCheckInterval = n
+			return nil
+		}
+	}
+	first := make(chan error)
+	go func() {
+		_, err := s.Update(configSourceApp, change(10))
+		first <- err
+	}()
+	<-recording
+	// The store takes the next change while the first one is still being recorded
+	second := make(chan error)
+	go func() {
+		_, err := s.Update(configSourceApp, change(20))
+		second <- err
+	}()
+	select {
+	case <-recording:
+		t.Fatal("second change recorded before the first")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if c, _ := s.Get(); c.EnvCheckInterval != 20 {
+		t.Fatalf("second change not committed while recording: %d", c.EnvCheckInterval)
+	}
+	close(release)
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-second; err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded) != 2 || recorded[0] != 10 || recorded[1] != 20 {
+		t.Fatalf("recorded %v", recorded)
+	}
+}
+
+func TestSaveConfigNormalizes(t *testing.T) {
+	a := newSecretTestApp(t, newFakeSecretBackend("fake"))
+	t.Setenv("HOME", a.testHomeDir)
+	path, _ := a.getConfigPath()
+	if err := a.saveToPath(path, secretTestConfig()); err != nil {
+		t.Fatal(err)
+	}
+	config, err := a.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// What the UI may send: Original dropped, a key list out of step and a
+	// selection with different casing
+	m := getProviderModel(&config.Claude, "GLM")
+	m.ApiKeys = []ApiKeyEntry{{Label: "a", Key: "sk-a"}, {Label: "b", Key: "sk-b"}}
+	m.ActiveKey = 1
+	m.ApiKey = ""
+	config.Claude.Models = config.Claude.Models[1:]
+	config.Claude.CurrentModel = "glm"
+	if err := a.SaveConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := a.LoadConfig()
+	read, err := a.readConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, c := range map[string]AppConfig{"stored": stored, "read back": read} {
+		if c.Claude.Models[0].ModelName != "Original" || c.Claude.CurrentModel != "GLM" {
+			t.Fatalf("%s: models %v, current %q", name, c.Claude.Models[0].ModelName, c.Claude.CurrentModel)
+		}
+		if m := getProviderModel(&c.Claude, "GLM"); m.ApiKey != "sk-b" {
+			t.Fatalf("%s: key %q", name, m.ApiKey)
+		}
+	}
+}
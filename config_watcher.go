@@ -0,0 +1,244 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// configWatchDebounce is how long the watcher waits for a burst of events to settle.
+const configWatchDebounce = 300 * time.Millisecond
+
+// writeTracker remembers the content AICoder last wrote to each file, so that the
+// watcher can tell its own writes from edits made elsewhere.
+type writeTracker struct {
+	mu     sync.Mutex
+	hashes map[string][sha256.Size]byte
+}
+
+// ownWrites tracks every file written through writePrivateFile.
+var ownWrites = &writeTracker{hashes: make(map[string][sha256.Size]byte)}
+
+func (t *writeTracker) note(path string, data []byte) {
+	t.mu.Lock()
+	t.hashes[filepath.Clean(path)] = sha256.Sum256(data)
+	t.mu.Unlock()
+}
+
+func (t *writeTracker) isOwn(path string, data []byte) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	hash, ok := t.hashes[filepath.Clean(path)]
+	return ok && hash == sha256.Sum256(data)
+}
+
+// ConfigFileError reports an external edit that left the config file unreadable.
+type ConfigFileError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// watchedToolFiles lists the tool config files AICoder writes, for the watcher.
+func (a *App) watchedToolFiles() []string {
+	_, claudeSettings, claudeLegacy := a.getClaudeConfigPaths()
+	codexDir, codexAuth := a.getCodexConfigPaths()
+	_, opencodeConfig := a.getOpencodeConfigPaths()
+	_, iflowConfig := a.getIFlowConfigPaths()
+	_, kiloConfig := a.getKiloConfigPaths()
+	_, kodeConfig := a.getKodeConfigPaths()
+	files := []string{
+		claudeSettings, claudeLegacy,
+		codexAuth, filepath.Join(codexDir, "config.toml"),
+		opencodeConfig, iflowConfig, kiloConfig, kodeConfig,
+	}
+	// Kode reads ~/.kode.json, which is where the settings are written
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, ".kode.json"))
+	}
+	return files
+}
+
+// startConfigWatcher watches the directories holding the config file and the tool
+// config files, which also catches editors that save by renaming a new file into
+// place. Bursts of events are debounced per file, and content AICoder wrote itself
+// is ignored, so only genuine external edits are acted on.
+func (a *App) startConfigWatcher() {
+	configPath, err := a.getConfigPath()
+	if err != nil {
+		return
+	}
+	a.watcher, err = fsnotify.NewWatcher()
+	if err != nil {
+		a.log("Failed to create file watcher: " + err.Error())
+		return
+	}
+	handlers := map[string]func(string){configPath: a.configFileChanged}
+	for _, f := range a.watchedToolFiles() {
+		handlers[filepath.Clean(f)] = a.toolFileChanged
+	}
+	timers := make(map[string]*time.Timer)
+	go func() {
+		for {
+			select {
+			case event, ok := <-a.watcher.Events:
+				if !ok {
+					return
+				}
+				path := filepath.Clean(event.Name)
+				handle := handlers[path]
+				if handle == nil || event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+					continue
+				}
+				if t := timers[path]; t != nil {
+					t.Reset(configWatchDebounce)
+				} else {
+					timers[path] = time.AfterFunc(configWatchDebounce, func() { handle(path) })
+				}
+			case err, ok := <-a.watcher.Errors:
+				if !ok {
+					return
+				}
+				a.log("Watcher error: " + err.Error())
+			}
+		}
+	}()
+	dirs := make(map[string]bool)
+	for path := range handlers {
+		dirs[filepath.Dir(path)] = true
+	}
+	for dir := range dirs {
+		if _, err := os.Stat(dir); err != nil {
+			// A tool that has never run has no directory yet
+			continue
+		}
+		if err := a.watcher.Add(dir); err != nil {
+			a.log("Failed to watch " + dir + ": " + err.Error())
+		}
+	}
+	a.log("Watching config file: " + configPath)
+}
+
+// configFileChanged reloads the config after an external edit. An edit that does not
+// parse is reported and the config in memory is kept, rather than restoring a backup
+// over the file the user is still working on.
+func (a *App) configFileChanged(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// Renamed away; the new file raises its own event
+		return
+	}
+	if ownWrites.isOwn(path, data) {
+		return
+	}
+	a.log(a.tr("Config file modified: ") + path)
+	if err := json.Unmarshal(data, &AppConfig{}); err != nil {
+		a.log("Config file edit ignored, it is not valid: " + err.Error())
+		a.emitEvent("config-file-error", ConfigFileError{Path: path, Error: err.Error()})
+		return
+	}
+	config, err := a.configStore().Reload(configSourceWatcher)
+	if err != nil {
+		a.log("Failed to reload config: " + err.Error())
+		a.emitEvent("config-file-error", ConfigFileError{Path: path, Error: err.Error()})
+		return
+	}
+	a.emitEvent("config-updated", config)
+}
+
+// ToolConfigChange reports that managed values in a tool's config file were changed
+// outside AICoder, e.g. by `codex login`, so the active provider no longer matches.
+type ToolConfigChange struct {
+	Tool     string      `json:"tool"`
+	Provider string      `json:"provider"` // Provider selected in AICoder for the tool
+	Active   bool        `json:"active"`   // The tool is the active one
+	Path     string      `json:"path"`
+	Items    []DriftItem `json:"items"` // Empty once the file matches again
+}
+
+// toolDriftState remembers which managed keys were last reported per file, so an
+// unrelated rewrite of a file does not repeat the report.
+type toolDriftState struct {
+	mu       sync.Mutex
+	reported map[string]string
+}
+
+// changed records the keys now differing in path and reports whether that is news.
+func (s *toolDriftState) changed(path string, items []DriftItem) bool {
+	var keys []string
+	for _, it := range items {
+		keys = append(keys, it.Key+"="+it.Theirs)
+	}
+	sort.Strings(keys)
+	sig := strings.Join(keys, "\n")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reported == nil {
+		s.reported = make(map[string]string)
+	}
+	if s.reported[path] == sig {
+		return false
+	}
+	s.reported[path] = sig
+	return true
+}
+
+// toolFileChanged checks a tool config file edited outside AICoder against what
+// AICoder would write and emits "tool-config-changed" when a managed value, such as
+// the API key, base URL or model, differs or matches again.
+func (a *App) toolFileChanged(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil || ownWrites.isOwn(path, data) {
+		return
+	}
+	config, err := a.LoadConfig()
+	if err != nil {
+		return
+	}
+	for _, tool := range driftTools {
+		files, err := a.renderToolConfig(tool, config)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			if filepath.Clean(f.Path) != path {
+				continue
+			}
+			df, err := diffRenderedFile(tool, f)
+			if err != nil {
+				a.log(fmt.Sprintf("%s: %v", tool, err))
+				continue
+			}
+			m := currentModelConfig(getToolConfig(&config, tool))
+			items := []DriftItem{}
+			for _, it := range df.Items {
+				// Only values taken from the provider matter here
+				if f.Fields[it.Key] == "" {
+					continue
+				}
+				it.Field = adoptableField(tool, m, f.Fields[it.Key])
+				items = append(items, it)
+			}
+			if !a.toolDrift.changed(path, items) {
+				continue
+			}
+			if len(items) > 0 {
+				a.log(fmt.Sprintf("%s settings in %s were changed outside AICoder", tool, path))
+			}
+			a.emitEvent("tool-config-changed", ToolConfigChange{
+				Tool:     tool,
+				Provider: m.ModelName,
+				Active:   config.ActiveTool == tool,
+				Path:     path,
+				Items:    items,
+			})
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteTracker(t *testing.T) {
+	tracker := &writeTracker{hashes: make(map[string][32]byte)}
+	path := filepath.Join(t.TempDir(), "settings.json")
+	tracker.note(path, []byte("a"))
+	if !tracker.isOwn(filepath.Join(filepath.Dir(path), ".", "settings.json"), []byte("a")) {
+		t.Fatal("own write not recognized")
+	}
+	if tracker.isOwn(path, []byte("b")) || tracker.isOwn(path+".other", []byte("a")) {
+		t.Fatal("edit taken for an own write")
+	}
+}
+
+// writeExternalConfig writes config to path as an editor would, behind AICoder's back.
+func writeExternalConfig(t *testing.T, path string, edit func(*AppConfig)) []byte {
+	t.Helper()
+	var config AppConfig
+	data, _ := os.ReadFile(path)
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatal(err)
+	}
+	edit(&config)
+	data, _ = json.MarshalIndent(config, "", "  ")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// newWatcherTestApp returns an App with secretTestConfig saved and loaded.
+func newWatcherTestApp(t *testing.T) (*App, string) {
+	t.Helper()
+	a := newSecretTestApp(t, newFakeSecretBackend("fake"))
+	t.Setenv("HOME", a.testHomeDir)
+	path, _ := a.getConfigPath()
+	if err := a.saveToPath(path, secretTestConfig()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	return a, path
+}
+
+func TestConfigFileChanged(t *testing.T) {
+	a, path := newWatcherTestApp(t)
+
+	// An external edit is reloaded
+	writeExternalConfig(t, path, func(c *AppConfig) { c.EnvCheckInterval = 11 })
+	a.configFileChanged(path)
+	if config, _ := a.LoadConfig(); config.EnvCheckInterval != 11 {
+		t.Fatalf("interval %d after an external edit", config.EnvCheckInterval)
+	}
+
+	// Content AICoder wrote itself is not reloaded
+	data := writeExternalConfig(t, path, func(c *AppConfig) { c.EnvCheckInterval = 12 })
+	ownWrites.note(path, data)
+	a.configFileChanged(path)
+	if config, _ := a.LoadConfig(); config.EnvCheckInterval != 11 {
+		t.Fatalf("own write reloaded: interval %d", config.EnvCheckInterval)
+	}
+
+	// An edit that does not parse is reported, and neither reloaded nor repaired
+	os.WriteFile(path, []byte(`{"env_check_interval": 13,`), 0600)
+	a.configFileChanged(path)
+	if config, _ := a.LoadConfig(); config.EnvCheckInterval != 11 {
+		t.Fatalf("invalid edit reloaded: interval %d", config.EnvCheckInterval)
+	}
+	if data, _ := os.ReadFile(path); string(data) != `{"env_check_interval": 13,` {
+		t.Fatalf("file being edited was replaced:\n%s", data)
+	}
+}
+
+// A burst of writes is reloaded once it settles, with the last content.
+func TestConfigWatcherDebounces(t *testing.T) {
+	a, path := newWatcherTestApp(t)
+	a.startConfigWatcher()
+	if a.watcher == nil {
+		t.Fatal("watcher not started")
+	}
+	t.Cleanup(func() { a.watcher.Close() })
+	for i := 20; i <= 23; i++ {
+		writeExternalConfig(t, path, func(c *AppConfig) { c.EnvCheckInterval = i })
+		time.Sleep(20 * time.Millisecond)
+	}
+	time.Sleep(configWatchDebounce / 2)
+	if config, _ := a.LoadConfig(); config.EnvCheckInterval != 7 {
+		t.Fatalf("reloaded before the writes settled: interval %d", config.EnvCheckInterval)
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(50 * time.Millisecond) {
+		if config, _ := a.LoadConfig(); config.EnvCheckInterval == 23 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("edit not reloaded")
+		}
+	}
+}
+
+func TestToolFileChanged(t *testing.T) {
+	a, settingsPath := newDriftTestApp(t)
+	reported := func() string {
+		a.toolDrift.mu.Lock()
+		defer a.toolDrift.mu.Unlock()
+		return a.toolDrift.reported[settingsPath]
+	}
+	// settings.json as AICoder synced it
+	a.toolFileChanged(settingsPath)
+	if r := reported(); r != "" {
+		t.Fatalf("own write reported: %s", r)
+	}
+
+	settings, err := readOrderedJSONFile(settingsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := settings.object("env")
+	env.set("ANTHROPIC_MODEL", "claude-y")
+	settings.set("env", env)
+	data, _ := settings.marshalIndent()
+	os.WriteFile(settingsPath, data, 0600)
+	a.toolFileChanged(settingsPath)
+	if r := reported(); r != "env.ANTHROPIC_MODEL=claude-y" {
+		t.Fatalf("reported %q", r)
+	}
+	// The same drift is reported once, and matching again is news
+	if a.toolDrift.changed(settingsPath, []DriftItem{{Key: "env.ANTHROPIC_MODEL", Theirs: "claude-y"}}) {
+		t.Fatal("same drift reported twice")
+	}
+	if !a.toolDrift.changed(settingsPath, nil) || reported() != "" {
+		t.Fatal("matching again not reported")
+	}
+}
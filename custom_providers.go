@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// toolTakesCustom reports whether a tool accepts user-defined providers.
+func toolTakesCustom(tool string) bool {
+	return currentCatalog().defaults(tool).CustomSlots > 0
+}
+
+// ensureSharedCustomProviders copies every shared custom provider into the tools
+// that do not have it yet. A custom provider of the same name joins the shared one.
+func ensureSharedCustomProviders(config *AppConfig) {
+	var shared []ModelConfig
+	seen := make(map[string]bool)
+	for _, tool := range toolNames {
+		for _, m := range getToolConfig(config, tool).Models {
+			if m.IsCustom && m.Shared && !seen[strings.ToLower(m.ModelName)] {
+				seen[strings.ToLower(m.ModelName)] = true
+				shared = append(shared, m)
+			}
+		}
+	}
+	for _, tool := range toolNames {
+		if !toolTakesCustom(tool) {
+			continue
+		}
+		toolCfg := getToolConfig(config, tool)
+		for _, s := range shared {
+			if existing := getProviderModel(toolCfg, s.ModelName); existing != nil {
+				if existing.IsCustom {
+					existing.Shared = true
+				}
+				continue
+			}
+			toolCfg.Models = append(toolCfg.Models, s)
+		}
+	}
+}
+
+// sharedCustomDefinition is what the copies of a shared custom provider have in
+// common besides the name. Keys are kept in step by the key sync.
+type sharedCustomDefinition struct {
+	ModelUrl string
+	ModelId  string
+	WireApi  string
+	Roles    ModelRoles
+	Params   ModelParams
+}
+
+func customDefinition(m ModelConfig) sharedCustomDefinition {
+	return sharedCustomDefinition{m.ModelUrl, m.ModelId, m.WireApi, m.Roles, m.Params}
+}
+
+// syncSharedCustomProviders carries what a change did to a shared custom provider in
+// one tool over to the other tools: edits to its endpoint and models are copied, and
+// a provider removed or unshared in one tool is removed or unshared in all of them,
+// so that ensureSharedCustomProviders does not bring it back. If several tools were
+// edited differently, the first in tool order wins.
+func syncSharedCustomProviders(old, next *AppConfig) {
+	type edit struct {
+		tool string
+		def  sharedCustomDefinition
+	}
+	edits := make(map[string]edit)
+	removed := make(map[string]bool)
+	unshared := make(map[string]bool)
+	for _, tool := range toolNames {
+		nextTool := getToolConfig(next, tool)
+		for _, m := range getToolConfig(old, tool).Models {
+			if !m.IsCustom || !m.Shared {
+				continue
+			}
+			lower := strings.ToLower(m.ModelName)
+			n := getProviderModel(nextTool, m.ModelName)
+			switch {
+			case n == nil || !n.IsCustom:
+				removed[lower] = true
+			case !n.Shared:
+				unshared[lower] = true
+			case customDefinition(*n) != customDefinition(m):
+				if _, seen := edits[lower]; !seen {
+					edits[lower] = edit{tool, customDefinition(*n)}
+				}
+			}
+		}
+	}
+	for name := range removed {
+		for _, tool := range toolNames {
+			removeCustomProvider(next, tool, name)
+		}
+	}
+	for _, tool := range toolNames {
+		toolCfg := getToolConfig(next, tool)
+		for i := range toolCfg.Models {
+			m := &toolCfg.Models[i]
+			if !m.IsCustom || !m.Shared {
+				continue
+			}
+			if lower := strings.ToLower(m.ModelName); unshared[lower] {
+				m.Shared = false
+			} else if e, ok := edits[lower]; ok && e.tool != tool {
+				m.ModelUrl, m.ModelId, m.WireApi, m.Roles, m.Params = e.def.ModelUrl, e.def.ModelId, e.def.WireApi, e.def.Roles, e.def.Params
+			}
+		}
+	}
+}
+
+// removeCustomProvider drops a custom provider from a tool. If the tool had it
+// selected, it falls back to its default provider.
+func removeCustomProvider(config *AppConfig, tool, name string) {
+	toolCfg := getToolConfig(config, tool)
+	var kept []ModelConfig
+	for _, p := range toolCfg.Models {
+		if !(p.IsCustom && strings.EqualFold(p.ModelName, name)) {
+			kept = append(kept, p)
+		}
+	}
+	toolCfg.Models = kept
+	if strings.EqualFold(toolCfg.CurrentModel, name) {
+		if d := currentCatalog().defaults(tool); d.Original {
+			toolCfg.CurrentModel = "Original"
+		} else {
+			toolCfg.CurrentModel = d.DefaultProvider
+		}
+	}
+}
+
+// customProviderTools returns the tools a custom provider lives in: all tools that
+// take custom providers if it is shared, otherwise just its own.
+func customProviderTools(tool string, shared bool) []string {
+	if !shared {
+		return []string{tool}
+	}
+	var tools []string
+	for _, t := range toolNames {
+		if toolTakesCustom(t) {
+			tools = append(tools, t)
+		}
+	}
+	return tools
+}
+
+// validateCustomName checks that name can be used for a custom provider in tools.
+// except is the provider's current name when renaming.
+func validateCustomName(config *AppConfig, tools []string, name, except string) error {
+	if name == "" {
+		return fmt.Errorf("provider name must not be empty")
+	}
+	if strings.EqualFold(name, "Original") || currentCatalog().lookup(name) != nil {
+		return fmt.Errorf("%s is the name of a built-in provider", name)
+	}
+	for _, tool := range tools {
+		for _, m := range getToolConfig(config, tool).Models {
+			if strings.EqualFold(m.ModelName, name) && !strings.EqualFold(m.ModelName, except) {
+				return fmt.Errorf("a provider named %s already exists for %s", m.ModelName, tool)
+			}
+		}
+	}
+	return nil
+}
+
+// sanitizeCustomNames names unnamed custom providers "Custom" and numbers custom
+// providers whose name is already taken in the tool, since the name identifies a
+// provider and its stored key.
+func sanitizeCustomNames(models []ModelConfig) {
+	taken := make(map[string]bool)
+	for _, m := range models {
+		if !m.IsCustom {
+			taken[strings.ToLower(m.ModelName)] = true
+		}
+	}
+	for i := range models {
+		m := &models[i]
+		if !m.IsCustom {
+			continue
+		}
+		name := strings.TrimSpace(m.ModelName)
+		if name == "" {
+			name = "Custom"
+		}
+		unique := name
+		for n := 2; taken[strings.ToLower(unique)]; n++ {
+			unique = fmt.Sprintf("%s %d", name, n)
+		}
+		taken[strings.ToLower(unique)] = true
+		m.ModelName = unique
+	}
+}
+
+// findCustomProvider returns the named custom provider of a tool.
+func findCustomProvider(config *AppConfig, tool, name string) (*ToolConfig, *ModelConfig, error) {
+	toolCfg := getToolConfig(config, tool)
+	if toolCfg == nil {
+		return nil, nil, fmt.Errorf("unknown tool %s", tool)
+	}
+	m := getProviderModel(toolCfg, name)
+	if m == nil || !m.IsCustom {
+		return nil, nil, fmt.Errorf("custom provider %s not found for %s", name, tool)
+	}
+	return toolCfg, m, nil
+}
+
+// AddCustomProvider appends a new, empty custom provider to a tool, or to every
+// tool when shared.
+func (a *App) AddCustomProvider(tool string, name string, shared bool) (AppConfig, error) {
+	tool = strings.ToLower(tool)
+	name = strings.TrimSpace(name)
+	return a.modifyConfig(func(config *AppConfig) error {
+		toolCfg := getToolConfig(config, tool)
+		if toolCfg == nil {
+			return fmt.Errorf("unknown tool %s", tool)
+		}
+		if !toolTakesCustom(tool) {
+			return fmt.Errorf("%s does not take custom providers", tool)
+		}
+		if err := validateCustomName(config, customProviderTools(tool, shared), name, ""); err != nil {
+			return err
+		}
+		toolCfg.Models = append(toolCfg.Models, ModelConfig{ModelName: name, IsCustom: true, Shared: shared})
+		ensureSharedCustomProviders(config)
+		return nil
+	})
+}
+
+// RenameCustomProvider renames a custom provider, in every tool if it is shared.
+func (a *App) RenameCustomProvider(tool string, oldName string, newName string) (AppConfig, error) {
+	tool = strings.ToLower(tool)
+	newName = strings.TrimSpace(newName)
+	return a.modifyConfig(func(config *AppConfig) error {
+		_, m, err := findCustomProvider(config, tool, oldName)
+		if err != nil {
+			return err
+		}
+		tools := customProviderTools(tool, m.Shared)
+		if err := validateCustomName(config, tools, newName, m.ModelName); err != nil {
+			return err
+		}
+		oldName = m.ModelName
+		for _, t := range tools {
+			toolCfg := getToolConfig(config, t)
+			if p := getProviderModel(toolCfg, oldName); p != nil && p.IsCustom {
+				p.ModelName = newName
+				if toolCfg.CurrentModel == oldName {
+					toolCfg.CurrentModel = newName
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// DuplicateCustomProvider copies a custom provider under a new name, right after the
+// original. The copy belongs to this tool only.
+func (a *App) DuplicateCustomProvider(tool string, name string, newName string) (AppConfig, error) {
+	tool = strings.ToLower(tool)
+	newName = strings.TrimSpace(newName)
+	return a.modifyConfig(func(config *AppConfig) error {
+		toolCfg, m, err := findCustomProvider(config, tool, name)
+		if err != nil {
+			return err
+		}
+		if err := validateCustomName(config, []string{tool}, newName, ""); err != nil {
+			return err
+		}
+		dup := *m
+		dup.ModelName = newName
+		dup.Shared = false
+		for i := range toolCfg.Models {
+			if toolCfg.Models[i].ModelName == m.ModelName {
+				toolCfg.Models = append(toolCfg.Models[:i+1], append([]ModelConfig{dup}, toolCfg.Models[i+1:]...)...)
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// ReorderCustomProviders puts a tool's custom providers in the given order. names must
+// list each custom provider of the tool exactly once; built-in providers keep their places.
+func (a *App) ReorderCustomProviders(tool string, names []string) (AppConfig, error) {
+	tool = strings.ToLower(tool)
+	return a.modifyConfig(func(config *AppConfig) error {
+		toolCfg := getToolConfig(config, tool)
+		if toolCfg == nil {
+			return fmt.Errorf("unknown tool %s", tool)
+		}
+		var slots []int
+		byName := make(map[string]ModelConfig)
+		for i, m := range toolCfg.Models {
+			if m.IsCustom {
+				slots = append(slots, i)
+				byName[strings.ToLower(m.ModelName)] = m
+			}
+		}
+		if len(names) != len(slots) {
+			return fmt.Errorf("expected %d custom providers, got %d", len(slots), len(names))
+		}
+		for i, name := range names {
+			m, ok := byName[strings.ToLower(name)]
+			if !ok {
+				return fmt.Errorf("custom provider %s not found for %s", name, tool)
+			}
+			delete(byName, strings.ToLower(name))
+			toolCfg.Models[slots[i]] = m
+		}
+		return nil
+	})
+}
+
+// DeleteCustomProvider removes a custom provider, from every tool if it is shared.
+// Tools that had it selected fall back to their default provider.
+func (a *App) DeleteCustomProvider(tool string, name string) (AppConfig, error) {
+	tool = strings.ToLower(tool)
+	return a.modifyConfig(func(config *AppConfig) error {
+		_, m, err := findCustomProvider(config, tool, name)
+		if err != nil {
+			return err
+		}
+		name = m.ModelName
+		for _, t := range customProviderTools(tool, m.Shared) {
+			removeCustomProvider(config, t, name)
+		}
+		return nil
+	})
+}
+
+// SetCustomProviderShared shares a custom provider with every tool, or stops sharing
+// it. Unsharing leaves independent copies in the other tools.
+func (a *App) SetCustomProviderShared(tool string, name string, shared bool) (AppConfig, error) {
+	tool = strings.ToLower(tool)
+	return a.modifyConfig(func(config *AppConfig) error {
+		_, m, err := findCustomProvider(config, tool, name)
+		if err != nil {
+			return err
+		}
+		if m.Shared == shared {
+			return nil
+		}
+		name = m.ModelName
+		if shared {
+			// Another tool may use the name for a different provider
+			for _, t := range customProviderTools(tool, true) {
+				if p := getProviderModel(getToolConfig(config, t), name); p != nil && !p.IsCustom {
+					return fmt.Errorf("a provider named %s already exists for %s", p.ModelName, t)
+				}
+			}
+			m.Shared = true
+			ensureSharedCustomProviders(config)
+			return nil
+		}
+		for _, t := range toolNames {
+			if p := getProviderModel(getToolConfig(config, t), name); p != nil && p.IsCustom {
+				p.Shared = false
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func newCustomProviderTestApp(t *testing.T) *App {
+	t.Helper()
+	a := newSecretTestApp(t, newFakeSecretBackend("fake"))
+	t.Setenv("HOME", a.testHomeDir)
+	path, _ := a.getConfigPath()
+	if err := a.saveToPath(path, secretTestConfig()); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// customNames lists the custom providers of a tool in order.
+func customNames(config *AppConfig, tool string) string {
+	var names []string
+	for _, m := range getToolConfig(config, tool).Models {
+		if m.IsCustom {
+			names = append(names, m.ModelName)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func TestCustomProviderLifecycle(t *testing.T) {
+	a := newCustomProviderTestApp(t)
+	tools := customProviderTools("claude", true)
+	if len(tools) < 2 {
+		t.Fatalf("tools taking custom providers: %v", tools)
+	}
+	if _, err := a.AddCustomProvider("claude", "Relay", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.AddCustomProvider("Claude", " Own ", false); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"relay", "GLM", "Original", ""} {
+		if _, err := a.AddCustomProvider("claude", name, false); err == nil {
+			t.Errorf("custom provider %q added", name)
+		}
+	}
+	config, err := a.RenameCustomProvider("codex", "Relay", "Gateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := customNames(&config, "claude"); got != "Gateway,Own" {
+		t.Fatalf("claude custom providers = %s", got)
+	}
+	for _, tool := range tools {
+		toolCfg := getToolConfig(&config, tool)
+		if getProviderModel(toolCfg, "Gateway") == nil || getProviderModel(toolCfg, "Relay") != nil {
+			t.Fatalf("%s custom providers = %s", tool, customNames(&config, tool))
+		}
+		if tool != "claude" && getProviderModel(toolCfg, "Own") != nil {
+			t.Fatalf("unshared provider added to %s", tool)
+		}
+	}
+	config, err = a.DuplicateCustomProvider("claude", "Gateway", "Gateway copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := customNames(&config, "claude"); got != "Gateway,Gateway copy,Own" || getProviderModel(&config.Claude, "Gateway copy").Shared {
+		t.Fatalf("after duplicating: %s", got)
+	}
+	if config, err = a.ReorderCustomProviders("claude", []string{"Own", "gateway", "Gateway copy"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := customNames(&config, "claude"); got != "Own,Gateway,Gateway copy" {
+		t.Fatalf("after reordering: %s", got)
+	}
+	if _, err := a.ReorderCustomProviders("claude", []string{"Own"}); err == nil {
+		t.Fatal("incomplete order accepted")
+	}
+
+	config, _ = a.modifyConfig(func(c *AppConfig) error {
+		c.Codex.CurrentModel = "Gateway"
+		return nil
+	})
+	if config, err = a.DeleteCustomProvider("claude", "Gateway"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tool := range tools {
+		if getProviderModel(getToolConfig(&config, tool), "Gateway") != nil {
+			t.Fatalf("shared provider left in %s", tool)
+		}
+	}
+	if config.Codex.CurrentModel == "Gateway" || config.Codex.CurrentModel == "" {
+		t.Fatalf("codex selection = %q", config.Codex.CurrentModel)
+	}
+}
+
+func TestSetCustomProviderShared(t *testing.T) {
+	a := newCustomProviderTestApp(t)
+	a.AddCustomProvider("claude", "Relay", false)
+	config, err := a.SetCustomProviderShared("claude", "Relay", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := getProviderModel(&config.Codex, "Relay"); m == nil || !m.Shared {
+		t.Fatalf("codex Relay = %+v", m)
+	}
+	config, err = a.SetCustomProviderShared("codex", "Relay", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Unsharing leaves independent copies
+	for _, tool := range []string{"claude", "codex"} {
+		if m := getProviderModel(getToolConfig(&config, tool), "Relay"); m == nil || m.Shared {
+			t.Fatalf("%s Relay = %+v", tool, m)
+		}
+	}
+	if config, err = a.DeleteCustomProvider("codex", "Relay"); err != nil || getProviderModel(&config.Claude, "Relay") == nil {
+		t.Fatalf("deleting an unshared copy: %v", err)
+	}
+}
+
+// Edits made through SaveConfig, as the UI does, reach every copy of a shared provider.
+func TestSaveConfigSyncsSharedCustomProviders(t *testing.T) {
+	a := newCustomProviderTestApp(t)
+	a.AddCustomProvider("claude", "Relay", true)
+	a.AddCustomProvider("claude", "Other", true)
+	tools := customProviderTools("claude", true)
+
+	config, _ := a.LoadConfig()
+	m := getProviderModel(&config.Codex, "Relay")
+	m.ModelUrl = "https://relay.example/v1"
+	m.ModelId = "m-1"
+	m.Roles.Fast = "m-mini"
+	if err := a.SaveConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	config, _ = a.LoadConfig()
+	for _, tool := range tools {
+		m := getProviderModel(getToolConfig(&config, tool), "Relay")
+		if m.ModelUrl != "https://relay.example/v1" || m.ModelId != "m-1" || m.Roles.Fast != "m-mini" {
+			t.Fatalf("%s Relay = %+v", tool, m)
+		}
+	}
+
+	// Removed from one tool, gone from all, also after reading the file again
+	var kept []ModelConfig
+	for _, m := range config.Opencode.Models {
+		if m.ModelName != "Relay" {
+			kept = append(kept, m)
+		}
+	}
+	config.Opencode.Models = kept
+	if err := a.SaveConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	read, err := a.readConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := a.LoadConfig()
+	for _, tool := range tools {
+		for name, c := range map[string]*AppConfig{"stored": &stored, "read": &read} {
+			if getProviderModel(getToolConfig(c, tool), "Relay") != nil {
+				t.Fatalf("%s: deleted shared provider back in %s", name, tool)
+			}
+			if getProviderModel(getToolConfig(c, tool), "Other") == nil {
+				t.Fatalf("%s: other shared provider lost in %s", name, tool)
+			}
+		}
+	}
+
+	// Unshared in one tool, unshared in all
+	getProviderModel(&stored.Claude, "Other").Shared = false
+	if err := a.SaveConfig(stored); err != nil {
+		t.Fatal(err)
+	}
+	config, _ = a.LoadConfig()
+	for _, tool := range tools {
+		if m := getProviderModel(getToolConfig(&config, tool), "Other"); m == nil || m.Shared {
+			t.Fatalf("%s Other = %+v", tool, m)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
+)
+
+// Modes for files holding API keys or passwords and for their directories.
+const (
+	privateFileMode = 0600
+	privateDirMode  = 0700
+)
+
+// writePrivateFile atomically writes a file that holds credentials. The file is made 0600
+// even if it already existed with a looser mode, and its directory 0700, except for the home
+// directory, whose mode is left to the user. Windows has no such modes and files there
+// inherit the profile's ACLs.
+func writePrivateFile(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, privateDirMode); err != nil {
+		return err
+	}
+	// Noted first, as the watcher may see the file before this returns
+	ownWrites.note(path, data)
+	if err := atomicWriteFile(path, data, privateFileMode); err != nil {
+		return err
+	}
+	if goruntime.GOOS == "windows" {
+		return nil
+	}
+	if !isHomeDir(dir) {
+		return tightenMode(dir, privateDirMode)
+	}
+	return nil
+}
+
+// appendPrivateFile adds data to the end of path, creating it as writePrivateFile would.
+func appendPrivateFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), privateDirMode); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, privateFileMode)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// tightenMode removes group and other permissions from path if it has any.
+func tightenMode(path string, mode os.FileMode) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm()&0077 == 0 {
+		return nil
+	}
+	return os.Chmod(path, mode)
+}
+
+func isHomeDir(dir string) bool {
+	home, err := os.UserHomeDir()
+	return err == nil && filepath.Clean(dir) == filepath.Clean(home)
+}
+
+// managedFile is a file AICoder writes credentials into.
+type managedFile struct {
+	Tool string
+	Path string
+}
+
+// managedFiles lists every file AICoder may have written credentials into: its own
+// config and secrets, the tool configs and the project files of Qoder and CodeBuddy.
+func (a *App) managedFiles() []managedFile {
+	var files []managedFile
+	if path, err := a.getConfigPath(); err == nil {
+		files = append(files, managedFile{"aicoder", path})
+	}
+	secretFile := newFileSecretBackend(a.GetUserHomeDir())
+	files = append(files, managedFile{"aicoder", secretFile.path}, managedFile{"aicoder", secretFile.keyPath})
+
+	_, claudeSettings, claudeLegacy := a.getClaudeConfigPaths()
+	codexDir, codexAuth := a.getCodexConfigPaths()
+	_, opencodeConfig := a.getOpencodeConfigPaths()
+	_, iflowConfig := a.getIFlowConfigPaths()
+	_, kiloConfig := a.getKiloConfigPaths()
+	files = append(files,
+		managedFile{"claude", claudeSettings},
+		managedFile{"claude", claudeLegacy},
+		managedFile{"codex", codexAuth},
+		managedFile{"codex", filepath.Join(codexDir, "config.toml")},
+		managedFile{"opencode", opencodeConfig},
+		managedFile{"iflow", iflowConfig},
+		managedFile{"kilo", kiloConfig},
+	)
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, managedFile{"kode", filepath.Join(home, ".kode.json")})
+	}
+
+	if config, err := a.LoadConfig(); err == nil {
+		seen := make(map[string]bool)
+		for _, p := range config.Projects {
+			if p.Path == "" || seen[p.Path] {
+				continue
+			}
+			seen[p.Path] = true
+			for _, tool := range []string{"qoder", "codebuddy"} {
+				files = append(files, managedFile{tool, filepath.Join(p.Path, "."+tool, "models.json")})
+			}
+		}
+	}
+	return files
+}
+
+// FilePermission describes the mode and owner of a file or directory AICoder manages.
+type FilePermission struct {
+	Path    string `json:"path"`
+	Tool    string `json:"tool"`
+	IsDir   bool   `json:"is_dir"`
+	Mode    string `json:"mode"`     // Permission bits in octal, e.g. 0644
+	Owner   string `json:"owner"`    // User name, or uid if it has no name; empty on Windows
+	Want    string `json:"want"`     // Mode the entry should have
+	TooOpen bool   `json:"too_open"` // Readable or writable by other users
+}
+
+// AuditFilePermissions reports every existing AICoder-managed file, and the directories
+// holding them, with their current mode and owner. On Windows modes do not apply and
+// nothing is reported as too permissive.
+func (a *App) AuditFilePermissions() ([]FilePermission, error) {
+	var result []FilePermission
+	seen := make(map[string]bool)
+	add := func(tool, path string, want os.FileMode) bool {
+		if seen[path] {
+			return true
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return false
+		}
+		seen[path] = true
+		result = append(result, FilePermission{
+			Path:    path,
+			Tool:    tool,
+			IsDir:   info.IsDir(),
+			Mode:    fmt.Sprintf("%04o", info.Mode().Perm()),
+			Owner:   fileOwner(info),
+			Want:    fmt.Sprintf("%04o", want),
+			TooOpen: goruntime.GOOS != "windows" && info.Mode().Perm()&0077 != 0,
+		})
+		return true
+	}
+	for _, f := range a.managedFiles() {
+		if !add(f.Tool, f.Path, privateFileMode) {
+			continue
+		}
+		if dir := filepath.Dir(f.Path); !isHomeDir(dir) && dir != a.GetUserHomeDir() {
+			add(f.Tool, dir, privateDirMode)
+		}
+	}
+	return result, nil
+}
+
+// FixFilePermissions removes group and other access from the given paths, which must
+// be entries of AuditFilePermissions. It returns the audit after the fix.
+func (a *App) FixFilePermissions(paths []string) ([]FilePermission, error) {
+	audit, err := a.AuditFilePermissions()
+	if err != nil {
+		return nil, err
+	}
+	byPath := make(map[string]FilePermission)
+	for _, e := range audit {
+		byPath[e.Path] = e
+	}
+	var errs []string
+	for _, path := range paths {
+		e, ok := byPath[path]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("%s is not managed by AICoder", path))
+			continue
+		}
+		if !e.TooOpen {
+			continue
+		}
+		mode := os.FileMode(privateFileMode)
+		if e.IsDir {
+			mode = privateDirMode
+		}
+		if err := os.Chmod(path, mode); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		a.log(fmt.Sprintf("Permissions: %s set to %04o", path, mode))
+	}
+	audit, _ = a.AuditFilePermissions()
+	if len(errs) > 0 {
+		return audit, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return audit, nil
+}
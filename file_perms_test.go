@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"testing"
+)
+
+func fileMode(t *testing.T, path string) os.FileMode {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Mode().Perm()
+}
+
+func TestWritePrivateFileTightensModes(t *testing.T) {
+	if goruntime.GOOS == "windows" {
+		t.Skip("no file modes on Windows")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, ".codex")
+	os.Mkdir(dir, 0755)
+	path := filepath.Join(dir, "auth.json")
+	os.WriteFile(path, []byte("{}"), 0644)
+	os.Chmod(home, 0755)
+
+	if err := writePrivateFile(path, []byte(`{"OPENAI_API_KEY":"sk-1"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if m := fileMode(t, path); m != 0600 {
+		t.Fatalf("file mode %04o", m)
+	}
+	if m := fileMode(t, dir); m != 0700 {
+		t.Fatalf("directory mode %04o", m)
+	}
+	// The home directory is the user's business
+	if err := writePrivateFile(filepath.Join(home, ".kode.json"), []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if m := fileMode(t, home); m != 0755 {
+		t.Fatalf("home mode changed to %04o", m)
+	}
+
+	log := filepath.Join(home, ".cceasy", "history.jsonl")
+	for i := 0; i < 2; i++ {
+		if err := appendPrivateFile(log, []byte("line\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if data, _ := os.ReadFile(log); string(data) != "line\nline\n" || fileMode(t, log) != 0600 {
+		t.Fatalf("appended %q, mode %04o", data, fileMode(t, log))
+	}
+}
+
+func TestAuditAndFixFilePermissions(t *testing.T) {
+	if goruntime.GOOS == "windows" {
+		t.Skip("no file modes on Windows")
+	}
+	a := newSecretTestApp(t, newFakeSecretBackend("fake"))
+	t.Setenv("HOME", a.testHomeDir)
+	path, _ := a.getConfigPath()
+	if err := a.saveToPath(path, secretTestConfig()); err != nil {
+		t.Fatal(err)
+	}
+	codexDir, authPath := a.getCodexConfigPaths()
+	os.MkdirAll(codexDir, 0755)
+	os.WriteFile(authPath, []byte(`{"OPENAI_API_KEY":"sk-1"}`), 0644)
+	os.Chmod(codexDir, 0755)
+
+	audit, err := a.AuditFilePermissions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := make(map[string]FilePermission)
+	for _, e := range audit {
+		if e.TooOpen {
+			open[e.Path] = e
+		}
+		if e.Path == path && (e.TooOpen || e.Mode != "0600" || e.Owner == "") {
+			t.Errorf("config entry = %+v", e)
+		}
+	}
+	if e := open[authPath]; e.Mode != "0644" || e.Want != "0600" || e.Tool != "codex" || len(open) != 2 || !open[codexDir].IsDir {
+		t.Fatalf("too open: %+v", open)
+	}
+
+	audit, err = a.FixFilePermissions([]string{authPath, codexDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range audit {
+		if e.TooOpen {
+			t.Errorf("still too open: %+v", e)
+		}
+	}
+	if m := fileMode(t, codexDir); m != 0700 {
+		t.Fatalf("directory mode %04o", m)
+	}
+	if _, err := a.FixFilePermissions([]string{"/etc/passwd"}); err == nil {
+		t.Fatal("file AICoder does not manage changed")
+	}
+}
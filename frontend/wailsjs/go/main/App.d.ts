@@ -2,10 +2,18 @@
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
 
+export function AddCustomProvider(arg1:string,arg2:string,arg3:boolean):Promise<main.AppConfig>;
+
 export function AddSkill(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<void>;
 
+export function ApplyConfigImports(arg1:Array<main.ImportCandidate>):Promise<main.AppConfig>;
+
+export function AuditFilePermissions():Promise<Array<main.FilePermission>>;
+
 export function CancelDownload(arg1:string):Promise<void>;
 
+export function CheckConfigDrift():Promise<main.DriftReport>;
+
 export function CheckEnvironment(arg1:boolean):Promise<void>;
 
 export function CheckToolsStatus():Promise<Array<main.ToolStatus>>;
@@ -14,24 +22,48 @@ export function CheckUpdate(arg1:string):Promise<main.UpdateResult>;
 
 export function ClipboardGetText():Promise<string>;
 
+export function DeleteCustomProvider(arg1:string,arg2:string):Promise<main.AppConfig>;
+
 export function DeleteSkill(arg1:string,arg2:string):Promise<void>;
 
 export function DownloadUpdate(arg1:string,arg2:string):Promise<string>;
 
+export function DuplicateCustomProvider(arg1:string,arg2:string,arg3:string):Promise<main.AppConfig>;
+
+export function ExportConfigBundle(arg1:string,arg2:string):Promise<void>;
+
+export function FixFilePermissions(arg1:Array<string>):Promise<Array<main.FilePermission>>;
+
+export function GetConfigHistoryDiff(arg1:string):Promise<Array<main.ConfigChange>>;
+
+export function GetConfigRecovery():Promise<main.ConfigRecovery>;
+
 export function GetCurrentProjectPath():Promise<string>;
 
 export function GetDownloadsFolder():Promise<string>;
 
+export function GetEffectiveProjectSettings(arg1:string):Promise<main.EffectiveProjectSettings>;
+
 export function GetEnvCheckInterval():Promise<number>;
 
 export function GetLocalCacheDir():Promise<string>;
 
+export function GetProviderCatalogStatus():Promise<main.CatalogStatus>;
+
+export function GetProviderKeySync():Promise<Record<string, string>>;
+
+export function GetSecretStoreStatus():Promise<main.SecretStoreStatus>;
+
 export function GetSkillsDir(arg1:string):Promise<string>;
 
 export function GetSystemInfo():Promise<main.SystemInfo>;
 
 export function GetUserHomeDir():Promise<string>;
 
+export function ImportConfigBundle(arg1:string,arg2:string):Promise<main.BundlePreview>;
+
+export function ImportExistingConfigs():Promise<Array<main.ImportCandidate>>;
+
 export function InstallDefaultMarketplace():Promise<void>;
 
 export function InstallSkill(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:string,arg7:string):Promise<void>;
@@ -48,6 +80,10 @@ export function LaunchInstallerAndExit(arg1:string):Promise<void>;
 
 export function LaunchTool(arg1:string,arg2:boolean,arg3:boolean,arg4:boolean,arg5:string,arg6:string,arg7:boolean):Promise<void>;
 
+export function ListConfigHistory():Promise<Array<main.ConfigHistoryEntry>>;
+
+export function ListProviderModels(arg1:string,arg2:string):Promise<main.ProviderModelList>;
+
 export function ListPythonEnvironments():Promise<Array<main.PythonEnvironment>>;
 
 export function ListSkills(arg1:string):Promise<Array<main.Skill>>;
@@ -60,6 +96,12 @@ export function OpenSystemUrl(arg1:string):Promise<void>;
 
 export function PackLog(arg1:string):Promise<string>;
 
+export function PreviewApiKeySync(arg1:main.AppConfig):Promise<main.KeySyncPlan>;
+
+export function PreviewConfigBundle(arg1:string,arg2:string):Promise<main.BundlePreview>;
+
+export function ProbeApiKeys(arg1:string,arg2:string):Promise<Array<main.ApiKeyProbe>>;
+
 export function ReadBBS():Promise<string>;
 
 export function ReadThanks():Promise<string>;
@@ -68,26 +110,50 @@ export function ReadTutorial():Promise<string>;
 
 export function RecoverCC():Promise<void>;
 
+export function RefreshProviderCatalog():Promise<main.CatalogStatus>;
+
+export function RenameCustomProvider(arg1:string,arg2:string,arg3:string):Promise<main.AppConfig>;
+
+export function ReorderCustomProviders(arg1:string,arg2:Array<string>):Promise<main.AppConfig>;
+
 export function ResizeWindow(arg1:number,arg2:number):Promise<void>;
 
+export function ResolveConfigDrift(arg1:string,arg2:string,arg3:Array<string>,arg4:string):Promise<void>;
+
+export function RevertConfig(arg1:string):Promise<main.AppConfig>;
+
 export function RunEnvironmentCheckCLI():Promise<void>;
 
 export function SaveConfig(arg1:main.AppConfig):Promise<void>;
 
+export function SelectBundleExportPath():Promise<string>;
+
+export function SelectBundleFile():Promise<string>;
+
 export function SelectProjectDir():Promise<string>;
 
 export function SelectSkillFile():Promise<string>;
 
+export function SetApiKeyExhausted(arg1:string,arg2:string,arg3:number,arg4:boolean):Promise<main.AppConfig>;
+
+export function SetCustomProviderShared(arg1:string,arg2:string,arg3:boolean):Promise<main.AppConfig>;
+
 export function SetEnvCheckInterval(arg1:number):Promise<void>;
 
 export function SetLanguage(arg1:string):Promise<void>;
 
+export function SetProviderKeySync(arg1:string,arg2:string):Promise<main.AppConfig>;
+
 export function ShouldCheckEnvironment():Promise<boolean>;
 
 export function ShowItemInFolder(arg1:string):Promise<void>;
 
 export function ShowMessage(arg1:string,arg2:string):Promise<void>;
 
+export function TestProvider(arg1:string,arg2:string):Promise<main.ProviderTestResult>;
+
+export function TrustProjectFile(arg1:string):Promise<void>;
+
 export function UpdateLastEnvCheckTime():Promise<void>;
 
 export function UpdateTool(arg1:string):Promise<void>;
@@ -2,14 +2,30 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AddCustomProvider(arg1, arg2, arg3) {
+  return window['go']['main']['App']['AddCustomProvider'](arg1, arg2, arg3);
+}
+
 export function AddSkill(arg1, arg2, arg3, arg4, arg5) {
   return window['go']['main']['App']['AddSkill'](arg1, arg2, arg3, arg4, arg5);
 }
 
+export function ApplyConfigImports(arg1) {
+  return window['go']['main']['App']['ApplyConfigImports'](arg1);
+}
+
+export function AuditFilePermissions() {
+  return window['go']['main']['App']['AuditFilePermissions']();
+}
+
 export function CancelDownload(arg1) {
   return window['go']['main']['App']['CancelDownload'](arg1);
 }
 
+export function CheckConfigDrift() {
+  return window['go']['main']['App']['CheckConfigDrift']();
+}
+
 export function CheckEnvironment(arg1) {
   return window['go']['main']['App']['CheckEnvironment'](arg1);
 }
@@ -26,6 +42,10 @@ export function ClipboardGetText() {
   return window['go']['main']['App']['ClipboardGetText']();
 }
 
+export function DeleteCustomProvider(arg1, arg2) {
+  return window['go']['main']['App']['DeleteCustomProvider'](arg1, arg2);
+}
+
 export function DeleteSkill(arg1, arg2) {
   return window['go']['main']['App']['DeleteSkill'](arg1, arg2);
 }
@@ -34,6 +54,26 @@ export function DownloadUpdate(arg1, arg2) {
   return window['go']['main']['App']['DownloadUpdate'](arg1, arg2);
 }
 
+export function DuplicateCustomProvider(arg1, arg2, arg3) {
+  return window['go']['main']['App']['DuplicateCustomProvider'](arg1, arg2, arg3);
+}
+
+export function ExportConfigBundle(arg1, arg2) {
+  return window['go']['main']['App']['ExportConfigBundle'](arg1, arg2);
+}
+
+export function FixFilePermissions(arg1) {
+  return window['go']['main']['App']['FixFilePermissions'](arg1);
+}
+
+export function GetConfigHistoryDiff(arg1) {
+  return window['go']['main']['App']['GetConfigHistoryDiff'](arg1);
+}
+
+export function GetConfigRecovery() {
+  return window['go']['main']['App']['GetConfigRecovery']();
+}
+
 export function GetCurrentProjectPath() {
   return window['go']['main']['App']['GetCurrentProjectPath']();
 }
@@ -42,6 +82,10 @@ export function GetDownloadsFolder() {
   return window['go']['main']['App']['GetDownloadsFolder']();
 }
 
+export function GetEffectiveProjectSettings(arg1) {
+  return window['go']['main']['App']['GetEffectiveProjectSettings'](arg1);
+}
+
 export function GetEnvCheckInterval() {
   return window['go']['main']['App']['GetEnvCheckInterval']();
 }
@@ -50,6 +94,18 @@ export function GetLocalCacheDir() {
   return window['go']['main']['App']['GetLocalCacheDir']();
 }
 
+export function GetProviderCatalogStatus() {
+  return window['go']['main']['App']['GetProviderCatalogStatus']();
+}
+
+export function GetProviderKeySync() {
+  return window['go']['main']['App']['GetProviderKeySync']();
+}
+
+export function GetSecretStoreStatus() {
+  return window['go']['main']['App']['GetSecretStoreStatus']();
+}
+
 export function GetSkillsDir(arg1) {
   return window['go']['main']['App']['GetSkillsDir'](arg1);
 }
@@ -62,6 +118,14 @@ export function GetUserHomeDir() {
   return window['go']['main']['App']['GetUserHomeDir']();
 }
 
+export function ImportConfigBundle(arg1, arg2) {
+  return window['go']['main']['App']['ImportConfigBundle'](arg1, arg2);
+}
+
+export function ImportExistingConfigs() {
+  return window['go']['main']['App']['ImportExistingConfigs']();
+}
+
 export function InstallDefaultMarketplace() {
   return window['go']['main']['App']['InstallDefaultMarketplace']();
 }
@@ -94,6 +158,14 @@ export function LaunchTool(arg1, arg2, arg3, arg4, arg5, arg6, arg7) {
   return window['go']['main']['App']['LaunchTool'](arg1, arg2, arg3, arg4, arg5, arg6, arg7);
 }
 
+export function ListConfigHistory() {
+  return window['go']['main']['App']['ListConfigHistory']();
+}
+
+export function ListProviderModels(arg1, arg2) {
+  return window['go']['main']['App']['ListProviderModels'](arg1, arg2);
+}
+
 export function ListPythonEnvironments() {
   return window['go']['main']['App']['ListPythonEnvironments']();
 }
@@ -118,6 +190,18 @@ export function PackLog(arg1) {
   return window['go']['main']['App']['PackLog'](arg1);
 }
 
+export function PreviewApiKeySync(arg1) {
+  return window['go']['main']['App']['PreviewApiKeySync'](arg1);
+}
+
+export function PreviewConfigBundle(arg1, arg2) {
+  return window['go']['main']['App']['PreviewConfigBundle'](arg1, arg2);
+}
+
+export function ProbeApiKeys(arg1, arg2) {
+  return window['go']['main']['App']['ProbeApiKeys'](arg1, arg2);
+}
+
 export function ReadBBS() {
   return window['go']['main']['App']['ReadBBS']();
 }
@@ -134,10 +218,30 @@ export function RecoverCC() {
   return window['go']['main']['App']['RecoverCC']();
 }
 
+export function RefreshProviderCatalog() {
+  return window['go']['main']['App']['RefreshProviderCatalog']();
+}
+
+export function RenameCustomProvider(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RenameCustomProvider'](arg1, arg2, arg3);
+}
+
+export function ReorderCustomProviders(arg1, arg2) {
+  return window['go']['main']['App']['ReorderCustomProviders'](arg1, arg2);
+}
+
 export function ResizeWindow(arg1, arg2) {
   return window['go']['main']['App']['ResizeWindow'](arg1, arg2);
 }
 
+export function ResolveConfigDrift(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['ResolveConfigDrift'](arg1, arg2, arg3, arg4);
+}
+
+export function RevertConfig(arg1) {
+  return window['go']['main']['App']['RevertConfig'](arg1);
+}
+
 export function RunEnvironmentCheckCLI() {
   return window['go']['main']['App']['RunEnvironmentCheckCLI']();
 }
@@ -146,6 +250,14 @@ export function SaveConfig(arg1) {
   return window['go']['main']['App']['SaveConfig'](arg1);
 }
 
+export function SelectBundleExportPath() {
+  return window['go']['main']['App']['SelectBundleExportPath']();
+}
+
+export function SelectBundleFile() {
+  return window['go']['main']['App']['SelectBundleFile']();
+}
+
 export function SelectProjectDir() {
   return window['go']['main']['App']['SelectProjectDir']();
 }
@@ -154,6 +266,14 @@ export function SelectSkillFile() {
   return window['go']['main']['App']['SelectSkillFile']();
 }
 
+export function SetApiKeyExhausted(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['SetApiKeyExhausted'](arg1, arg2, arg3, arg4);
+}
+
+export function SetCustomProviderShared(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SetCustomProviderShared'](arg1, arg2, arg3);
+}
+
 export function SetEnvCheckInterval(arg1) {
   return window['go']['main']['App']['SetEnvCheckInterval'](arg1);
 }
@@ -162,6 +282,10 @@ export function SetLanguage(arg1) {
   return window['go']['main']['App']['SetLanguage'](arg1);
 }
 
+export function SetProviderKeySync(arg1, arg2) {
+  return window['go']['main']['App']['SetProviderKeySync'](arg1, arg2);
+}
+
 export function ShouldCheckEnvironment() {
   return window['go']['main']['App']['ShouldCheckEnvironment']();
 }
@@ -174,6 +298,14 @@ export function ShowMessage(arg1, arg2) {
   return window['go']['main']['App']['ShowMessage'](arg1, arg2);
 }
 
+export function TestProvider(arg1, arg2) {
+  return window['go']['main']['App']['TestProvider'](arg1, arg2);
+}
+
+export function TrustProjectFile(arg1) {
+  return window['go']['main']['App']['TrustProjectFile'](arg1);
+}
+
 export function UpdateLastEnvCheckTime() {
   return window['go']['main']['App']['UpdateLastEnvCheckTime']();
 }
@@ -1,5 +1,87 @@
 export namespace main {
 	
+	export class ApiKeyEntry {
+	    label: string;
+	    key: string;
+	    exhausted: boolean;
+	    exhausted_at?: string;
+	    reason?: string;
+	    last_used?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ApiKeyEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.label = source["label"];
+	        this.key = source["key"];
+	        this.exhausted = source["exhausted"];
+	        this.exhausted_at = source["exhausted_at"];
+	        this.reason = source["reason"];
+	        this.last_used = source["last_used"];
+	    }
+	}
+	export class ProviderTestResult {
+	    ok: boolean;
+	    kind: string;
+	    status: number;
+	    message: string;
+	    latency_ms: number;
+	    protocol: string;
+	    url: string;
+	    model: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProviderTestResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ok = source["ok"];
+	        this.kind = source["kind"];
+	        this.status = source["status"];
+	        this.message = source["message"];
+	        this.latency_ms = source["latency_ms"];
+	        this.protocol = source["protocol"];
+	        this.url = source["url"];
+	        this.model = source["model"];
+	    }
+	}
+	export class ApiKeyProbe {
+	    index: number;
+	    label: string;
+	    result: ProviderTestResult;
+	
+	    static createFrom(source: any = {}) {
+	        return new ApiKeyProbe(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.index = source["index"];
+	        this.label = source["label"];
+	        this.result = this.convertValues(source["result"], ProviderTestResult);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class ProjectConfig {
 	    id: string;
 	    name: string;
@@ -36,6 +118,46 @@ export namespace main {
 	        this.proxy_password = source["proxy_password"];
 	    }
 	}
+	export class ModelParams {
+	    context_window?: number;
+	    max_output_tokens?: number;
+	    max_thinking_tokens?: number;
+	    reasoning_effort?: string;
+	    request_max_retries?: number;
+	    stream_max_retries?: number;
+	    stream_idle_timeout_ms?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ModelParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.context_window = source["context_window"];
+	        this.max_output_tokens = source["max_output_tokens"];
+	        this.max_thinking_tokens = source["max_thinking_tokens"];
+	        this.reasoning_effort = source["reasoning_effort"];
+	        this.request_max_retries = source["request_max_retries"];
+	        this.stream_max_retries = source["stream_max_retries"];
+	        this.stream_idle_timeout_ms = source["stream_idle_timeout_ms"];
+	    }
+	}
+	export class ModelRoles {
+	    fast: string;
+	    reasoning: string;
+	    compact: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ModelRoles(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.fast = source["fast"];
+	        this.reasoning = source["reasoning"];
+	        this.compact = source["compact"];
+	    }
+	}
 	export class ModelConfig {
 	    model_name: string;
 	    model_id: string;
@@ -43,6 +165,13 @@ export namespace main {
 	    api_key: string;
 	    wire_api: string;
 	    is_custom: boolean;
+	    shared: boolean;
+	    roles: ModelRoles;
+	    params: ModelParams;
+	    api_keys?: ApiKeyEntry[];
+	    key_policy?: string;
+	    active_key: number;
+	    catalog_url?: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new ModelConfig(source);
@@ -56,7 +185,32 @@ export namespace main {
 	        this.api_key = source["api_key"];
 	        this.wire_api = source["wire_api"];
 	        this.is_custom = source["is_custom"];
+	        this.shared = source["shared"];
+	        this.roles = this.convertValues(source["roles"], ModelRoles);
+	        this.params = this.convertValues(source["params"], ModelParams);
+	        this.api_keys = this.convertValues(source["api_keys"], ApiKeyEntry);
+	        this.key_policy = source["key_policy"];
+	        this.active_key = source["active_key"];
+	        this.catalog_url = source["catalog_url"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class ToolConfig {
 	    current_model: string;
@@ -123,6 +277,12 @@ export namespace main {
 	    default_proxy_username: string;
 	    default_proxy_password: string;
 	    use_windows_terminal: boolean;
+	    provider_catalog_url: string;
+	    github_token?: string;
+	    schema_version: number;
+	    provider_key_sync?: Record<string, string>;
+	    trusted_project_files?: Record<string, string>;
+	    revision?: number;
 	
 	    static createFrom(source: any = {}) {
 	        return new AppConfig(source);
@@ -162,6 +322,66 @@ export namespace main {
 	        this.default_proxy_username = source["default_proxy_username"];
 	        this.default_proxy_password = source["default_proxy_password"];
 	        this.use_windows_terminal = source["use_windows_terminal"];
+	        this.provider_catalog_url = source["provider_catalog_url"];
+	        this.github_token = source["github_token"];
+	        this.schema_version = source["schema_version"];
+	        this.provider_key_sync = source["provider_key_sync"];
+	        this.trusted_project_files = source["trusted_project_files"];
+	        this.revision = source["revision"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class BundleChange {
+	    section: string;
+	    tool?: string;
+	    name: string;
+	    action: string;
+	    detail: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new BundleChange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.section = source["section"];
+	        this.tool = source["tool"];
+	        this.name = source["name"];
+	        this.action = source["action"];
+	        this.detail = source["detail"];
+	    }
+	}
+	export class BundlePreview {
+	    created_at: string;
+	    has_secrets: boolean;
+	    changes: BundleChange[];
+	
+	    static createFrom(source: any = {}) {
+	        return new BundlePreview(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.created_at = source["created_at"];
+	        this.has_secrets = source["has_secrets"];
+	        this.changes = this.convertValues(source["changes"], BundleChange);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -182,7 +402,380 @@ export namespace main {
 		    return a;
 		}
 	}
+	export class CatalogStatus {
+	    version: number;
+	    source: string;
+	    providers: number;
 	
+	    static createFrom(source: any = {}) {
+	        return new CatalogStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.version = source["version"];
+	        this.source = source["source"];
+	        this.providers = source["providers"];
+	    }
+	}
+	export class ConfigChange {
+	    path: string;
+	    old: string;
+	    new: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ConfigChange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.old = source["old"];
+	        this.new = source["new"];
+	    }
+	}
+	export class ConfigHistoryEntry {
+	    id: string;
+	    time: string;
+	    source: string;
+	    summary: string;
+	    changed: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ConfigHistoryEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.time = source["time"];
+	        this.source = source["source"];
+	        this.summary = source["summary"];
+	        this.changed = source["changed"];
+	    }
+	}
+	export class ConfigRecovery {
+	    error: string;
+	    backup: string;
+	    corrupt_copy: string;
+	    time: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ConfigRecovery(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.error = source["error"];
+	        this.backup = source["backup"];
+	        this.corrupt_copy = source["corrupt_copy"];
+	        this.time = source["time"];
+	    }
+	}
+	export class DriftItem {
+	    path: string[];
+	    key: string;
+	    ours: string;
+	    theirs: string;
+	    ours_set: boolean;
+	    theirs_set: boolean;
+	    field: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DriftItem(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.key = source["key"];
+	        this.ours = source["ours"];
+	        this.theirs = source["theirs"];
+	        this.ours_set = source["ours_set"];
+	        this.theirs_set = source["theirs_set"];
+	        this.field = source["field"];
+	    }
+	}
+	export class DriftFile {
+	    tool: string;
+	    path: string;
+	    items: DriftItem[];
+	
+	    static createFrom(source: any = {}) {
+	        return new DriftFile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.tool = source["tool"];
+	        this.path = source["path"];
+	        this.items = this.convertValues(source["items"], DriftItem);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class DriftReport {
+	    files: DriftFile[];
+	    errors: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new DriftReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.files = this.convertValues(source["files"], DriftFile);
+	        this.errors = source["errors"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class EffectiveSetting {
+	    name: string;
+	    value: string;
+	    source: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new EffectiveSetting(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.value = source["value"];
+	        this.source = source["source"];
+	    }
+	}
+	export class EffectiveProjectSettings {
+	    project_dir: string;
+	    file: string;
+	    trusted: boolean;
+	    error: string;
+	    settings: EffectiveSetting[];
+	
+	    static createFrom(source: any = {}) {
+	        return new EffectiveProjectSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.project_dir = source["project_dir"];
+	        this.file = source["file"];
+	        this.trusted = source["trusted"];
+	        this.error = source["error"];
+	        this.settings = this.convertValues(source["settings"], EffectiveSetting);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class FilePermission {
+	    path: string;
+	    tool: string;
+	    is_dir: boolean;
+	    mode: string;
+	    owner: string;
+	    want: string;
+	    too_open: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new FilePermission(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.tool = source["tool"];
+	        this.is_dir = source["is_dir"];
+	        this.mode = source["mode"];
+	        this.owner = source["owner"];
+	        this.want = source["want"];
+	        this.too_open = source["too_open"];
+	    }
+	}
+	export class ImportCandidate {
+	    tool: string;
+	    source: string;
+	    provider: string;
+	    known: boolean;
+	    base_url: string;
+	    model_id: string;
+	    wire_api: string;
+	    roles: ModelRoles;
+	    api_key: string;
+	    current: boolean;
+	    status: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportCandidate(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.tool = source["tool"];
+	        this.source = source["source"];
+	        this.provider = source["provider"];
+	        this.known = source["known"];
+	        this.base_url = source["base_url"];
+	        this.model_id = source["model_id"];
+	        this.wire_api = source["wire_api"];
+	        this.roles = this.convertValues(source["roles"], ModelRoles);
+	        this.api_key = source["api_key"];
+	        this.current = source["current"];
+	        this.status = source["status"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class KeySyncChange {
+	    provider: string;
+	    source_tool: string;
+	    tools: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new KeySyncChange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.provider = source["provider"];
+	        this.source_tool = source["source_tool"];
+	        this.tools = source["tools"];
+	    }
+	}
+	export class KeySyncConflict {
+	    provider: string;
+	    tools: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new KeySyncConflict(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.provider = source["provider"];
+	        this.tools = source["tools"];
+	    }
+	}
+	export class KeySyncPlan {
+	    changes: KeySyncChange[];
+	    conflicts: KeySyncConflict[];
+	
+	    static createFrom(source: any = {}) {
+	        return new KeySyncPlan(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.changes = this.convertValues(source["changes"], KeySyncChange);
+	        this.conflicts = this.convertValues(source["conflicts"], KeySyncConflict);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	
+	
+	export class ProviderModelList {
+	    models: string[];
+	    source: string;
+	    fetched_at: string;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProviderModelList(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.models = source["models"];
+	        this.source = source["source"];
+	        this.fetched_at = source["fetched_at"];
+	        this.error = source["error"];
+	    }
+	}
 	
 	export class PythonEnvironment {
 	    name: string;
@@ -200,6 +793,28 @@ export namespace main {
 	        this.type = source["type"];
 	    }
 	}
+	export class SecretStoreStatus {
+	    backend: string;
+	    keyring_available: boolean;
+	    secrets: number;
+	    plaintext: number;
+	    protection: string;
+	    protected: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SecretStoreStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.backend = source["backend"];
+	        this.keyring_available = source["keyring_available"];
+	        this.secrets = source["secrets"];
+	        this.plaintext = source["plaintext"];
+	        this.protection = source["protection"];
+	        this.protected = source["protected"];
+	    }
+	}
 	export class Skill {
 	    name: string;
 	    description: string;
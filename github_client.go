@@ -0,0 +1,225 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const githubAPIBase = "https://api.github.com"
+
+// Backoff after a rate-limited response that does not say when the limit resets.
+const (
+	githubMinBackoff = time.Minute
+	githubMaxBackoff = time.Hour
+)
+
+// githubCacheEntry is a cached response, revalidated with If-None-Match.
+type githubCacheEntry struct {
+	Url       string `json:"url"`
+	ETag      string `json:"etag"`
+	Body      []byte `json:"body"`
+	FetchedAt string `json:"fetched_at"`
+}
+
+// githubRateLimitError is returned while the API is rate limited and nothing is cached.
+type githubRateLimitError struct {
+	Reset time.Time
+}
+
+func (e *githubRateLimitError) Error() string {
+	return fmt.Sprintf("github api rate limit exceeded (resets at %s)", e.Reset.Format(time.RFC3339))
+}
+
+// githubStatusError is returned for any other unexpected status.
+type githubStatusError struct {
+	Code   int
+	Status string
+}
+
+func (e *githubStatusError) Error() string {
+	return "github api returned " + e.Status
+}
+
+// githubClient is the one client for GitHub API requests. Responses are cached on disk
+// and revalidated with conditional requests, which GitHub does not count against the
+// rate limit. Once limited, it stops calling the API until the limit resets and serves
+// the cache meanwhile. No credential is built in: the token comes from the caller.
+type githubClient struct {
+	http     *http.Client
+	baseUrl  string
+	cacheDir string
+	token    func() string
+	logf     func(string)
+	now      func() time.Time
+
+	mu           sync.Mutex
+	blockedUntil time.Time
+	backoff      time.Duration
+}
+
+func newGitHubClient(baseUrl, cacheDir string, token func() string, logf func(string)) *githubClient {
+	return &githubClient{
+		http:     &http.Client{Timeout: 15 * time.Second},
+		baseUrl:  baseUrl,
+		cacheDir: cacheDir,
+		token:    token,
+		logf:     logf,
+		now:      time.Now,
+	}
+}
+
+// githubAPI returns the App's GitHub client. The token is the one saved in the
+// config, i.e. the secret store, or else GITHUB_TOKEN.
+func (a *App) githubAPI() *githubClient {
+	a.githubOnce.Do(func() {
+		if a.github != nil {
+			return
+		}
+		cacheDir := filepath.Join(a.GetUserHomeDir(), ".cceasy", "github_cache")
+		a.github = newGitHubClient(githubAPIBase, cacheDir, a.githubToken, a.log)
+	})
+	return a.github
+}
+
+func (a *App) githubToken() string {
+	if config, err := a.LoadConfig(); err == nil && config.GitHubToken != "" {
+		return config.GitHubToken
+	}
+	return os.Getenv("GITHUB_TOKEN")
+}
+
+func (c *githubClient) cachePath(url, accept string) string {
+	sum := sha256.Sum256([]byte(accept + " " + url))
+	return filepath.Join(c.cacheDir, hex.EncodeToString(sum[:12])+".json")
+}
+
+func (c *githubClient) readCache(url, accept string) *githubCacheEntry {
+	data, err := os.ReadFile(c.cachePath(url, accept))
+	if err != nil {
+		return nil
+	}
+	var entry githubCacheEntry
+	if json.Unmarshal(data, &entry) != nil || entry.Url != url {
+		return nil
+	}
+	return &entry
+}
+
+func (c *githubClient) writeCache(url, accept string, entry githubCacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(c.cacheDir, 0755); err != nil {
+		return
+	}
+	// Replaced atomically, as two requests for the same URL may finish together
+	if err := atomicWriteFile(c.cachePath(url, accept), data, 0644); err != nil {
+		c.logf("GitHub: cache: " + err.Error())
+	}
+}
+
+// get fetches an API path such as /repos/o/r/releases/latest with the given Accept
+// header. While rate limited, or when the request fails, a cached copy is returned
+// if there is one.
+func (c *githubClient) get(path, accept string) ([]byte, error) {
+	url := c.baseUrl + path
+	cached := c.readCache(url, accept)
+
+	c.mu.Lock()
+	blockedUntil := c.blockedUntil
+	c.mu.Unlock()
+	if c.now().Before(blockedUntil) {
+		if cached != nil {
+			return cached.Body, nil
+		}
+		return nil, &githubRateLimitError{Reset: blockedUntil}
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "AICoder-App")
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if token := c.token(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if cached != nil && cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if cached != nil {
+			c.logf("GitHub: " + err.Error() + ", using cached copy")
+			return cached.Body, nil
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		c.noteRateLimit(resp, false)
+		return cached.Body, nil
+	case resp.StatusCode == http.StatusOK:
+		c.noteRateLimit(resp, false)
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+		if err != nil {
+			return nil, err
+		}
+		if etag := resp.Header.Get("ETag"); etag != "" {
+			c.writeCache(url, accept, githubCacheEntry{Url: url, ETag: etag, Body: body, FetchedAt: c.now().Format(time.RFC3339)})
+		}
+		return body, nil
+	case resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusForbidden && (resp.Header.Get("X-RateLimit-Remaining") == "0" || resp.Header.Get("Retry-After") != "")):
+		reset := c.noteRateLimit(resp, true)
+		c.logf(fmt.Sprintf("GitHub: rate limited until %s", reset.Format(time.RFC3339)))
+		if cached != nil {
+			return cached.Body, nil
+		}
+		return nil, &githubRateLimitError{Reset: reset}
+	}
+	return nil, &githubStatusError{Code: resp.StatusCode, Status: resp.Status}
+}
+
+// noteRateLimit records when requests may resume. A response with no quota left blocks
+// until X-RateLimit-Reset; a limited one without a reset time backs off exponentially.
+func (c *githubClient) noteRateLimit(resp *http.Response, limited bool) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var until time.Time
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && limited {
+		until = c.now().Add(time.Duration(secs) * time.Second)
+	} else if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			until = time.Unix(reset, 0)
+		}
+	}
+	if !limited {
+		c.backoff = 0
+		c.blockedUntil = until
+		return until
+	}
+	if until.IsZero() || !until.After(c.now()) {
+		if c.backoff == 0 {
+			c.backoff = githubMinBackoff
+		} else {
+			c.backoff = min(c.backoff*2, githubMaxBackoff)
+		}
+		until = c.now().Add(c.backoff)
+	}
+	c.blockedUntil = until
+	return until
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestGitHubClient returns a client for srv with a clock the test moves.
+func newTestGitHubClient(t *testing.T, srv *httptest.Server, token string) (*githubClient, *time.Time) {
+	t.Helper()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := newGitHubClient(srv.URL, t.TempDir(), func() string { return token }, func(s string) { t.Log(s) })
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestGitHubClientRevalidatesWithETag(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("Authorization") != "Bearer gh-token" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		if r.Header.Get("Accept") != "application/vnd.github+json" {
+			t.Errorf("Accept = %q", r.Header.Get("Accept"))
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"tag_name":"v1"}`))
+	}))
+	defer srv.Close()
+	c, _ := newTestGitHubClient(t, srv, "gh-token")
+
+	for i := 0; i < 2; i++ {
+		body, err := c.get("/repos/o/r/releases/latest", "application/vnd.github+json")
+		if err != nil || string(body) != `{"tag_name":"v1"}` {
+			t.Fatalf("get %d = %q, %v", i, body, err)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Fatalf("%d requests, want 2", n)
+	}
+	if c.readCache(srv.URL+"/repos/o/r/releases/latest", "application/vnd.github+json") == nil {
+		t.Fatal("response not cached")
+	}
+}
+
+func TestGitHubClientWaitsForRateLimitReset(t *testing.T) {
+	var requests int32
+	var reset time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+	c, now := newTestGitHubClient(t, srv, "")
+	reset = now.Add(30 * time.Minute)
+
+	_, err := c.get("/x", "application/json")
+	var limited *githubRateLimitError
+	if !errors.As(err, &limited) || !limited.Reset.Equal(reset) {
+		t.Fatalf("err = %v, want rate limit until %s", err, reset)
+	}
+	// No requests until the reset; a cached copy is served meanwhile
+	c.writeCache(srv.URL+"/cached", "application/json", githubCacheEntry{Url: srv.URL + "/cached", ETag: "e", Body: []byte("old")})
+	if body, err := c.get("/cached", "application/json"); err != nil || string(body) != "old" {
+		t.Fatalf("cached get = %q, %v", body, err)
+	}
+	if _, err := c.get("/x", "application/json"); !errors.As(err, &limited) {
+		t.Fatalf("err = %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("%d requests while limited, want 1", n)
+	}
+	*now = reset.Add(time.Second)
+	c.get("/x", "application/json")
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Fatalf("%d requests after the reset, want 2", n)
+	}
+}
+
+func TestGitHubClientBacksOffWithoutResetTime(t *testing.T) {
+	var requests int32
+	var limited atomic.Bool
+	limited.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if limited.Load() {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	c, now := newTestGitHubClient(t, srv, "")
+	start := *now
+
+	var rateErr *githubRateLimitError
+	if _, err := c.get("/x", "application/json"); !errors.As(err, &rateErr) || !rateErr.Reset.Equal(start.Add(githubMinBackoff)) {
+		t.Fatalf("first limit: %v", err)
+	}
+	*now = start.Add(githubMinBackoff + time.Second)
+	if _, err := c.get("/x", "application/json"); !errors.As(err, &rateErr) || !rateErr.Reset.Equal(now.Add(2*githubMinBackoff)) {
+		t.Fatalf("second limit should double the backoff: %v", err)
+	}
+	*now = now.Add(2*githubMinBackoff + time.Second)
+	limited.Store(false)
+	if body, err := c.get("/x", "application/json"); err != nil || string(body) != "ok" {
+		t.Fatalf("after backoff = %q, %v", body, err)
+	}
+	if c.backoff != 0 {
+		t.Fatalf("backoff not reset: %v", c.backoff)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Fatalf("%d requests, want 3", n)
+	}
+}
+
+func TestGitHubClientFallsBackToCache(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("body"))
+	}))
+	c, _ := newTestGitHubClient(t, srv, "")
+	if _, err := c.get("/x", "application/json"); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+	if body, err := c.get("/x", "application/json"); err != nil || string(body) != "body" {
+		t.Fatalf("offline get = %q, %v", body, err)
+	}
+	if _, err := c.get("/uncached", "application/json"); err == nil {
+		t.Fatal("offline get without cache succeeded")
+	}
+}
+
+func TestGitHubClientStatusError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+	c, _ := newTestGitHubClient(t, srv, "")
+	var status *githubStatusError
+	if _, err := c.get("/x", "application/json"); !errors.As(err, &status) || status.Code != http.StatusNotFound {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// orderedJSON is a JSON object that remembers its key order, so config files
+// owned partly by other tools can be edited without reshuffling them.
+// Values are kept raw; only the keys we touch are re-encoded.
+type orderedJSON struct {
+	keys   []string
+	values map[string]json.RawMessage
+}
+
+func newOrderedJSON() *orderedJSON {
+	return &orderedJSON{values: make(map[string]json.RawMessage)}
+}
+
+func parseOrderedJSON(data []byte) (*orderedJSON, error) {
+	o := newOrderedJSON()
+	if len(bytes.TrimSpace(data)) == 0 {
+		return o, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected an object key")
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		if _, exists := o.values[key]; !exists {
+			o.keys = append(o.keys, key)
+		}
+		o.values[key] = raw
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// readOrderedJSONFile returns an empty object for a missing file and an error for an unparseable one.
+func readOrderedJSONFile(path string) (*orderedJSON, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return newOrderedJSON(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	o, err := parseOrderedJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not valid JSON: %v", path, err)
+	}
+	return o, nil
+}
+
+func (o *orderedJSON) has(key string) bool {
+	_, ok := o.values[key]
+	return ok
+}
+
+// get decodes the value of key into v and reports whether the key was present and decodable.
+func (o *orderedJSON) get(key string, v interface{}) bool {
+	raw, ok := o.values[key]
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(raw, v) == nil
+}
+
+// set replaces the value of key in place, or appends the key if it is new.
+func (o *orderedJSON) set(key string, v interface{}) error {
+	raw, err := encodeJSONValue(v)
+	if err != nil {
+		return err
+	}
+	if _, exists := o.values[key]; !exists {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = raw
+	return nil
+}
+
+func (o *orderedJSON) remove(key string) {
+	if _, exists := o.values[key]; !exists {
+		return
+	}
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// object returns the nested object stored under key, or an empty one if the key
+// is missing or holds something other than an object.
+func (o *orderedJSON) object(key string) *orderedJSON {
+	if raw, ok := o.values[key]; ok {
+		if child, err := parseOrderedJSON(raw); err == nil {
+			return child
+		}
+	}
+	return newOrderedJSON()
+}
+
+func (o *orderedJSON) len() int {
+	return len(o.keys)
+}
+
+func (o *orderedJSON) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := encodeJSONValue(k)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(o.values[k])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// marshalIndent renders the object with the two-space indent we use for every config file.
+func (o *orderedJSON) marshalIndent() ([]byte, error) {
+	compact, err := o.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, compact, "", "  "); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// encodeJSONValue marshals without HTML escaping, so URLs with & stay readable.
+func encodeJSONValue(v interface{}) (json.RawMessage, error) {
+	if o, ok := v.(*orderedJSON); ok {
+		return o.MarshalJSON()
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return json.RawMessage(bytes.TrimRight(buf.Bytes(), "\n")), nil
+}
+
+// writeOrderedJSONFile writes the object unless the file already has exactly that content.
+func writeOrderedJSONFile(path string, o *orderedJSON) error {
+	data, err := o.marshalIndent()
+	if err != nil {
+		return err
+	}
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		return nil
+	}
+	// Claude's settings.json carries the API key in its env block
+	return writePrivateFile(path, data)
+}
+
+func isJSONArray(raw json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// jsonPathValue returns the value at path inside raw. Object members are matched
+// by key and array elements by their decimal index.
+func jsonPathValue(raw json.RawMessage, path []string) (json.RawMessage, bool) {
+	for _, p := range path {
+		if isJSONArray(raw) {
+			var elems []json.RawMessage
+			i, err := strconv.Atoi(p)
+			if err != nil || json.Unmarshal(raw, &elems) != nil || i < 0 || i >= len(elems) {
+				return nil, false
+			}
+			raw = elems[i]
+			continue
+		}
+		o, err := parseOrderedJSON(raw)
+		if err != nil || !o.has(p) {
+			return nil, false
+		}
+		raw = o.values[p]
+	}
+	return raw, true
+}
+
+// setJSONPath returns raw with the value at path replaced by value. Objects missing
+// along the path are created; array elements must already exist.
+func setJSONPath(raw json.RawMessage, path []string, value json.RawMessage) (json.RawMessage, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	if isJSONArray(raw) {
+		var elems []json.RawMessage
+		if err := json.Unmarshal(raw, &elems); err != nil {
+			return nil, err
+		}
+		i, err := strconv.Atoi(path[0])
+		if err != nil || i < 0 || i >= len(elems) {
+			return nil, fmt.Errorf("no array element %q", path[0])
+		}
+		if elems[i], err = setJSONPath(elems[i], path[1:], value); err != nil {
+			return nil, err
+		}
+		return encodeJSONValue(elems)
+	}
+	o, err := parseOrderedJSON(raw)
+	if err != nil {
+		return nil, err
+	}
+	child, err := setJSONPath(o.values[path[0]], path[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.set(path[0], child); err != nil {
+		return nil, err
+	}
+	return o.MarshalJSON()
+}
+
+// removeJSONPath returns raw without the value at path. A missing path is not an error.
+func removeJSONPath(raw json.RawMessage, path []string) (json.RawMessage, error) {
+	if len(path) == 0 {
+		return raw, nil
+	}
+	if isJSONArray(raw) {
+		var elems []json.RawMessage
+		if err := json.Unmarshal(raw, &elems); err != nil {
+			return nil, err
+		}
+		i, err := strconv.Atoi(path[0])
+		if err != nil || i < 0 || i >= len(elems) {
+			return raw, nil
+		}
+		if len(path) == 1 {
+			elems = append(elems[:i], elems[i+1:]...)
+		} else if elems[i], err = removeJSONPath(elems[i], path[1:]); err != nil {
+			return nil, err
+		}
+		return encodeJSONValue(elems)
+	}
+	o, err := parseOrderedJSON(raw)
+	if err != nil {
+		return nil, err
+	}
+	if !o.has(path[0]) {
+		return raw, nil
+	}
+	if len(path) == 1 {
+		o.remove(path[0])
+	} else {
+		child, err := removeJSONPath(o.values[path[0]], path[1:])
+		if err != nil {
+			return nil, err
+		}
+		o.values[path[0]] = child
+	}
+	return o.MarshalJSON()
+}
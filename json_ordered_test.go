@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOrderedJSONRoundTrip(t *testing.T) {
+	data := `{
+  "zeta": 1,
+  "hooks": {
+    "PreToolUse": [
+      {
+        "matcher": "Bash"
+      }
+    ]
+  },
+  "alpha": "a & b",
+  "env": {
+    "Z_KEY": "z",
+    "A_KEY": "a"
+  },
+  "n": 1.50
+}`
+	o, err := parseOrderedJSON([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := o.marshalIndent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Values we do not touch keep their exact source text
+	if string(out) != data {
+		t.Fatalf("round trip changed\n%s\nto\n%s", data, out)
+	}
+}
+
+func TestOrderedJSONEdits(t *testing.T) {
+	o, err := parseOrderedJSON([]byte(`{"b":1,"env":{"Z":"z","A":"a"},"a":2,"unknown":{"deep":[1,{"x":null}]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := o.object("env")
+	env.set("A", "changed")
+	env.set("NEW", "https://x?a=1&b=2")
+	env.remove("Z")
+	env.remove("missing")
+	if err := o.set("env", env); err != nil {
+		t.Fatal(err)
+	}
+	o.set("b", 3)
+	o.set("added", true)
+	o.remove("a")
+	out, err := o.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"b":3,"env":{"A":"changed","NEW":"https://x?a=1&b=2"},"unknown":{"deep":[1,{"x":null}]},"added":true}`
+	if string(out) != want {
+		t.Fatalf("got  %s\nwant %s", out, want)
+	}
+	var n int
+	if !o.get("b", &n) || n != 3 || o.get("a", &n) || o.get("env", &n) {
+		t.Fatal("get does not report presence and type")
+	}
+	if o.object("b").len() != 0 || o.object("missing").len() != 0 {
+		t.Fatal("non-object values read as objects")
+	}
+}
+
+func TestParseOrderedJSONRejects(t *testing.T) {
+	for _, data := range []string{`[1]`, `"s"`, `{"a":}`, `{"a":1`} {
+		if _, err := parseOrderedJSON([]byte(data)); err == nil {
+			t.Errorf("%s parsed", data)
+		}
+	}
+	if o, err := parseOrderedJSON([]byte(" \n")); err != nil || o.len() != 0 {
+		t.Fatalf("empty file = %v, %v", o, err)
+	}
+	if strings.Contains(string(mustMarshalOrdered(t, `{"a":1,"a":2}`)), `"a":1`) {
+		t.Fatal("duplicate key kept its first value")
+	}
+}
+
+func mustMarshalOrdered(t *testing.T, data string) []byte {
+	t.Helper()
+	o, err := parseOrderedJSON([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := o.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
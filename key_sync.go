@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KeySyncPlan describes what saving a config would do to API keys in other tools.
+type KeySyncPlan struct {
+	Changes   []KeySyncChange   `json:"changes"`
+	Conflicts []KeySyncConflict `json:"conflicts"`
+}
+
+// KeySyncChange is a provider key edited in one tool that will be copied to others.
+type KeySyncChange struct {
+	Provider   string   `json:"provider"`
+	SourceTool string   `json:"source_tool"`
+	Tools      []string `json:"tools"` // Tools whose key would be overwritten
+	key        string
+}
+
+// KeySyncConflict is a provider whose key was changed to different values in
+// several tools in the same save. None of them is copied anywhere.
+type KeySyncConflict struct {
+	Provider string   `json:"provider"`
+	Tools    []string `json:"tools"`
+}
+
+// providerKeySync returns a provider's sync policy, "shared" or "per_tool".
+func providerKeySync(config *AppConfig, providerName string) string {
+	if config.ProviderKeySync[strings.ToLower(providerName)] == "per_tool" {
+		return "per_tool"
+	}
+	return "shared"
+}
+
+// keySharedAcrossTools reports whether a provider's API key is kept the same in
+// every tool: catalog providers by name unless set to per_tool, custom providers
+// only when shared.
+func keySharedAcrossTools(config *AppConfig, m ModelConfig) bool {
+	if strings.EqualFold(m.ModelName, "Original") {
+		return false
+	}
+	if providerKeySync(config, m.ModelName) == "per_tool" {
+		return false
+	}
+	if m.IsCustom {
+		return m.Shared
+	}
+	return !strings.EqualFold(m.ModelName, "Custom")
+}
+
+// planApiKeySync works out which tools get which keys when newConfig replaces
+// oldConfig, without changing either.
+func planApiKeySync(oldConfig, newConfig *AppConfig) KeySyncPlan {
+	type edit struct {
+		tool, name, key string
+	}
+	// providerName (lower) -> edits made in this save, in tool order
+	edits := make(map[string][]edit)
+	var order []string
+	for _, tool := range toolNames {
+		oldTool := getToolConfig(oldConfig, tool)
+		for _, m := range getToolConfig(newConfig, tool).Models {
+			// A key list rotates its active key on its own, which is not an edit to
+			// copy; it still receives keys edited elsewhere in its active entry
+			if !keySharedAcrossTools(newConfig, m) || len(m.ApiKeys) > 0 {
+				continue
+			}
+			oldM := getProviderModel(oldTool, m.ModelName)
+			// If key changed or a new key was added where none existed
+			if (oldM != nil && m.ApiKey != oldM.ApiKey) || (oldM == nil && m.ApiKey != "") {
+				lower := strings.ToLower(m.ModelName)
+				if _, seen := edits[lower]; !seen {
+					order = append(order, lower)
+				}
+				edits[lower] = append(edits[lower], edit{tool, m.ModelName, m.ApiKey})
+			}
+		}
+	}
+	plan := KeySyncPlan{Changes: []KeySyncChange{}, Conflicts: []KeySyncConflict{}}
+	for _, lower := range order {
+		es := edits[lower]
+		conflict := false
+		for _, e := range es[1:] {
+			if e.key != es[0].key {
+				conflict = true
+			}
+		}
+		if conflict {
+			c := KeySyncConflict{Provider: es[0].name}
+			for _, e := range es {
+				c.Tools = append(c.Tools, e.tool)
+			}
+			plan.Conflicts = append(plan.Conflicts, c)
+			continue
+		}
+		change := KeySyncChange{Provider: es[0].name, SourceTool: es[0].tool, key: es[0].key}
+		for _, tool := range toolNames {
+			m := getProviderModel(getToolConfig(newConfig, tool), es[0].name)
+			if m != nil && keySharedAcrossTools(newConfig, *m) && m.ApiKey != es[0].key {
+				change.Tools = append(change.Tools, tool)
+			}
+		}
+		if len(change.Tools) > 0 {
+			plan.Changes = append(plan.Changes, change)
+		}
+	}
+	return plan
+}
+
+// PreviewApiKeySync reports which tools' API keys SaveConfig would change for config,
+// and which providers conflict, without saving anything.
+func (a *App) PreviewApiKeySync(config AppConfig) (KeySyncPlan, error) {
+	oldConfig, err := a.LoadConfig()
+	if err != nil {
+		return KeySyncPlan{}, err
+	}
+	return planApiKeySync(&oldConfig, &config), nil
+}
+
+// GetProviderKeySync returns the sync policy of every provider that has one set.
+func (a *App) GetProviderKeySync() (map[string]string, error) {
+	config, err := a.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	policies := make(map[string]string)
+	for name := range config.ProviderKeySync {
+		policies[name] = providerKeySync(&config, name)
+	}
+	return policies, nil
+}
+
+// SetProviderKeySync sets whether a provider's API key is shared by all tools or kept
+// per tool. Switching back to shared does not touch existing keys; the next edit is copied.
+func (a *App) SetProviderKeySync(providerName string, policy string) (AppConfig, error) {
+	providerName = strings.ToLower(strings.TrimSpace(providerName))
+	if providerName == "" {
+		return AppConfig{}, fmt.Errorf("provider name must not be empty")
+	}
+	if policy != "shared" && policy != "per_tool" {
+		return AppConfig{}, fmt.Errorf("unknown sync policy %q", policy)
+	}
+	return a.modifyConfig(func(config *AppConfig) error {
+		if policy == "shared" {
+			delete(config.ProviderKeySync, providerName)
+			return nil
+		}
+		if config.ProviderKeySync == nil {
+			config.ProviderKeySync = make(map[string]string)
+		}
+		config.ProviderKeySync[providerName] = policy
+		return nil
+	})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func keySyncTestConfig() AppConfig {
+	config := secretTestConfig()
+	config.Claude = ToolConfig{CurrentModel: "Kimi", Models: []ModelConfig{{ModelName: "Original"}, {ModelName: "Kimi", ApiKey: "sk-old"}, {ModelName: "Relay", IsCustom: true, Shared: true, ModelUrl: "https://relay.example", ApiKey: "sk-relay"}}}
+	config.Codex = ToolConfig{CurrentModel: "Kimi", Models: []ModelConfig{{ModelName: "Original"}, {ModelName: "Kimi", ApiKey: "sk-old"}, {ModelName: "Relay", IsCustom: true, Shared: true, ModelUrl: "https://relay.example", ApiKey: "sk-relay"}}}
+	config.Opencode = ToolConfig{CurrentModel: "Kimi", Models: []ModelConfig{{ModelName: "Original"}, {ModelName: "kimi", ApiKey: "sk-old"}, {ModelName: "Relay", IsCustom: true, ModelUrl: "https://relay.example", ApiKey: "sk-mine"}}}
+	return config
+}
+
+func TestPlanApiKeySync(t *testing.T) {
+	old := keySyncTestConfig()
+	next := keySyncTestConfig()
+	getProviderModel(&next.Codex, "Kimi").ApiKey = "sk-new"
+	getProviderModel(&next.Claude, "Relay").ApiKey = "sk-relay-2"
+	plan := planApiKeySync(&old, &next)
+	if len(plan.Conflicts) != 0 || len(plan.Changes) != 2 {
+		t.Fatalf("plan = %+v", plan)
+	}
+	// Changes come in the order of the tool first edited
+	relay, kimi := plan.Changes[0], plan.Changes[1]
+	if kimi.Provider != "Kimi" || kimi.SourceTool != "codex" || strings.Join(kimi.Tools, ",") != "claude,opencode" {
+		t.Fatalf("Kimi change = %+v", kimi)
+	}
+	// The opencode Relay is not shared and keeps its own key
+	if relay.Provider != "Relay" || relay.SourceTool != "claude" || strings.Join(relay.Tools, ",") != "codex" {
+		t.Fatalf("Relay change = %+v", relay)
+	}
+
+	next.ProviderKeySync = map[string]string{"kimi": "per_tool"}
+	if plan := planApiKeySync(&old, &next); len(plan.Changes) != 1 || plan.Changes[0].Provider != "Relay" {
+		t.Fatalf("per_tool provider synced: %+v", plan)
+	}
+}
+
+func TestPlanApiKeySyncConflict(t *testing.T) {
+	old := keySyncTestConfig()
+	next := keySyncTestConfig()
+	getProviderModel(&next.Claude, "Kimi").ApiKey = "sk-a"
+	getProviderModel(&next.Opencode, "Kimi").ApiKey = "sk-b"
+	plan := planApiKeySync(&old, &next)
+	if len(plan.Changes) != 0 || len(plan.Conflicts) != 1 || strings.Join(plan.Conflicts[0].Tools, ",") != "claude,opencode" {
+		t.Fatalf("plan = %+v", plan)
+	}
+	// The same new key in two tools is not a conflict
+	getProviderModel(&next.Opencode, "Kimi").ApiKey = "sk-a"
+	if plan := planApiKeySync(&old, &next); len(plan.Conflicts) != 0 || len(plan.Changes) != 1 || strings.Join(plan.Changes[0].Tools, ",") != "codex" {
+		t.Fatalf("plan = %+v", plan)
+	}
+}
+
+func TestKeySyncUpdatesActiveKeyOfKeyList(t *testing.T) {
+	a := newSecretTestApp(t, newFakeSecretBackend("fake"))
+	t.Setenv("HOME", a.testHomeDir)
+	config := keySyncTestConfig()
+	list := getProviderModel(&config.Codex, "Kimi")
+	list.ApiKeys = []ApiKeyEntry{{Label: "personal", Key: "sk-personal"}, {Label: "team", Key: "sk-old", Exhausted: true, Reason: "quota"}}
+	list.ActiveKey = 1
+	normalizeApiKeys(list)
+	path, _ := a.getConfigPath()
+	if err := a.saveToPath(path, config); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.modifyConfig(func(c *AppConfig) error {
+		getProviderModel(&c.Claude, "Kimi").ApiKey = "sk-new"
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// Read back from disk, where normalizing takes the key from the active entry
+	saved, err := a.readConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := getProviderModel(&saved.Codex, "Kimi")
+	if m.ApiKey != "sk-new" || m.ActiveKey != 1 || len(m.ApiKeys) != 2 {
+		t.Fatalf("codex Kimi = %q, active %d, %+v", m.ApiKey, m.ActiveKey, m.ApiKeys)
+	}
+	if k := m.ApiKeys[1]; k.Key != "sk-new" || k.Label != "team" || k.Exhausted {
+		t.Fatalf("active entry = %+v", k)
+	}
+	if m.ApiKeys[0].Key != "sk-personal" {
+		t.Fatalf("other entry changed: %+v", m.ApiKeys[0])
+	}
+	if m := getProviderModel(&saved.Opencode, "Kimi"); m.ApiKey != "sk-new" {
+		t.Fatalf("opencode Kimi = %q", m.ApiKey)
+	}
+
+	// Rotating the key list is not an edit that is copied elsewhere
+	if _, err := a.modifyConfig(func(c *AppConfig) error {
+		m := getProviderModel(&c.Codex, "Kimi")
+		m.ActiveKey = 0
+		m.ApiKey = m.ApiKeys[0].Key
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	saved, _ = a.readConfig()
+	if m := getProviderModel(&saved.Claude, "Kimi"); m.ApiKey != "sk-new" {
+		t.Fatalf("key list rotation copied to claude: %q", m.ApiKey)
+	}
+}
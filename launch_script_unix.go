@@ -0,0 +1,129 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// launchEnvTimeout is how long the environment waits for the terminal to pick it up.
+var launchEnvTimeout = 2 * time.Minute
+
+var shellNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// privateRuntimeDir returns a directory only this user can enter: $XDG_RUNTIME_DIR
+// when it is ours and private, otherwise the temp directory, which on macOS is
+// already per-user.
+func privateRuntimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() && info.Mode().Perm()&0077 == 0 {
+			if st, ok := info.Sys().(*syscall.Stat_t); !ok || int(st.Uid) == os.Getuid() {
+				return dir
+			}
+		}
+	}
+	return os.TempDir()
+}
+
+// writeLaunchScript writes a script that starts command in projectDir with env set.
+// The script and a named pipe live in a fresh 0700 directory. The environment, which
+// carries API keys and proxy passwords, is handed over once through the pipe and never
+// written to disk; the script deletes itself and the directory as soon as it has read it.
+func (a *App) writeLaunchScript(projectDir string, env map[string]string, pathPrefix string, command []string, pauseAtEnd bool) (string, error) {
+	dir, err := os.MkdirTemp(privateRuntimeDir(), "aicoder-launch-")
+	if err != nil {
+		return "", err
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	fifoPath := filepath.Join(dir, "env")
+	if err := syscall.Mkfifo(fifoPath, 0600); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		if !shellNamePattern.MatchString(k) {
+			a.log(fmt.Sprintf("Launch: skipped environment variable with invalid name %q", k))
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var exports strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&exports, "export %s=%s\n", k, shellQuote(env[k]))
+	}
+
+	quoted := make([]string, len(command))
+	for i, arg := range command {
+		quoted[i] = shellQuote(arg)
+	}
+	var script strings.Builder
+	script.WriteString("#!/bin/bash\n")
+	script.WriteString("rm -f -- \"$0\"\n")
+	fmt.Fprintf(&script, "aicoder_env=\"$(cat -- %s)\" || { echo 'Launch settings expired, please launch again from AICoder.'; read; exit 1; }\n", shellQuote(fifoPath))
+	fmt.Fprintf(&script, "rm -rf -- %s\n", shellQuote(dir))
+	script.WriteString("eval \"$aicoder_env\"\nunset aicoder_env\n")
+	fmt.Fprintf(&script, "cd -- %s || exit 1\n", shellQuote(projectDir))
+	if pathPrefix != "" {
+		fmt.Fprintf(&script, "export PATH=%s:\"$PATH\"\n", shellQuote(pathPrefix))
+	}
+	script.WriteString(strings.Join(quoted, " ") + "\n")
+	if pauseAtEnd {
+		script.WriteString("echo 'Press Enter to close...'\nread\n")
+	}
+	scriptPath := filepath.Join(dir, "launch.sh")
+	if err := os.WriteFile(scriptPath, []byte(script.String()), 0600); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+
+	go a.serveLaunchEnv(dir, fifoPath, exports.String(), launchEnvTimeout)
+	return scriptPath, nil
+}
+
+// serveLaunchEnv writes the environment into the pipe once the script opens it,
+// or removes everything if no terminal comes for it within timeout.
+func (a *App) serveLaunchEnv(dir, fifoPath, content string, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for {
+		// Non-blocking open fails with ENXIO until the reader is there
+		fd, err := syscall.Open(fifoPath, syscall.O_WRONLY|syscall.O_NONBLOCK, 0)
+		if err == nil {
+			syscall.SetNonblock(fd, false)
+			f := os.NewFile(uintptr(fd), fifoPath)
+			f.WriteString(content)
+			f.Close()
+			return
+		}
+		if err != syscall.ENXIO || time.Now().After(deadline) {
+			switch {
+			case err == syscall.ENXIO:
+				// The script tells the user when its terminal starts after all
+				a.log(fmt.Sprintf("Launch: the terminal did not start within %s, its launch settings expired", timeout))
+			case !os.IsNotExist(err):
+				a.log("Launch: could not hand over the environment: " + err.Error())
+			}
+			os.RemoveAll(dir)
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
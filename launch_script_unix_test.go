@@ -0,0 +1,82 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShellQuote(t *testing.T) {
+	for _, s := range []string{"", "plain", "it's", `"$HOME" $(id) ` + "`id`", "a\nb", `back\slash`, "'''"} {
+		out, err := exec.Command("/bin/bash", "-c", "printf %s "+shellQuote(s)).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != s {
+			t.Errorf("shellQuote(%q) reads back as %q", s, out)
+		}
+	}
+}
+
+// The environment reaches the command through the pipe, and nothing of it is left on disk.
+func TestWriteLaunchScriptHandsOverEnv(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	a := NewApp()
+	projectDir := t.TempDir()
+	secret := `sk-it's "$HOME" $(id)`
+	env := map[string]string{"AICODER_TEST_KEY": secret, "NOT-A-NAME": "x"}
+	command := []string{"/bin/bash", "-c", `printf '%s\n' "$AICODER_TEST_KEY" "$PWD" "${PATH%%:*}" "${NOT_A_NAME-unset}"`}
+	scriptPath, err := a.writeLaunchScript(projectDir, env, "/opt/tools bin", command, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := os.ReadFile(scriptPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(script), "sk-it") {
+		t.Fatal("secret written into the script")
+	}
+	if info, _ := os.Stat(scriptPath); info.Mode().Perm() != 0600 {
+		t.Fatalf("script mode %v", info.Mode().Perm())
+	}
+
+	cmd := exec.Command("/bin/bash", scriptPath)
+	cmd.Env = launchEnviron(nil)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	realProjectDir, _ := filepath.EvalSymlinks(projectDir)
+	want := strings.Join([]string{secret, realProjectDir, "/opt/tools bin", "unset"}, "\n") + "\n"
+	if string(out) != want {
+		t.Fatalf("output\n%s\nwant\n%s", out, want)
+	}
+	if _, err := os.Stat(scriptPath); !os.IsNotExist(err) {
+		t.Fatal("launch directory left behind")
+	}
+}
+
+func TestLaunchEnvExpires(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	timeout := launchEnvTimeout
+	launchEnvTimeout = 200 * time.Millisecond
+	defer func() { launchEnvTimeout = timeout }()
+	a := NewApp()
+	scriptPath, err := a.writeLaunchScript(t.TempDir(), map[string]string{"K": "v"}, "", []string{"true"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
+			return
+		}
+	}
+	t.Fatal("launch directory kept after the settings expired")
+}
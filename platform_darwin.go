@@ -157,16 +157,12 @@ func (a *App) CheckEnvironment(force bool) {
 		a.log(a.tr("✓ Base environment check complete."))
 		
 		// Update config to mark base env check done
-		if cfg, err := a.LoadConfig(); err == nil {
-			needsSave := false
-			if !cfg.EnvCheckDone {
+		if cfg, err := a.LoadConfig(); err == nil && !cfg.EnvCheckDone {
+			a.configStore().Update(configSourceApp, func(cfg *AppConfig) error {
 				cfg.EnvCheckDone = true
 				cfg.PauseEnvCheck = true
-				needsSave = true
-			}
-			if needsSave {
-				a.SaveConfig(cfg)
-			}
+				return nil
+			})
 		}
 		
 		a.emitEvent("env-check-done")
@@ -460,22 +456,23 @@ func (a *App) platformLaunch(binaryName string, yoloMode bool, adminMode bool, p
 		}
 	}
 	
-	scriptPath := filepath.Join(os.TempDir(), fmt.Sprintf("aicoder_launch_%d.sh", time.Now().UnixNano()))
-	scriptContent := "#!/bin/bash\n"
-	scriptContent += fmt.Sprintf("cd \"%s\"\n", projectDir)
-	for k, v := range env {
-		scriptContent += fmt.Sprintf("export %s=\"%s\"\n", k, v)
-	}
-	
+	// The environment reaches the script through a pipe, never through the script body
 	home, _ := os.UserHomeDir()
 	localBin := filepath.Join(home, ".cceasy", "tools", "bin")
-	scriptContent += fmt.Sprintf("export PATH=\"%s:$PATH\"\n", localBin)
-	
-	scriptContent += fmt.Sprintf("\"%s\" %s\n", status.Path, strings.Join(cmdArgs, " "))
-	
-	os.WriteFile(scriptPath, []byte(scriptContent), 0755)
+	scriptPath, err := a.writeLaunchScript(projectDir, env, localBin, append([]string{status.Path}, cmdArgs...), false)
+	if err != nil {
+		a.log("Error creating launch script: " + err.Error())
+		a.ShowMessage("Launch Error", "Failed to create launch script")
+		return
+	}
 	
-	cmd := exec.Command("open", "-a", "Terminal", scriptPath)
+	// The script is not executable, so have Terminal run it through bash
+	command := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace("/bin/bash " + shellQuote(scriptPath))
+	cmd := exec.Command("osascript",
+		"-e", `tell application "Terminal"`,
+		"-e", "activate",
+		"-e", `do script "`+command+`"`,
+		"-e", "end tell")
 	cmd.Start()
 }
 
@@ -162,16 +162,12 @@ func (a *App) CheckEnvironment(force bool) {
 		a.log(a.tr("✓ Base environment check complete."))
 		
 		// Update config to mark base env check done
-		if cfg, err := a.LoadConfig(); err == nil {
-			needsSave := false
-			if !cfg.EnvCheckDone {
+		if cfg, err := a.LoadConfig(); err == nil && !cfg.EnvCheckDone {
+			a.configStore().Update(configSourceApp, func(cfg *AppConfig) error {
 				cfg.EnvCheckDone = true
 				cfg.PauseEnvCheck = true
-				needsSave = true
-			}
-			if needsSave {
-				a.SaveConfig(cfg)
-			}
+				return nil
+			})
 		}
 		
 		a.emitEvent("env-check-done")
@@ -444,23 +440,15 @@ func (a *App) platformLaunch(binaryName string, yoloMode bool, adminMode bool, p
 		}
 	}
 	
-	// Create shell script wrapper
-	scriptPath := filepath.Join(os.TempDir(), fmt.Sprintf("aicoder_launch_%d.sh", time.Now().UnixNano()))
-	scriptContent := "#!/bin/bash\n"
-	scriptContent += fmt.Sprintf("cd \"%s\"\n", projectDir)
-	for k, v := range env {
-		scriptContent += fmt.Sprintf("export %s=\"%s\"\n", k, v)
-	}
-	
-	// Add local node to PATH
+	// Create shell script wrapper; the environment reaches it through a pipe
 	home, _ := os.UserHomeDir()
 	localBin := filepath.Join(home, ".cceasy", "tools", "bin")
-	scriptContent += fmt.Sprintf("export PATH=\"%s:$PATH\"\n", localBin)
-	
-	scriptContent += fmt.Sprintf("\"%s\" %s\n", status.Path, strings.Join(cmdArgs, " "))
-	scriptContent += "echo 'Press Enter to close...'\nread\n"
-	
-	os.WriteFile(scriptPath, []byte(scriptContent), 0755)
+	scriptPath, err := a.writeLaunchScript(projectDir, env, localBin, append([]string{status.Path}, cmdArgs...), true)
+	if err != nil {
+		a.log("Error creating launch script: " + err.Error())
+		a.ShowMessage("Launch Error", "Failed to create launch script")
+		return
+	}
 	
 	// Try to open terminal
 	terminals := []string{"x-terminal-emulator", "gnome-terminal", "konsole", "xterm"}
@@ -468,15 +456,17 @@ func (a *App) platformLaunch(binaryName string, yoloMode bool, adminMode bool, p
 	for _, t := range terminals {
 		if _, err := exec.LookPath(t); err == nil {
 			if t == "gnome-terminal" {
-				cmd = exec.Command(t, "--", scriptPath)
+				cmd = exec.Command(t, "--", "/bin/bash", scriptPath)
 			} else {
-				cmd = exec.Command(t, "-e", scriptPath)
+				cmd = exec.Command(t, "-e", "/bin/bash", scriptPath)
 			}
 			break
 		}
 	}
 	
 	if cmd != nil {
+		// Nothing a launch sets is inherited; the script gets it through the pipe
+		cmd.Env = launchEnviron(nil)
 		cmd.Start()
 	} else {
 		a.log("No supported terminal emulator found.")
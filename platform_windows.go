@@ -256,11 +256,11 @@ func (a *App) RunEnvironmentCheckCLI() {
 	}
 
 	// Update config
-	if cfg, err := a.LoadConfig(); err == nil {
+	a.configStore().Update(configSourceApp, func(cfg *AppConfig) error {
 		cfg.EnvCheckDone = true
 		cfg.PauseEnvCheck = true
-		a.SaveConfig(cfg)
-	}
+		return nil
+	})
 
 	fmt.Println("\n✓ Base environment setup completed!")
 	fmt.Println("AI tools will be installed in background when the application starts.")
@@ -421,16 +421,12 @@ func (a *App) CheckEnvironment(force bool) {
 		a.log(a.tr("✓ Base environment check complete."))
 
 		// Update config to mark base env check done
-		if cfg, err := a.LoadConfig(); err == nil {
-			needsSave := false
-			if !cfg.EnvCheckDone {
+		if cfg, err := a.LoadConfig(); err == nil && !cfg.EnvCheckDone {
+			a.configStore().Update(configSourceApp, func(cfg *AppConfig) error {
 				cfg.EnvCheckDone = true
 				cfg.PauseEnvCheck = true
-				needsSave = true
-			}
-			if needsSave {
-				a.SaveConfig(cfg)
-			}
+				return nil
+			})
 		}
 
 		a.emitEvent("env-check-done")
@@ -1681,6 +1677,8 @@ func (a *App) platformLaunch(binaryName string, yoloMode bool, adminMode bool, p
 				CmdLine:    cmdLine,
 				HideWindow: true,
 			}
+			// The batch file sets what the tool needs; nothing stale is inherited
+			cmd.Env = launchEnviron(nil)
 
 			if err := cmd.Start(); err != nil {
 				a.log("Error launching tool: " + err.Error())
@@ -1700,6 +1698,8 @@ func (a *App) platformLaunch(binaryName string, yoloMode bool, adminMode bool, p
 				CmdLine:    cmdLine,
 				HideWindow: true,
 			}
+			// The batch file sets what the tool needs; nothing stale is inherited
+			cmd.Env = launchEnviron(nil)
 
 			if err := cmd.Start(); err != nil {
 				a.log("Error launching tool: " + err.Error())
@@ -1773,10 +1773,7 @@ func (a *App) syncToSystemEnv(config AppConfig) {
 		return
 	}
 
-	baseUrl := selectedModel.ModelUrl
-	if toolName == "claude" {
-		baseUrl = getBaseUrl(selectedModel)
-	}
+	baseUrl := resolveProvider(toolName, selectedModel).BaseUrl
 
 	os.Setenv(envKey, selectedModel.ApiKey)
 	if baseUrl != "" {
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// projectFileName is the file a repository commits to share its launch settings.
+const projectFileName = ".aicoder.json"
+
+// preLaunchTimeout is how long a pre-launch command may run.
+const preLaunchTimeout = 10 * time.Minute
+
+// Layers a launch setting can come from, lowest first.
+const (
+	layerGlobal  = "global"  // The tool's settings in AICoder
+	layerProject = "project" // The project's settings in AICoder
+	layerFile    = "file"    // The project's .aicoder.json
+)
+
+// ProjectFile is the content of .aicoder.json. Every field is optional and takes
+// precedence over the user's own settings for the project. There is deliberately no
+// place for API keys: the file names a provider and the key stays in AICoder.
+type ProjectFile struct {
+	Tool          string            `json:"tool,omitempty"`
+	Provider      string            `json:"provider,omitempty"`
+	Model         string            `json:"model,omitempty"` // Model ID sent to the provider
+	YoloMode      *bool             `json:"yolo_mode,omitempty"`
+	AdminMode     *bool             `json:"admin_mode,omitempty"`
+	TeamMode      *bool             `json:"team_mode,omitempty"`
+	UseProxy      *bool             `json:"use_proxy,omitempty"`
+	PythonProject *bool             `json:"python_project,omitempty"`
+	PythonEnv     string            `json:"python_env,omitempty"`
+	Env           map[string]string `json:"env,omitempty"`
+	PreLaunch     []string          `json:"pre_launch,omitempty"` // Shell commands run in the project first
+}
+
+// launchSettings are the settings a launch uses, with the layer each came from.
+type launchSettings struct {
+	Tool          string
+	Provider      string
+	Model         string
+	YoloMode      bool
+	AdminMode     bool
+	TeamMode      bool
+	UseProxy      bool
+	PythonProject bool
+	PythonEnv     string
+	Env           map[string]string
+	PreLaunch     []string
+	sources       map[string]string
+}
+
+// projectFileState describes the .aicoder.json of a project.
+type projectFileState struct {
+	Path    string
+	Trusted bool
+	Hash    string
+	Error   string // Why the file could not be used; it is then ignored
+}
+
+// EffectiveSetting is one setting of a launch and the layer it came from: global,
+// project or file.
+type EffectiveSetting struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// EffectiveProjectSettings shows what launching the active tool in a project uses.
+type EffectiveProjectSettings struct {
+	ProjectDir string             `json:"project_dir"`
+	File       string             `json:"file"`    // The project's .aicoder.json, empty when it has none
+	Trusted    bool               `json:"trusted"` // Untrusted files are ignored until TrustProjectFile
+	Error      string             `json:"error"`
+	Settings   []EffectiveSetting `json:"settings"`
+}
+
+// findProject returns the project at projectDir, or else the current project.
+func findProject(config *AppConfig, projectDir string) *ProjectConfig {
+	for i := range config.Projects {
+		if config.Projects[i].Path == projectDir {
+			return &config.Projects[i]
+		}
+	}
+	for i := range config.Projects {
+		if config.Projects[i].Id == config.CurrentProject {
+			return &config.Projects[i]
+		}
+	}
+	return nil
+}
+
+// projectLaunchSettings returns the settings of a launch in proj before any
+// .aicoder.json, as the UI passes them to LaunchTool.
+func projectLaunchSettings(tool string, proj *ProjectConfig) launchSettings {
+	s := launchSettings{Tool: strings.ToLower(tool), sources: map[string]string{"tool": layerGlobal}}
+	if proj != nil {
+		s.YoloMode, s.AdminMode, s.TeamMode, s.UseProxy = proj.YoloMode, proj.AdminMode, proj.TeamMode, proj.UseProxy
+		s.PythonProject, s.PythonEnv = proj.PythonProject, proj.PythonEnv
+	}
+	for _, name := range []string{"yolo_mode", "admin_mode", "team_mode", "use_proxy", "python_project", "python_env"} {
+		s.sources[name] = layerProject
+	}
+	return s
+}
+
+// readProjectFile reads the .aicoder.json in projectDir. It returns nil when there is
+// none. Unknown fields are an error, so that a key committed by mistake is noticed.
+func readProjectFile(projectDir string) (*ProjectFile, *projectFileState, error) {
+	if projectDir == "" {
+		return nil, nil, nil
+	}
+	path := filepath.Join(projectDir, projectFileName)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	sum := sha256.Sum256(data)
+	state := &projectFileState{Path: path, Hash: hex.EncodeToString(sum[:])}
+	var f ProjectFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return nil, state, fmt.Errorf("%s: %v", path, err)
+	}
+	if f.Tool != "" && !isToolName(f.Tool) {
+		return nil, state, fmt.Errorf("%s: unknown tool %q", path, f.Tool)
+	}
+	for k := range f.Env {
+		if k == "" || strings.ContainsAny(k, "= \t\n") {
+			return nil, state, fmt.Errorf("%s: invalid environment variable name %q", path, k)
+		}
+	}
+	return &f, state, nil
+}
+
+func isToolName(tool string) bool {
+	for _, t := range toolNames {
+		if strings.EqualFold(t, tool) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveLaunchSettings layers the .aicoder.json in projectDir over s and selects
+// the tool's provider and model in config, the launch's own copy of the config, so
+// that the tool config files are written for them. A file is only used once the user
+// has trusted its current content, as it can run commands; until then, or when it is
+// not valid, it is ignored.
+func resolveLaunchSettings(config *AppConfig, s launchSettings, projectDir string) (launchSettings, *projectFileState, error) {
+	f, state, err := readProjectFile(projectDir)
+	if err != nil && state == nil {
+		return s, nil, err
+	}
+	if err != nil {
+		state.Error = err.Error()
+	} else if f != nil {
+		state.Trusted = config.TrustedProjectFiles[state.Path] == state.Hash
+	}
+	if f == nil || !state.Trusted {
+		f = &ProjectFile{}
+	}
+	setString := func(name string, dst *string, v string) {
+		if v != "" {
+			*dst = v
+			s.sources[name] = layerFile
+		}
+	}
+	setBool := func(name string, dst *bool, v *bool) {
+		if v != nil {
+			*dst = *v
+			s.sources[name] = layerFile
+		}
+	}
+	setString("tool", &s.Tool, strings.ToLower(f.Tool))
+	setBool("yolo_mode", &s.YoloMode, f.YoloMode)
+	setBool("admin_mode", &s.AdminMode, f.AdminMode)
+	setBool("team_mode", &s.TeamMode, f.TeamMode)
+	setBool("use_proxy", &s.UseProxy, f.UseProxy)
+	setBool("python_project", &s.PythonProject, f.PythonProject)
+	setString("python_env", &s.PythonEnv, f.PythonEnv)
+	s.Env = f.Env
+	s.PreLaunch = f.PreLaunch
+
+	toolCfg := getToolConfig(config, s.Tool)
+	if toolCfg == nil {
+		// Not a coding tool, e.g. the message page
+		return s, state, nil
+	}
+	s.sources["provider"], s.sources["model"] = layerGlobal, layerGlobal
+	if f.Provider != "" {
+		m := getProviderModel(toolCfg, f.Provider)
+		if m == nil {
+			return s, state, fmt.Errorf("provider %q from %s is not available for %s", f.Provider, state.Path, s.Tool)
+		}
+		toolCfg.CurrentModel = m.ModelName
+		s.sources["provider"] = layerFile
+	}
+	s.Provider = toolCfg.CurrentModel
+	if m := getProviderModel(toolCfg, s.Provider); m != nil {
+		if f.Model != "" {
+			m.ModelId = f.Model
+			s.sources["model"] = layerFile
+		}
+		s.Model = resolveProvider(s.Tool, m).ModelId
+	}
+	return s, state, nil
+}
+
+// GetEffectiveProjectSettings returns the settings launching the active tool in
+// projectDir would use, and whether each comes from the tool's settings (global),
+// the project's settings (project) or the project's .aicoder.json (file).
+func (a *App) GetEffectiveProjectSettings(projectDir string) (EffectiveProjectSettings, error) {
+	config, err := a.LoadConfig()
+	if err != nil {
+		return EffectiveProjectSettings{}, err
+	}
+	if projectDir == "" {
+		projectDir = a.GetCurrentProjectPath()
+	}
+	result := EffectiveProjectSettings{ProjectDir: projectDir}
+	base := projectLaunchSettings(config.ActiveTool, findProject(&config, projectDir))
+	s, state, err := resolveLaunchSettings(&config, base, projectDir)
+	if state != nil {
+		result.File = state.Path
+		result.Trusted = state.Trusted
+		result.Error = state.Error
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	add := func(name, value string) {
+		result.Settings = append(result.Settings, EffectiveSetting{Name: name, Value: value, Source: s.sources[name]})
+	}
+	add("tool", s.Tool)
+	add("provider", s.Provider)
+	add("model", s.Model)
+	add("yolo_mode", strconv.FormatBool(s.YoloMode))
+	add("admin_mode", strconv.FormatBool(s.AdminMode))
+	add("team_mode", strconv.FormatBool(s.TeamMode))
+	add("use_proxy", strconv.FormatBool(s.UseProxy))
+	add("python_project", strconv.FormatBool(s.PythonProject))
+	add("python_env", s.PythonEnv)
+	var names []string
+	for k := range s.Env {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		result.Settings = append(result.Settings, EffectiveSetting{Name: "env." + k, Value: s.Env[k], Source: layerFile})
+	}
+	for i, c := range s.PreLaunch {
+		result.Settings = append(result.Settings, EffectiveSetting{Name: "pre_launch." + strconv.Itoa(i), Value: c, Source: layerFile})
+	}
+	return result, nil
+}
+
+// TrustProjectFile allows the current content of the .aicoder.json in projectDir to
+// be used for launches. Any later change to the file has to be trusted again.
+func (a *App) TrustProjectFile(projectDir string) error {
+	f, state, err := readProjectFile(projectDir)
+	if err != nil {
+		return err
+	}
+	if f == nil {
+		return fmt.Errorf("%s has no %s", projectDir, projectFileName)
+	}
+	_, err = a.modifyConfig(func(config *AppConfig) error {
+		if config.TrustedProjectFiles == nil {
+			config.TrustedProjectFiles = make(map[string]string)
+		}
+		config.TrustedProjectFiles[state.Path] = state.Hash
+		return nil
+	})
+	if err == nil {
+		a.log("Trusted " + state.Path)
+	}
+	return err
+}
+
+// runPreLaunch runs the pre-launch commands of a project in order, in projectDir and
+// with the environment the tool gets, see launchEnviron, and stops at the first that
+// fails.
+func (a *App) runPreLaunch(projectDir string, commands []string, env map[string]string) error {
+	cmdEnv := launchEnviron(env)
+	for _, command := range commands {
+		a.log("Pre-launch: " + command)
+		cmd := createHiddenCmd("/bin/sh", "-c", command)
+		if goruntime.GOOS == "windows" {
+			cmd = createHiddenCmd("cmd", "/C", command)
+		}
+		cmd.Dir = projectDir
+		cmd.Env = cmdEnv
+		var out bytes.Buffer
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("%s: %v", command, err)
+		}
+		timer := time.AfterFunc(preLaunchTimeout, func() { cmd.Process.Kill() })
+		err := cmd.Wait()
+		timer.Stop()
+		if text := strings.TrimSpace(out.String()); text != "" {
+			a.log(text)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", command, err)
+		}
+	}
+	return nil
+}
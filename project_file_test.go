@@ -0,0 +1,176 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
+	"testing"
+)
+
+// newProjectTestApp returns an App whose current project is a temporary directory
+// with the given .aicoder.json; empty content means the project has none.
+func newProjectTestApp(t *testing.T, content string) (*App, string) {
+	t.Helper()
+	a := newSecretTestApp(t, newFakeSecretBackend("fake"))
+	t.Setenv("HOME", a.testHomeDir)
+	dir := t.TempDir()
+	if content != "" {
+		writeProjectFile(t, dir, content)
+	}
+	config := secretTestConfig()
+	config.ActiveTool = "claude"
+	config.Claude.Models = append(config.Claude.Models, ModelConfig{ModelName: "Kimi", ApiKey: "sk-kimi"})
+	config.Projects = []ProjectConfig{{Id: "p1", Name: "p1", Path: dir, YoloMode: true}}
+	config.CurrentProject = "p1"
+	path, _ := a.getConfigPath()
+	if err := a.saveToPath(path, config); err != nil {
+		t.Fatal(err)
+	}
+	return a, dir
+}
+
+func writeProjectFile(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, projectFileName), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func effectiveSetting(t *testing.T, s EffectiveProjectSettings, name string) EffectiveSetting {
+	t.Helper()
+	for _, e := range s.Settings {
+		if e.Name == name {
+			return e
+		}
+	}
+	t.Fatalf("%s missing from %+v", name, s.Settings)
+	return EffectiveSetting{}
+}
+
+const testProjectFile = `{"provider": "Kimi", "model": "kimi-test", "yolo_mode": false, "env": {"PROJECT_VAR": "1"}}`
+
+func TestProjectFileIgnoredUntilTrusted(t *testing.T) {
+	a, dir := newProjectTestApp(t, testProjectFile)
+
+	s, err := a.GetEffectiveProjectSettings(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Trusted || s.File == "" {
+		t.Fatalf("new file: trusted=%v file=%q", s.Trusted, s.File)
+	}
+	if e := effectiveSetting(t, s, "provider"); e.Value != "GLM" || e.Source != layerGlobal {
+		t.Fatalf("untrusted file used: %+v", e)
+	}
+	for _, e := range s.Settings {
+		if strings.HasPrefix(e.Name, "env.") {
+			t.Fatalf("untrusted env used: %+v", e)
+		}
+	}
+
+	if err := a.TrustProjectFile(dir); err != nil {
+		t.Fatal(err)
+	}
+	s, _ = a.GetEffectiveProjectSettings(dir)
+	if !s.Trusted {
+		t.Fatal("trusted file not trusted")
+	}
+	if e := effectiveSetting(t, s, "provider"); e.Value != "Kimi" || e.Source != layerFile {
+		t.Fatalf("provider = %+v", e)
+	}
+
+	// Any change has to be trusted again
+	writeProjectFile(t, dir, strings.Replace(testProjectFile, "kimi-test", "kimi-other", 1))
+	s, _ = a.GetEffectiveProjectSettings(dir)
+	if s.Trusted {
+		t.Fatal("changed file still trusted")
+	}
+	if e := effectiveSetting(t, s, "model"); e.Source == layerFile {
+		t.Fatalf("changed file used: %+v", e)
+	}
+}
+
+func TestProjectFileLayering(t *testing.T) {
+	a, dir := newProjectTestApp(t, testProjectFile)
+	if err := a.TrustProjectFile(dir); err != nil {
+		t.Fatal(err)
+	}
+	s, err := a.GetEffectiveProjectSettings(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []EffectiveSetting{
+		{Name: "tool", Value: "claude", Source: layerGlobal},
+		{Name: "provider", Value: "Kimi", Source: layerFile},
+		{Name: "model", Value: "kimi-test", Source: layerFile},
+		{Name: "yolo_mode", Value: "false", Source: layerFile},
+		{Name: "admin_mode", Value: "false", Source: layerProject},
+		{Name: "env.PROJECT_VAR", Value: "1", Source: layerFile},
+	} {
+		if got := effectiveSetting(t, s, want.Name); got != want {
+			t.Errorf("%s = %+v, want %+v", want.Name, got, want)
+		}
+	}
+	// The launch's copy is changed, not the saved config
+	config, _ := a.LoadConfig()
+	if config.Claude.CurrentModel != "GLM" {
+		t.Fatalf("saved provider changed to %s", config.Claude.CurrentModel)
+	}
+}
+
+func TestProjectFileInvalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown field": `{"provider": "Kimi", "api_key": "sk-leak"}`,
+		"unknown tool":  `{"tool": "nano"}`,
+		"bad env name":  `{"env": {"A=B": "1"}}`,
+		"not json":      `{`,
+	} {
+		a, dir := newProjectTestApp(t, content)
+		s, err := a.GetEffectiveProjectSettings(dir)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if s.Error == "" {
+			t.Errorf("%s: no error reported", name)
+		}
+		if err := a.TrustProjectFile(dir); err == nil {
+			t.Errorf("%s: invalid file trusted", name)
+		}
+	}
+}
+
+func TestProjectFileUnknownProvider(t *testing.T) {
+	a, dir := newProjectTestApp(t, `{"provider": "Nowhere"}`)
+	if err := a.TrustProjectFile(dir); err != nil {
+		t.Fatal(err)
+	}
+	s, _ := a.GetEffectiveProjectSettings(dir)
+	if !strings.Contains(s.Error, "Nowhere") {
+		t.Fatalf("error = %q", s.Error)
+	}
+}
+
+func TestRunPreLaunchUsesLaunchEnv(t *testing.T) {
+	if goruntime.GOOS == "windows" {
+		t.Skip("uses /bin/sh")
+	}
+	a := NewApp()
+	dir := t.TempDir()
+	err := a.runPreLaunch(dir, []string{`printf %s "$PROJECT_VAR" > out`}, map[string]string{"PROJECT_VAR": "from-file"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "out")); string(data) != "from-file" {
+		t.Fatalf("pre-launch saw PROJECT_VAR=%q", data)
+	}
+	if _, ok := os.LookupEnv("PROJECT_VAR"); ok {
+		t.Fatal("launch env leaked into AICoder's environment")
+	}
+	if err := a.runPreLaunch(dir, []string{"exit 3", "touch never"}, nil); err == nil {
+		t.Fatal("failing command not reported")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "never")); err == nil {
+		t.Fatal("ran a command after one failed")
+	}
+}
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+//go:embed providers.json
+var embeddedCatalog []byte
+
+// toolNames lists every tool AICoder manages, in UI order.
+var toolNames = []string{"claude", "gemini", "codex", "opencode", "codebuddy", "qoder", "iflow", "kilo", "kode"}
+
+// ProviderCatalog declares every known provider once, with per-tool endpoints
+// and defaults. It is embedded from providers.json.
+type ProviderCatalog struct {
+	Version      int                            `json:"version"`
+	ToolDefaults map[string]CatalogToolDefaults `json:"tool_defaults"`
+	Providers    []CatalogProvider              `json:"providers"`
+}
+
+type CatalogToolDefaults struct {
+	DefaultProvider string      `json:"default_provider"`
+	Original        bool        `json:"original"`          // Whether the tool offers an "Original" (official login) entry
+	CustomSlots     int         `json:"custom_slots"`      // Custom providers a new config starts with; 0 means the tool takes no custom providers
+	FallbackModelId string      `json:"fallback_model_id"` // Used when neither the model nor the catalog has a model id
+	FallbackBaseUrl string      `json:"fallback_base_url"`
+	FallbackWireApi string      `json:"fallback_wire_api"`
+	Params          ModelParams `json:"params"` // Tool-wide parameter defaults
+}
+
+type CatalogProvider struct {
+	Name    string                      `json:"name"`    // Display name, also the ModelName stored in AppConfig
+	Id      string                      `json:"id"`      // Stable ASCII id used for config keys
+	Aliases []string                    `json:"aliases"` // Historical names that map onto this provider
+	Tools   map[string]CatalogToolEntry `json:"tools"`
+}
+
+type CatalogToolEntry struct {
+	BaseUrl         string                 `json:"base_url"`
+	ModelId         string                 `json:"model_id"`
+	WireApi         string                 `json:"wire_api,omitempty"`
+	Roles           ModelRoles             `json:"roles"`
+	Env             map[string]string      `json:"env,omitempty"` // "{model}", "{fast}", "{reasoning}" and "{compact}" expand to the resolved model ids
+	Permissions     map[string]interface{} `json:"permissions,omitempty"`
+	Params          ModelParams            `json:"params"`
+	ProviderOptions map[string]interface{} `json:"provider_options,omitempty"` // Extra keys for Codex's [model_providers.<id>] table
+}
+
+var (
+	catalogMutex  sync.RWMutex
+	activeCatalog = mustParseCatalog(embeddedCatalog)
+)
+
+func parseCatalog(data []byte) (*ProviderCatalog, error) {
+	var c ProviderCatalog
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, p := range c.Providers {
+		if p.Name == "" || p.Id == "" {
+			return nil, fmt.Errorf("provider catalog: provider without name or id")
+		}
+		for _, n := range append([]string{p.Name}, p.Aliases...) {
+			key := strings.ToLower(n)
+			if seen[key] {
+				return nil, fmt.Errorf("provider catalog: duplicate provider name %q", n)
+			}
+			seen[key] = true
+		}
+	}
+	return &c, nil
+}
+
+func mustParseCatalog(data []byte) *ProviderCatalog {
+	c, err := parseCatalog(data)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// currentCatalog returns the catalog in effect.
+func currentCatalog() *ProviderCatalog {
+	catalogMutex.RLock()
+	defer catalogMutex.RUnlock()
+	return activeCatalog
+}
+
+// lookup finds a provider by display name or alias, case-insensitively.
+func (c *ProviderCatalog) lookup(name string) *CatalogProvider {
+	for i := range c.Providers {
+		p := &c.Providers[i]
+		if strings.EqualFold(p.Name, name) {
+			return p
+		}
+		for _, alias := range p.Aliases {
+			if strings.EqualFold(alias, name) {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+// hasId reports whether a catalog provider uses id as its config key.
+func (c *ProviderCatalog) hasId(id string) bool {
+	for _, p := range c.Providers {
+		if p.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// entry returns the provider and its settings for a tool, or nil if the tool does not offer it.
+func (c *ProviderCatalog) entry(tool, name string) (*CatalogProvider, *CatalogToolEntry) {
+	p := c.lookup(name)
+	if p == nil {
+		return nil, nil
+	}
+	e, ok := p.Tools[tool]
+	if !ok {
+		return p, nil
+	}
+	return p, &e
+}
+
+func (c *ProviderCatalog) defaults(tool string) CatalogToolDefaults {
+	return c.ToolDefaults[tool]
+}
+
+func customSlotName(index int) string {
+	if index == 0 {
+		return "Custom"
+	}
+	return fmt.Sprintf("Custom%d", index)
+}
+
+// defaultModels builds the provider list a fresh config starts with for a tool.
+func (c *ProviderCatalog) defaultModels(tool string) []ModelConfig {
+	d := c.defaults(tool)
+	var models []ModelConfig
+	if d.Original {
+		models = append(models, ModelConfig{ModelName: "Original"})
+	}
+	for _, p := range c.Providers {
+		if e, ok := p.Tools[tool]; ok {
+			models = append(models, ModelConfig{ModelName: p.Name, ModelId: e.ModelId, ModelUrl: e.BaseUrl, CatalogUrl: e.BaseUrl, WireApi: e.WireApi})
+		}
+	}
+	for i := 0; i < d.CustomSlots; i++ {
+		models = append(models, ModelConfig{ModelName: customSlotName(i), IsCustom: true})
+	}
+	return models
+}
+
+func (c *ProviderCatalog) defaultToolConfig(tool string) ToolConfig {
+	return ToolConfig{
+		CurrentModel: c.defaults(tool).DefaultProvider,
+		Models:       c.defaultModels(tool),
+	}
+}
+
+// applyCatalog brings the provider lists of an existing config in line with the catalog.
+// Endpoints and wire APIs follow the catalog; user-set API keys, model ids and
+// endpoints are kept. A provider the catalog drops for a tool is removed only when
+// it holds no key; otherwise it stays as a custom provider.
+func (c *ProviderCatalog) applyCatalog(config *AppConfig) {
+	for _, tool := range toolNames {
+		toolCfg := getToolConfig(config, tool)
+		var models []ModelConfig
+		seen := make(map[string]int) // Provider id -> index in models
+		for _, m := range toolCfg.Models {
+			if m.IsCustom || strings.EqualFold(m.ModelName, "Original") {
+				models = append(models, m)
+				continue
+			}
+			p := c.lookup(m.ModelName)
+			if p == nil {
+				models = append(models, m)
+				continue
+			}
+			e, offered := p.Tools[tool]
+			i, dup := seen[p.Id]
+			switch {
+			case offered && !dup:
+			case dup && !hasApiKey(models[i]):
+				// A duplicate under an old alias; its key moves to the entry kept
+				models[i].ApiKey, models[i].ApiKeys, models[i].ActiveKey = m.ApiKey, m.ApiKeys, m.ActiveKey
+				continue
+			case !hasApiKey(m) || (dup && m.ApiKey == models[i].ApiKey):
+				// Nothing of the user's would be lost
+				continue
+			default:
+				// No longer offered for this tool, or a second key under an old alias:
+				// kept as a custom provider so that the user's key stays
+				m.IsCustom = true
+				m.CatalogUrl = ""
+				models = append(models, m)
+				continue
+			}
+			seen[p.Id] = len(models)
+			// A provider selected under an old name stays selected
+			if strings.EqualFold(toolCfg.CurrentModel, m.ModelName) {
+				toolCfg.CurrentModel = p.Name
+			}
+			m.ModelName = p.Name
+			// An endpoint the user changed stays; see migrateCatalogUrls
+			if e.BaseUrl != "" && (m.ModelUrl == "" || m.ModelUrl == m.CatalogUrl) {
+				m.ModelUrl = e.BaseUrl
+				m.CatalogUrl = e.BaseUrl
+			}
+			if m.ModelId == "" {
+				m.ModelId = e.ModelId
+			}
+			if e.WireApi != "" {
+				m.WireApi = e.WireApi
+			}
+			models = append(models, m)
+		}
+		// New catalog providers go before the user's custom ones
+		var added []ModelConfig
+		for _, p := range c.Providers {
+			if _, dup := seen[p.Id]; dup || getProviderModel(&ToolConfig{Models: models}, p.Name) != nil {
+				// Present, possibly kept as a custom provider
+				continue
+			}
+			if e, ok := p.Tools[tool]; ok {
+				added = append(added, ModelConfig{ModelName: p.Name, ModelId: e.ModelId, ModelUrl: e.BaseUrl, CatalogUrl: e.BaseUrl, WireApi: e.WireApi})
+			}
+		}
+		at := len(models)
+		for i, m := range models {
+			if m.IsCustom {
+				at = i
+				break
+			}
+		}
+		toolCfg.Models = append(models[:at:at], append(added, models[at:]...)...)
+	}
+}
+
+// getToolConfig returns the config section for a tool name, or nil for unknown tools.
+func getToolConfig(config *AppConfig, tool string) *ToolConfig {
+	switch strings.ToLower(tool) {
+	case "claude":
+		return &config.Claude
+	case "gemini":
+		return &config.Gemini
+	case "codex":
+		return &config.Codex
+	case "opencode":
+		return &config.Opencode
+	case "codebuddy":
+		return &config.CodeBuddy
+	case "qoder":
+		return &config.Qoder
+	case "iflow":
+		return &config.IFlow
+	case "kilo":
+		return &config.Kilo
+	case "kode":
+		return &config.Kode
+	}
+	return nil
+}
+
+// resolvedProvider is what a tool writer needs to know about the selected model,
+// after falling back from the model to the catalog entry to the tool defaults.
+type resolvedProvider struct {
+	Id              string
+	Name            string
+	BaseUrl         string
+	ModelId         string
+	Roles           ModelRoles // Every role filled in, falling back to ModelId
+	WireApi         string
+	Env             map[string]string
+	Permissions     map[string]interface{}
+	Params          ModelParams // Model values over catalog entry over tool defaults
+	ProviderOptions map[string]interface{}
+	Known           bool // Declared in the catalog for this tool
+}
+
+func resolveProvider(tool string, m *ModelConfig) resolvedProvider {
+	c := currentCatalog()
+	d := c.defaults(tool)
+	r := resolvedProvider{
+		Name:    m.ModelName,
+		BaseUrl: m.ModelUrl,
+		ModelId: m.ModelId,
+		Roles:   m.Roles,
+		Params:  m.Params,
+		WireApi: m.WireApi,
+	}
+	if strings.EqualFold(m.ModelName, "Original") {
+		// The tool's own login; nothing to resolve
+		return r
+	}
+	var e *CatalogToolEntry
+	if !m.IsCustom {
+		var p *CatalogProvider
+		p, e = c.entry(tool, m.ModelName)
+		if p != nil {
+			r.Id = p.Id
+		}
+	}
+	if e != nil {
+		r.Known = true
+		if r.BaseUrl == "" {
+			r.BaseUrl = e.BaseUrl
+		}
+		if r.ModelId == "" {
+			r.ModelId = e.ModelId
+		}
+		if r.WireApi == "" {
+			r.WireApi = e.WireApi
+		}
+		if r.Roles.Fast == "" {
+			r.Roles.Fast = e.Roles.Fast
+		}
+		if r.Roles.Reasoning == "" {
+			r.Roles.Reasoning = e.Roles.Reasoning
+		}
+		if r.Roles.Compact == "" {
+			r.Roles.Compact = e.Roles.Compact
+		}
+		r.Permissions = e.Permissions
+		r.Params.fillFrom(e.Params)
+		r.ProviderOptions = e.ProviderOptions
+	}
+	if r.Id == "" {
+		r.Id = providerKey(m.ModelName)
+	}
+	if r.BaseUrl == "" {
+		r.BaseUrl = d.FallbackBaseUrl
+	}
+	if r.ModelId == "" {
+		r.ModelId = d.FallbackModelId
+	}
+	if r.WireApi == "" {
+		r.WireApi = d.FallbackWireApi
+	}
+	r.Params.fillFrom(d.Params)
+	for _, role := range []*string{&r.Roles.Fast, &r.Roles.Reasoning, &r.Roles.Compact} {
+		if *role == "" {
+			*role = r.ModelId
+		}
+	}
+	if e != nil && len(e.Env) > 0 {
+		expand := strings.NewReplacer("{model}", r.ModelId, "{fast}", r.Roles.Fast, "{reasoning}", r.Roles.Reasoning, "{compact}", r.Roles.Compact)
+		r.Env = make(map[string]string, len(e.Env))
+		for k, v := range e.Env {
+			r.Env[k] = expand.Replace(v)
+		}
+	}
+	return r
+}
+
+// providerKey turns a display name into an identifier that is safe as a config key.
+// When characters had to be dropped, or the identifier is a catalog provider's, a
+// hash of the name is appended: otherwise names such as 摩尔线程 and 快手 would all
+// become the same key, and so would "My Relay" and "MyRelay".
+func providerKey(name string) string {
+	lower := strings.ToLower(name)
+	var b strings.Builder
+	for _, r := range lower {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+			b.WriteRune(r)
+		}
+	}
+	key := b.String()
+	if key != "" && key == lower && !currentCatalog().hasId(key) {
+		return key
+	}
+	if key == "" {
+		key = "custom"
+	}
+	sum := sha256.Sum256([]byte(lower))
+	return key + "-" + hex.EncodeToString(sum[:4])
+}
+
+// fillFrom copies the parameters that are unset in p from defaults.
+func (p *ModelParams) fillFrom(defaults ModelParams) {
+	if p.ContextWindow == 0 {
+		p.ContextWindow = defaults.ContextWindow
+	}
+	if p.MaxOutputTokens == 0 {
+		p.MaxOutputTokens = defaults.MaxOutputTokens
+	}
+	if p.MaxThinkingTokens == 0 {
+		p.MaxThinkingTokens = defaults.MaxThinkingTokens
+	}
+	if p.ReasoningEffort == "" {
+		p.ReasoningEffort = defaults.ReasoningEffort
+	}
+	if p.RequestMaxRetries == 0 {
+		p.RequestMaxRetries = defaults.RequestMaxRetries
+	}
+	if p.StreamMaxRetries == 0 {
+		p.StreamMaxRetries = defaults.StreamMaxRetries
+	}
+	if p.StreamIdleTimeoutMs == 0 {
+		p.StreamIdleTimeoutMs = defaults.StreamIdleTimeoutMs
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import "testing"
+
+const testCatalogJSON = `{
+  "version": 1,
+  "tool_defaults": {"codex": {"default_provider": "Alpha", "original": true, "custom_slots": 1}},
+  "providers": [
+    {"name": "Alpha", "id": "alpha", "aliases": ["alpha-old"], "tools": {
+      "codex": {"base_url": "https://alpha.example/v1", "model_id": "a-1"}}},
+    {"name": "Beta", "id": "beta", "tools": {
+      "claude": {"base_url": "https://beta.example", "model_id": "b-1"}}},
+    {"name": "Gamma", "id": "gamma", "tools": {
+      "codex": {"base_url": "https://gamma.example/v1", "model_id": "g-1"}}}
+  ]
+}`
+
+func TestApplyCatalog(t *testing.T) {
+	catalog := mustParseCatalog([]byte(testCatalogJSON))
+	config := AppConfig{Codex: ToolConfig{Models: []ModelConfig{
+		{ModelName: "Original"},
+		{ModelName: "Alpha", ModelUrl: "https://alpha.example/v1", CatalogUrl: "https://alpha.example/v1"},
+		{ModelName: "alpha-old", ApiKey: "sk-alpha"},
+		{ModelName: "Beta", ApiKey: "sk-beta", ModelUrl: "https://beta.example/v1"},
+		{ModelName: "Beta2Unknown", ApiKey: "sk-unknown"},
+		{ModelName: "My proxy", IsCustom: true, ApiKey: "sk-custom"},
+	}}}
+	// Beta is offered for Claude only, so Claude gains it and Codex keeps it as custom
+	config.Claude.Models = []ModelConfig{{ModelName: "Original"}}
+	catalog.applyCatalog(&config)
+
+	models := config.Codex.Models
+	var names []string
+	for _, m := range models {
+		names = append(names, m.ModelName)
+	}
+	want := []string{"Original", "Alpha", "Gamma", "Beta", "Beta2Unknown", "My proxy"}
+	if len(names) != len(want) {
+		t.Fatalf("codex providers = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("codex providers = %v, want %v", names, want)
+		}
+	}
+	if m := getProviderModel(&config.Codex, "Alpha"); m.ApiKey != "sk-alpha" || m.ModelId != "a-1" {
+		t.Errorf("alias key not moved to Alpha: %+v", m)
+	}
+	if m := getProviderModel(&config.Codex, "Beta"); !m.IsCustom || m.ApiKey != "sk-beta" || m.ModelUrl != "https://beta.example/v1" {
+		t.Errorf("Beta, no longer offered for codex, lost: %+v", m)
+	}
+	if m := getProviderModel(&config.Codex, "Beta2Unknown"); m.ApiKey != "sk-unknown" {
+		t.Errorf("unknown provider changed: %+v", m)
+	}
+	if getProviderModel(&config.Claude, "Beta") == nil {
+		t.Error("Beta not added to claude")
+	}
+
+	// Applying again changes nothing, and the kept custom provider is not duplicated
+	before := cloneConfig(config)
+	catalog.applyCatalog(&config)
+	if len(config.Codex.Models) != len(before.Codex.Models) {
+		t.Fatalf("second apply changed codex: %v", config.Codex.Models)
+	}
+}
+
+func TestApplyCatalogDropsKeylessProvider(t *testing.T) {
+	catalog := mustParseCatalog([]byte(testCatalogJSON))
+	config := AppConfig{Codex: ToolConfig{Models: []ModelConfig{{ModelName: "Beta"}}}}
+	catalog.applyCatalog(&config)
+	if getProviderModel(&config.Codex, "Beta") != nil {
+		t.Fatal("keyless provider no longer offered was kept")
+	}
+}
+
+// The embedded catalog must offer every provider the built-in lists had before it.
+func TestEmbeddedCatalogKeepsBuiltInProviders(t *testing.T) {
+	catalog := mustParseCatalog(embeddedCatalog)
+	for tool, names := range map[string][]string{
+		"opencode": {"ChatFire", "GLM", "Kimi", "Doubao", "MiniMax", "DeepSeek"},
+		"kilo":     {"ChatFire"},
+		"kode":     {"ChatFire"},
+	} {
+		for _, name := range names {
+			p := catalog.lookup(name)
+			if p == nil {
+				t.Errorf("%s: %s missing from the catalog", tool, name)
+				continue
+			}
+			if _, ok := p.Tools[tool]; !ok {
+				t.Errorf("%s is not offered for %s", name, tool)
+			}
+		}
+	}
+}
+
+func TestProviderKey(t *testing.T) {
+	names := []string{"摩尔线程", "快手", "我的中转", "My Relay", "MyRelay", "my-relay", "Custom", "Custom1", "mthreads", "kimi"}
+	seen := make(map[string]string)
+	for _, name := range names {
+		key := providerKey(name)
+		for _, r := range key {
+			if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '_' && r != '-' {
+				t.Fatalf("%s: key %q is not a bare config key", name, key)
+			}
+		}
+		if other, dup := seen[key]; dup {
+			t.Fatalf("%s and %s share the key %q", other, name, key)
+		}
+		seen[key] = name
+		if providerKey(name) != key {
+			t.Fatalf("%s: key not stable", name)
+		}
+	}
+	// Names that already are keys keep them, unless a catalog provider has it
+	for name, want := range map[string]string{"MyRelay": "myrelay", "my-relay": "my-relay", "Custom": "custom", "Custom1": "custom1"} {
+		if got := providerKey(name); got != want {
+			t.Errorf("providerKey(%s) = %q, want %q", name, got, want)
+		}
+	}
+	if key := providerKey("mthreads"); key == "mthreads" {
+		t.Error("custom provider given a catalog provider's key")
+	}
+	// The catalog providers themselves use their declared ids
+	if id := resolveProvider("claude", &ModelConfig{ModelName: "摩尔线程"}).Id; id != "mthreads" {
+		t.Errorf("摩尔线程 id = %q", id)
+	}
+}
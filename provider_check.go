@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type ProviderTestResult struct {
+	Ok        bool   `json:"ok"`
+	Kind      string `json:"kind"`   // "ok", "auth", "quota", "model_not_found", "not_found", "rate_limited", "bad_request", "server" or "network"
+	Status    int    `json:"status"` // HTTP status, 0 if there was no response
+	Message   string `json:"message"`
+	LatencyMs int64  `json:"latency_ms"`
+	Protocol  string `json:"protocol"` // "anthropic", "openai-chat", "openai-responses" or "gemini"
+	Url       string `json:"url"`      // Endpoint the tool will call, after the same rewriting its config gets
+	Model     string `json:"model"`
+}
+
+// providerTestClient is replaced in tests to talk to a local stand-in server.
+var providerTestClient = &http.Client{Timeout: 30 * time.Second}
+
+// providerProtocol returns the wire protocol a tool speaks for a provider.
+func providerProtocol(tool string, provider resolvedProvider) string {
+	switch tool {
+	case "claude":
+		return "anthropic"
+	case "gemini":
+		return "gemini"
+	case "codex":
+		if provider.WireApi == "responses" {
+			return "openai-responses"
+		}
+	}
+	return "openai-chat"
+}
+
+// buildProviderTestRequest builds the smallest request that proves the key, URL and
+// model work, in the given protocol.
+func buildProviderTestRequest(protocol, baseUrl, modelId, apiKey string) (*http.Request, error) {
+	base := strings.TrimRight(baseUrl, "/")
+	var endpoint string
+	var body interface{}
+	switch protocol {
+	case "anthropic":
+		endpoint = base + "/v1/messages"
+		body = map[string]interface{}{
+			"model":      modelId,
+			"max_tokens": 1,
+			"messages":   []map[string]string{{"role": "user", "content": "ping"}},
+		}
+	case "openai-responses":
+		endpoint = base + "/responses"
+		body = map[string]interface{}{
+			"model":             modelId,
+			"input":             "ping",
+			"max_output_tokens": 16,
+		}
+	case "gemini":
+		if base == "" {
+			base = "https://generativelanguage.googleapis.com"
+		}
+		endpoint = base + "/v1beta/models/" + url.PathEscape(modelId) + ":generateContent"
+		body = map[string]interface{}{
+			"contents":         []map[string]interface{}{{"parts": []map[string]string{{"text": "ping"}}}},
+			"generationConfig": map[string]int{"maxOutputTokens": 1},
+		}
+	default:
+		endpoint = chatCompletionsUrl(base)
+		body = map[string]interface{}{
+			"model":      modelId,
+			"max_tokens": 1,
+			"messages":   []map[string]string{{"role": "user", "content": "ping"}},
+		}
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "AICoder-App")
+	switch protocol {
+	case "anthropic":
+		// Claude is configured with ANTHROPIC_AUTH_TOKEN, which is sent as a bearer token
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		req.Header.Set("anthropic-version", "2023-06-01")
+	case "gemini":
+		req.Header.Set("x-goog-api-key", apiKey)
+	default:
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	return req, nil
+}
+
+// providerErrorMessage pulls the error text out of the common error body shapes.
+func providerErrorMessage(body []byte) string {
+	var resp struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+	}
+	if json.Unmarshal(body, &resp) == nil {
+		var nested struct {
+			Message string `json:"message"`
+		}
+		var plain string
+		switch {
+		case json.Unmarshal(resp.Error, &nested) == nil && nested.Message != "":
+			return nested.Message
+		case json.Unmarshal(resp.Error, &plain) == nil && plain != "":
+			return plain
+		case resp.Message != "":
+			return resp.Message
+		}
+	}
+	text := strings.TrimSpace(string(body))
+	if len(text) > 300 {
+		text = text[:300] + "..."
+	}
+	return text
+}
+
+// classifyProviderResponse maps a status and error text onto a failure kind.
+func classifyProviderResponse(status int, message string) string {
+	lower := strings.ToLower(message)
+	mentionsModel := strings.Contains(lower, "model") &&
+		(strings.Contains(lower, "not found") || strings.Contains(lower, "not exist") ||
+			strings.Contains(lower, "invalid") || strings.Contains(lower, "unknown") || strings.Contains(lower, "not supported"))
+	outOfQuota := strings.Contains(lower, "quota") || strings.Contains(lower, "insufficient") ||
+		strings.Contains(lower, "balance") || strings.Contains(lower, "billing")
+	switch {
+	case status >= 200 && status < 300:
+		return "ok"
+	case status == http.StatusPaymentRequired ||
+		((status == http.StatusTooManyRequests || status == http.StatusForbidden) && outOfQuota):
+		return "quota"
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return "auth"
+	case (status == http.StatusNotFound || status == http.StatusBadRequest) && mentionsModel:
+		return "model_not_found"
+	case status == http.StatusNotFound:
+		return "not_found"
+	case status == http.StatusTooManyRequests:
+		return "rate_limited"
+	case status >= 500:
+		return "server"
+	}
+	return "bad_request"
+}
+
+// checkProvider sends the test request and times it.
+func checkProvider(client *http.Client, protocol, baseUrl, modelId, apiKey string) ProviderTestResult {
+	result := ProviderTestResult{Protocol: protocol, Model: modelId}
+	req, err := buildProviderTestRequest(protocol, baseUrl, modelId, apiKey)
+	if err != nil {
+		result.Kind = "bad_request"
+		result.Message = err.Error()
+		return result
+	}
+	result.Url = req.URL.String()
+	start := time.Now()
+	resp, err := client.Do(req)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Kind = "network"
+		result.Message = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	result.Status = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		result.Message = providerErrorMessage(body)
+	}
+	result.Kind = classifyProviderResponse(resp.StatusCode, result.Message)
+	result.Ok = result.Kind == "ok"
+	return result
+}
+
+// TestProvider checks a provider's key, URL and model with a minimal request in the
+// protocol the tool itself will use, so problems show up before the terminal opens.
+func (a *App) TestProvider(tool string, providerName string) (ProviderTestResult, error) {
+	tool = strings.ToLower(tool)
+	config, err := a.LoadConfig()
+	if err != nil {
+		return ProviderTestResult{}, err
+	}
+	toolCfg := getToolConfig(&config, tool)
+	if toolCfg == nil {
+		return ProviderTestResult{}, fmt.Errorf("unknown tool %s", tool)
+	}
+	var m *ModelConfig
+	for i := range toolCfg.Models {
+		if toolCfg.Models[i].ModelName == providerName {
+			m = &toolCfg.Models[i]
+			break
+		}
+	}
+	if m == nil {
+		return ProviderTestResult{}, fmt.Errorf("provider %s not found for %s", providerName, tool)
+	}
+	if strings.EqualFold(m.ModelName, "Original") {
+		return ProviderTestResult{}, fmt.Errorf("the official login cannot be tested here")
+	}
+	result := probeProviderKey(tool, m, m.ApiKey)
+	a.log(fmt.Sprintf("Tested %s for %s: %s (HTTP %d, %d ms)", providerName, tool, result.Kind, result.Status, result.LatencyMs))
+	return result, nil
+}
+
+// probeProviderKey runs the provider check for one of the provider's keys.
+func probeProviderKey(tool string, m *ModelConfig, apiKey string) ProviderTestResult {
+	provider := resolveProvider(tool, m)
+	// CodeBuddy and Qoder accept a comma-separated list; the first one is enough
+	modelId := strings.TrimSpace(strings.Split(provider.ModelId, ",")[0])
+	return checkProvider(providerTestClient, providerProtocol(tool, provider), provider.BaseUrl, modelId, apiKey)
+}
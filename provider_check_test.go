@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckProviderUrls(t *testing.T) {
+	type request struct {
+		path, auth, googKey, version, model string
+	}
+	var got request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		got = request{r.URL.EscapedPath(), r.Header.Get("Authorization"), r.Header.Get("x-goog-api-key"), r.Header.Get("anthropic-version"), body.Model}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	for _, c := range []struct {
+		protocol string
+		base     string
+		model    string
+		want     request
+	}{
+		{"anthropic", srv.URL, "claude-x", request{path: "/v1/messages", auth: "Bearer sk-1", version: "2023-06-01", model: "claude-x"}},
+		{"anthropic", srv.URL + "/api/anthropic/", "glm", request{path: "/api/anthropic/v1/messages", auth: "Bearer sk-1", version: "2023-06-01", model: "glm"}},
+		{"openai-chat", srv.URL + "/v1", "m-1", request{path: "/v1/chat/completions", auth: "Bearer sk-1", model: "m-1"}},
+		{"openai-chat", srv.URL + "/v1/chat/completions", "m-1", request{path: "/v1/chat/completions", auth: "Bearer sk-1", model: "m-1"}},
+		{"openai-responses", srv.URL + "/v1/", "gpt-x", request{path: "/v1/responses", auth: "Bearer sk-1", model: "gpt-x"}},
+		{"gemini", srv.URL, "gemini-x", request{path: "/v1beta/models/gemini-x:generateContent", googKey: "sk-1"}},
+		{"gemini", srv.URL, "tuned/a b", request{path: "/v1beta/models/tuned%2Fa%20b:generateContent", googKey: "sk-1"}},
+	} {
+		got = request{}
+		r := checkProvider(http.DefaultClient, c.protocol, c.base, c.model, "sk-1")
+		if !r.Ok || r.Kind != "ok" || r.Status != http.StatusOK {
+			t.Errorf("%s %s: %+v", c.protocol, c.base, r)
+		}
+		if got != c.want {
+			t.Errorf("%s %s sent %+v, want %+v", c.protocol, c.base, got, c.want)
+		}
+		if r.Url != srv.URL+c.want.path || r.Protocol != c.protocol || r.Model != c.model {
+			t.Errorf("%s %s reported %s %s %s", c.protocol, c.base, r.Url, r.Protocol, r.Model)
+		}
+	}
+
+	req, err := buildProviderTestRequest("gemini", "", "gemini-x", "k")
+	if err != nil || req.URL.String() != "https://generativelanguage.googleapis.com/v1beta/models/gemini-x:generateContent" {
+		t.Fatalf("default Gemini endpoint = %v, %v", req.URL, err)
+	}
+}
+
+func TestCheckProviderClassifiesFailures(t *testing.T) {
+	for _, c := range []struct {
+		status  int
+		body    string
+		kind    string
+		message string
+	}{
+		{http.StatusUnauthorized, `{"error":{"message":"invalid x-api-key","type":"authentication_error"}}`, "auth", "invalid x-api-key"},
+		{http.StatusForbidden, `{"message":"forbidden"}`, "auth", "forbidden"},
+		{http.StatusForbidden, `{"error":{"message":"Insufficient balance"}}`, "quota", "Insufficient balance"},
+		{http.StatusPaymentRequired, `payment required`, "quota", "payment required"},
+		{http.StatusTooManyRequests, `{"error":{"message":"You exceeded your current quota"}}`, "quota", "You exceeded your current quota"},
+		{http.StatusTooManyRequests, `{"error":{"message":"Rate limit reached"}}`, "rate_limited", "Rate limit reached"},
+		{http.StatusNotFound, `{"error":{"message":"The model 'm-9' does not exist"}}`, "model_not_found", "The model 'm-9' does not exist"},
+		{http.StatusBadRequest, `{"error":"Unknown model: m-9"}`, "model_not_found", "Unknown model: m-9"},
+		{http.StatusNotFound, `<html>404 page not found</html>`, "not_found", "<html>404 page not found</html>"},
+		{http.StatusBadRequest, `{"error":{"message":"max_tokens too small"}}`, "bad_request", "max_tokens too small"},
+		{http.StatusServiceUnavailable, `upstream overloaded`, "server", "upstream overloaded"},
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(c.status)
+			w.Write([]byte(c.body))
+		}))
+		r := checkProvider(http.DefaultClient, "openai-chat", srv.URL, "m-1", "sk-1")
+		srv.Close()
+		if r.Ok || r.Kind != c.kind || r.Status != c.status || r.Message != c.message {
+			t.Errorf("%d %s = %s %d %q, want %s %q", c.status, c.body, r.Kind, r.Status, r.Message, c.kind, c.message)
+		}
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Close()
+	if r := checkProvider(http.DefaultClient, "anthropic", srv.URL, "m-1", "sk-1"); r.Kind != "network" || r.Status != 0 || r.Ok || r.Message == "" {
+		t.Fatalf("unreachable provider = %+v", r)
+	}
+}
+
+// TestProvider picks the protocol from the tool and the provider's wire API.
+func TestTestProviderUsesToolProtocol(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	a := newSecretTestApp(t, newFakeSecretBackend("fake"))
+	t.Setenv("HOME", a.testHomeDir)
+	config := secretTestConfig()
+	config.Claude = ToolConfig{CurrentModel: "Gateway", Models: []ModelConfig{
+		{ModelName: "Original"},
+		{ModelName: "Gateway", IsCustom: true, ModelUrl: srv.URL, ModelId: "claude-x", ApiKey: "sk-1"},
+	}}
+	config.Codex = ToolConfig{CurrentModel: "Chat", Models: []ModelConfig{
+		{ModelName: "Original"},
+		{ModelName: "Chat", IsCustom: true, ModelUrl: srv.URL + "/v1", ModelId: "m-1, m-2", ApiKey: "sk-1", WireApi: "chat"},
+		{ModelName: "Responses", IsCustom: true, ModelUrl: srv.URL + "/v1", ModelId: "gpt-x", ApiKey: "sk-1", WireApi: "responses"},
+	}}
+	config.Gemini = ToolConfig{CurrentModel: "Proxy", Models: []ModelConfig{
+		{ModelName: "Original"},
+		{ModelName: "Proxy", IsCustom: true, ModelUrl: srv.URL, ModelId: "gemini-x", ApiKey: "sk-1"},
+	}}
+	path, _ := a.getConfigPath()
+	if err := a.saveToPath(path, config); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		tool, provider, protocol, path, model string
+	}{
+		{"claude", "Gateway", "anthropic", "/v1/messages", "claude-x"},
+		{"Codex", "Chat", "openai-chat", "/v1/chat/completions", "m-1"},
+		{"codex", "Responses", "openai-responses", "/v1/responses", "gpt-x"},
+		{"gemini", "Proxy", "gemini", "/v1beta/models/gemini-x:generateContent", "gemini-x"},
+	} {
+		paths = nil
+		r, err := a.TestProvider(c.tool, c.provider)
+		if err != nil || !r.Ok || r.Protocol != c.protocol || r.Model != c.model || r.Url != srv.URL+c.path {
+			t.Errorf("%s/%s = %+v, %v", c.tool, c.provider, r, err)
+		}
+		if strings.Join(paths, ",") != c.path {
+			t.Errorf("%s/%s requested %v", c.tool, c.provider, paths)
+		}
+	}
+	if _, err := a.TestProvider("codex", "Original"); err == nil {
+		t.Error("official login tested")
+	}
+	if _, err := a.TestProvider("codex", "Missing"); err == nil {
+		t.Error("unknown provider tested")
+	}
+}
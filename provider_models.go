@@ -0,0 +1,216 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// modelListTTL is how long a fetched model list is reused before asking the provider again.
+const modelListTTL = 30 * time.Minute
+
+type ProviderModelList struct {
+	Models    []string `json:"models"`
+	Source    string   `json:"source"`     // "remote", "cache" or "catalog"
+	FetchedAt string   `json:"fetched_at"` // When the remote list was fetched, empty for the catalog
+	Error     string   `json:"error"`      // Why the provider could not be asked, when falling back to the catalog
+}
+
+type modelListCacheEntry struct {
+	models  []string
+	fetched time.Time
+}
+
+var (
+	modelListMutex sync.Mutex
+	modelListCache = make(map[string]modelListCacheEntry)
+	// modelListClient is replaced in tests to talk to a local stand-in server
+	modelListClient = &http.Client{Timeout: 15 * time.Second}
+)
+
+// modelListCacheKey identifies a provider endpoint and key without keeping the key itself.
+func modelListCacheKey(style, baseUrl, apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return style + "|" + strings.TrimRight(baseUrl, "/") + "|" + hex.EncodeToString(sum[:8])
+}
+
+// modelListStyle returns "anthropic" for tools that talk to Anthropic-style endpoints.
+func modelListStyle(tool string) string {
+	if tool == "claude" {
+		return "anthropic"
+	}
+	return "openai"
+}
+
+// modelListUrls returns the endpoints to try in order. OpenAI-style base URLs usually
+// end in /v1 already; Anthropic-style ones never do.
+func modelListUrls(style, baseUrl string) []string {
+	base := strings.TrimRight(baseUrl, "/")
+	if strings.HasSuffix(base, "/v1") {
+		return []string{base + "/models"}
+	}
+	if style == "anthropic" {
+		return []string{base + "/v1/models"}
+	}
+	return []string{base + "/models", base + "/v1/models"}
+}
+
+// fetchProviderModels lists the model ids a provider serves via GET /models.
+func fetchProviderModels(client *http.Client, style, baseUrl, apiKey string) ([]string, error) {
+	if baseUrl == "" {
+		return nil, fmt.Errorf("provider has no base URL")
+	}
+	var lastErr error
+	for _, u := range modelListUrls(style, baseUrl) {
+		models, status, err := fetchModelList(client, style, u, apiKey)
+		if err == nil {
+			return models, nil
+		}
+		lastErr = err
+		// Only a missing endpoint is worth another path; auth errors are final
+		if status != http.StatusNotFound {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+func fetchModelList(client *http.Client, style, u, apiKey string) ([]string, int, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "AICoder-App")
+	if apiKey != "" {
+		// Anthropic-compatible gateways differ in which header they read, so send both
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		if style == "anthropic" {
+			req.Header.Set("x-api-key", apiKey)
+		}
+	}
+	if style == "anthropic" {
+		req.Header.Set("anthropic-version", "2023-06-01")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	models, err := parseModelList(body)
+	return models, resp.StatusCode, err
+}
+
+// parseModelList reads {"data":[{"id":...}]} as returned by OpenAI and Anthropic,
+// and the {"models":[...]} variant some gateways use.
+func parseModelList(body []byte) ([]string, error) {
+	type entry struct {
+		Id   string `json:"id"`
+		Name string `json:"name"`
+	}
+	var resp struct {
+		Data   []entry `json:"data"`
+		Models []entry `json:"models"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("unexpected model list response: %v", err)
+	}
+	seen := make(map[string]bool)
+	var models []string
+	for _, e := range append(resp.Data, resp.Models...) {
+		id := e.Id
+		if id == "" {
+			id = strings.TrimPrefix(e.Name, "models/")
+		}
+		if id != "" && !seen[id] {
+			seen[id] = true
+			models = append(models, id)
+		}
+	}
+	if len(models) == 0 {
+		return nil, fmt.Errorf("provider returned no models")
+	}
+	sort.Strings(models)
+	return models, nil
+}
+
+// catalogModelIds lists the model ids the catalog and the config know for a provider,
+// for providers that cannot be asked.
+func catalogModelIds(tool string, m *ModelConfig) []string {
+	provider := resolveProvider(tool, m)
+	var ids []string
+	seen := make(map[string]bool)
+	for _, id := range []string{provider.ModelId, provider.Roles.Fast, provider.Roles.Reasoning, provider.Roles.Compact} {
+		for _, part := range strings.Split(id, ",") {
+			part = strings.TrimSpace(part)
+			if part != "" && !seen[part] {
+				seen[part] = true
+				ids = append(ids, part)
+			}
+		}
+	}
+	return ids
+}
+
+// ListProviderModels returns the models a provider offers, for picking the model id
+// and role models. Lists are cached per endpoint and key; providers that cannot be
+// listed fall back to the catalog defaults.
+func (a *App) ListProviderModels(tool string, providerName string) (ProviderModelList, error) {
+	tool = strings.ToLower(tool)
+	config, err := a.LoadConfig()
+	if err != nil {
+		return ProviderModelList{}, err
+	}
+	toolCfg := getToolConfig(&config, tool)
+	if toolCfg == nil {
+		return ProviderModelList{}, fmt.Errorf("unknown tool %s", tool)
+	}
+	var m *ModelConfig
+	for i := range toolCfg.Models {
+		if toolCfg.Models[i].ModelName == providerName {
+			m = &toolCfg.Models[i]
+			break
+		}
+	}
+	if m == nil {
+		return ProviderModelList{}, fmt.Errorf("provider %s not found for %s", providerName, tool)
+	}
+	if strings.EqualFold(m.ModelName, "Original") {
+		return ProviderModelList{}, fmt.Errorf("the official login has no model list")
+	}
+	provider := resolveProvider(tool, m)
+	style := modelListStyle(tool)
+	key := modelListCacheKey(style, provider.BaseUrl, m.ApiKey)
+
+	modelListMutex.Lock()
+	cached, ok := modelListCache[key]
+	modelListMutex.Unlock()
+	if ok && time.Since(cached.fetched) < modelListTTL {
+		return ProviderModelList{Models: cached.models, Source: "cache", FetchedAt: cached.fetched.Format(time.RFC3339)}, nil
+	}
+
+	models, err := fetchProviderModels(modelListClient, style, provider.BaseUrl, m.ApiKey)
+	if err != nil {
+		a.log(fmt.Sprintf("Listing models of %s for %s failed: %v", providerName, tool, err))
+		return ProviderModelList{Models: catalogModelIds(tool, m), Source: "catalog", Error: err.Error()}, nil
+	}
+	now := time.Now()
+	modelListMutex.Lock()
+	modelListCache[key] = modelListCacheEntry{models: models, fetched: now}
+	modelListMutex.Unlock()
+	return ProviderModelList{Models: models, Source: "remote", FetchedAt: now.Format(time.RFC3339)}, nil
+}
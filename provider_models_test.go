@@ -0,0 +1,175 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newModelListTestApp returns an App whose config has m as a provider of tool,
+// and forgets cached model lists afterwards.
+func newModelListTestApp(t *testing.T, tool string, m ModelConfig) *App {
+	t.Helper()
+	a := newSecretTestApp(t, newFakeSecretBackend("fake"))
+	t.Setenv("HOME", a.testHomeDir)
+	config := secretTestConfig()
+	*getToolConfig(&config, tool) = ToolConfig{CurrentModel: m.ModelName, Models: []ModelConfig{{ModelName: "Original"}, m}}
+	path, _ := a.getConfigPath()
+	if err := a.saveToPath(path, config); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		modelListMutex.Lock()
+		modelListCache = make(map[string]modelListCacheEntry)
+		modelListMutex.Unlock()
+	})
+	return a
+}
+
+func TestListProviderModelsOpenAIStyle(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path != "/v1/models" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer sk-openai" || r.Header.Get("x-api-key") != "" {
+			t.Errorf("auth headers = %v", r.Header)
+		}
+		w.Write([]byte(`{"object":"list","data":[{"id":"m-b"},{"id":"m-a"},{"id":"m-b"}]}`))
+	}))
+	defer srv.Close()
+	a := newModelListTestApp(t, "codex", ModelConfig{ModelName: "Proxy", IsCustom: true, ModelUrl: srv.URL + "/v1/", ApiKey: "sk-openai"})
+
+	list, err := a.ListProviderModels("Codex", "Proxy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Source != "remote" || strings.Join(list.Models, ",") != "m-a,m-b" || list.FetchedAt == "" {
+		t.Fatalf("list = %+v", list)
+	}
+	// The second call is served from the cache
+	list, err = a.ListProviderModels("codex", "Proxy")
+	if err != nil || list.Source != "cache" || strings.Join(list.Models, ",") != "m-a,m-b" {
+		t.Fatalf("cached list = %+v, %v", list, err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("%d requests, want 1", n)
+	}
+	// An expired entry is fetched again
+	modelListMutex.Lock()
+	for k, e := range modelListCache {
+		e.fetched = e.fetched.Add(-modelListTTL - time.Second)
+		modelListCache[k] = e
+	}
+	modelListMutex.Unlock()
+	if list, _ := a.ListProviderModels("codex", "Proxy"); list.Source != "remote" {
+		t.Fatalf("expired list source = %s", list.Source)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Fatalf("%d requests after expiry, want 2", n)
+	}
+}
+
+func TestListProviderModelsAnthropicStyle(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("x-api-key") != "sk-ant" || r.Header.Get("Authorization") != "Bearer sk-ant" {
+			t.Errorf("auth headers = %v", r.Header)
+		}
+		if r.Header.Get("anthropic-version") == "" {
+			t.Error("anthropic-version not sent")
+		}
+		w.Write([]byte(`{"data":[{"type":"model","id":"claude-x","display_name":"X"}],"has_more":false}`))
+	}))
+	defer srv.Close()
+	a := newModelListTestApp(t, "claude", ModelConfig{ModelName: "Gateway", IsCustom: true, ModelUrl: srv.URL, ApiKey: "sk-ant"})
+
+	list, err := a.ListProviderModels("claude", "Gateway")
+	if err != nil || list.Source != "remote" || strings.Join(list.Models, ",") != "claude-x" {
+		t.Fatalf("list = %+v, %v", list, err)
+	}
+}
+
+func TestListProviderModelsTriesV1(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path != "/api/v1/models" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"models":[{"name":"models/gemini-x"},{"id":"m-1"}]}`))
+	}))
+	defer srv.Close()
+	a := newModelListTestApp(t, "opencode", ModelConfig{ModelName: "Proxy", IsCustom: true, ModelUrl: srv.URL + "/api", ApiKey: "sk-1"})
+
+	list, err := a.ListProviderModels("opencode", "Proxy")
+	if err != nil || strings.Join(list.Models, ",") != "gemini-x,m-1" {
+		t.Fatalf("list = %+v, %v", list, err)
+	}
+	if strings.Join(paths, ",") != "/api/models,/api/v1/models" {
+		t.Fatalf("paths = %v", paths)
+	}
+}
+
+func TestListProviderModelsFallsBackToCatalog(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	m := ModelConfig{ModelName: "Proxy", IsCustom: true, ModelUrl: srv.URL, ApiKey: "sk-bad", ModelId: "m-main"}
+	m.Roles.Fast = "m-fast, m-main"
+	a := newModelListTestApp(t, "codex", m)
+
+	list, err := a.ListProviderModels("codex", "Proxy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Source != "catalog" || list.Error == "" || list.FetchedAt != "" {
+		t.Fatalf("list = %+v", list)
+	}
+	if strings.Join(list.Models, ",") != "m-main,m-fast" {
+		t.Fatalf("models = %v", list.Models)
+	}
+	// An auth error is final: the /v1 path is not tried, and nothing is cached
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("%d requests, want 1", n)
+	}
+	if list, _ := a.ListProviderModels("codex", "Proxy"); list.Source != "catalog" {
+		t.Fatalf("failure cached: %+v", list)
+	}
+
+	if _, err := a.ListProviderModels("codex", "Missing"); err == nil {
+		t.Fatal("unknown provider listed")
+	}
+	if _, err := a.ListProviderModels("codex", "Original"); err == nil {
+		t.Fatal("official login listed")
+	}
+}
+
+func TestParseModelList(t *testing.T) {
+	for _, c := range []struct {
+		body string
+		want string
+		ok   bool
+	}{
+		{`{"data":[{"id":"b"},{"id":"a"}]}`, "a,b", true},
+		{`{"models":[{"name":"models/g-1"}]}`, "g-1", true},
+		{`{"data":[]}`, "", false},
+		{`<html>`, "", false},
+	} {
+		models, err := parseModelList([]byte(c.body))
+		if (err == nil) != c.ok || strings.Join(models, ",") != c.want {
+			t.Errorf("%s = %v, %v", c.body, models, err)
+		}
+	}
+}
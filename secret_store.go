@@ -0,0 +1,579 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/scrypt"
+)
+
+// secretRefPrefix marks a config value that names a secret instead of holding it.
+// A reference reads "aicoder-secret:<backend>:<account>".
+const secretRefPrefix = "aicoder-secret:"
+
+// keyringService is the service name secrets are filed under in the OS keyring.
+const keyringService = "AICoder"
+
+var errSecretNotFound = errors.New("secret not found")
+
+// secretBackend keeps secrets by account name. Backends are swappable so the store
+// can run headless, and in tests, without a keyring daemon.
+type secretBackend interface {
+	name() string
+	get(account string) (string, error)
+	set(account, secret string) error
+	remove(account string) error
+}
+
+// keyringSecretBackend uses the Secret Service on Linux, the Keychain on macOS
+// and the Credential Manager on Windows.
+type keyringSecretBackend struct{}
+
+func (keyringSecretBackend) name() string { return "keyring" }
+
+func (keyringSecretBackend) get(account string) (string, error) {
+	secret, err := keyring.Get(keyringService, account)
+	if err == keyring.ErrNotFound {
+		return "", errSecretNotFound
+	}
+	return secret, err
+}
+
+func (keyringSecretBackend) set(account, secret string) error {
+	return keyring.Set(keyringService, account, secret)
+}
+
+func (keyringSecretBackend) remove(account string) error {
+	if err := keyring.Delete(keyringService, account); err != nil && err != keyring.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+var keyringProbe struct {
+	once sync.Once
+	ok   bool
+}
+
+// keyringAvailable checks that the keyring can store and return a value, which
+// fails without a Secret Service on the session bus. The check writes a secret,
+// so it runs once per process.
+func keyringAvailable() bool {
+	keyringProbe.once.Do(func() {
+		b := keyringSecretBackend{}
+		const probe = "availability-check"
+		if err := b.set(probe, "ok"); err != nil {
+			return
+		}
+		defer b.remove(probe)
+		v, err := b.get(probe)
+		keyringProbe.ok = err == nil && v == "ok"
+	})
+	return keyringProbe.ok
+}
+
+// fileSecretBackend keeps secrets in one AES-GCM encrypted file. The key comes from
+// AICODER_SECRETS_PASSPHRASE when set, otherwise from a random key file next to it.
+// Both files are readable by the owner only. Without a passphrase the key sits next
+// to the secrets, which keeps them out of the config file but not from anyone who
+// can read the home directory.
+type fileSecretBackend struct {
+	mu      sync.Mutex
+	path    string
+	keyPath string
+	derived *derivedSecretsKey // Guarded by mu
+}
+
+// derivedSecretsKey is the scrypt key for a passphrase and salt. It is kept, and the
+// salt reused on save, so the key is derived once per process and not on every
+// read and write.
+type derivedSecretsKey struct {
+	passphrase string
+	salt       []byte
+	key        []byte
+}
+
+type secretFile struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func newFileSecretBackend(dir string) *fileSecretBackend {
+	return &fileSecretBackend{
+		path:    filepath.Join(dir, ".aicoder_secrets"),
+		keyPath: filepath.Join(dir, ".aicoder_secrets.key"),
+	}
+}
+
+func (b *fileSecretBackend) name() string { return "file" }
+
+func secretsPassphrase() string {
+	return os.Getenv("AICODER_SECRETS_PASSPHRASE")
+}
+
+// fileKey returns the encryption key for a file with the given salt.
+func (b *fileSecretBackend) fileKey(salt []byte) ([]byte, error) {
+	if passphrase := secretsPassphrase(); passphrase != "" {
+		if d := b.derived; d != nil && d.passphrase == passphrase && bytes.Equal(d.salt, salt) {
+			return d.key, nil
+		}
+		key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+		if err != nil {
+			return nil, err
+		}
+		b.derived = &derivedSecretsKey{passphrase: passphrase, salt: salt, key: key}
+		return key, nil
+	}
+	key, err := os.ReadFile(b.keyPath)
+	if err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if _, statErr := os.Stat(b.path); statErr == nil {
+		return nil, fmt.Errorf("%s is missing; the secrets in %s cannot be read", b.keyPath, b.path)
+	}
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := atomicWriteFile(b.keyPath, key, 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (b *fileSecretBackend) load() (map[string]string, error) {
+	secrets := make(map[string]string)
+	data, err := os.ReadFile(b.path)
+	if os.IsNotExist(err) {
+		return secrets, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f secretFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %v", b.path, err)
+	}
+	key, err := b.fileKey(f.Salt)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newBundleCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, f.Nonce, f.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s cannot be decrypted", b.path)
+	}
+	err = json.Unmarshal(plain, &secrets)
+	return secrets, err
+}
+
+func (b *fileSecretBackend) save(secrets map[string]string) error {
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	f := secretFile{Version: 1, Salt: make([]byte, 16), Nonce: make([]byte, 12)}
+	if d := b.derived; d != nil && d.passphrase == secretsPassphrase() {
+		// A fresh nonce is enough for a new ciphertext under the same key
+		f.Salt = d.salt
+	} else if _, err := rand.Read(f.Salt); err != nil {
+		return err
+	}
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return err
+	}
+	key, err := b.fileKey(f.Salt)
+	if err != nil {
+		return err
+	}
+	gcm, err := newBundleCipher(key)
+	if err != nil {
+		return err
+	}
+	f.Ciphertext = gcm.Seal(nil, f.Nonce, plain, nil)
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return atomicWriteFile(b.path, data, 0600)
+}
+
+func (b *fileSecretBackend) get(account string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	secrets, err := b.load()
+	if err != nil {
+		return "", err
+	}
+	secret, ok := secrets[account]
+	if !ok {
+		return "", errSecretNotFound
+	}
+	return secret, nil
+}
+
+func (b *fileSecretBackend) set(account, secret string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	secrets, err := b.load()
+	if err != nil {
+		return err
+	}
+	secrets[account] = secret
+	return b.save(secrets)
+}
+
+func (b *fileSecretBackend) remove(account string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	secrets, err := b.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[account]; !ok {
+		return nil
+	}
+	delete(secrets, account)
+	return b.save(secrets)
+}
+
+// secretStore moves secrets between the config and a backend. It caches what it
+// has read or written, since the config is loaded often.
+type secretStore struct {
+	mu       sync.Mutex
+	primary  secretBackend
+	backends map[string]secretBackend
+	cache    map[string]string // Reference -> secret
+	// Account -> reference that could not be read, e.g. while the keyring was
+	// locked. The reference is written back and its secret never removed.
+	unresolved map[string]string
+}
+
+func newSecretStore(primary secretBackend, others ...secretBackend) *secretStore {
+	s := &secretStore{
+		primary:    primary,
+		backends:   make(map[string]secretBackend),
+		cache:      make(map[string]string),
+		unresolved: make(map[string]string),
+	}
+	for _, b := range append([]secretBackend{primary}, others...) {
+		s.backends[b.name()] = b
+	}
+	return s
+}
+
+// secrets returns the App's secret store. AICODER_SECRET_BACKEND=file or keyring
+// forces a backend; by default the keyring is used when it works.
+func (a *App) secrets() *secretStore {
+	a.secretsOnce.Do(func() {
+		if a.secretStore != nil {
+			return
+		}
+		file := newFileSecretBackend(a.GetUserHomeDir())
+		useKeyring := false
+		switch os.Getenv("AICODER_SECRET_BACKEND") {
+		case "file":
+		case "keyring":
+			useKeyring = true
+		default:
+			// A test home directory must not reach into the real keyring
+			useKeyring = a.testHomeDir == "" && keyringAvailable()
+		}
+		if useKeyring {
+			a.secretStore = newSecretStore(keyringSecretBackend{}, file)
+		} else {
+			a.secretStore = newSecretStore(file, keyringSecretBackend{})
+		}
+		a.log("Secret store: using " + a.secretStore.primary.name())
+	})
+	return a.secretStore
+}
+
+func isSecretRef(v string) bool {
+	return strings.HasPrefix(v, secretRefPrefix)
+}
+
+func secretRef(backend, account string) string {
+	return secretRefPrefix + backend + ":" + account
+}
+
+func parseSecretRef(ref string) (backend, account string, ok bool) {
+	rest := strings.TrimPrefix(ref, secretRefPrefix)
+	backend, account, ok = strings.Cut(rest, ":")
+	return backend, account, ok && isSecretRef(ref)
+}
+
+// forEachSecret calls fn with the account name and address of every secret in config.
+// Every secret gets its own account, even under a provider name used twice in a tool.
+func forEachSecret(config *AppConfig, fn func(account string, value *string)) {
+	for _, tool := range toolNames {
+		seen := make(map[string]bool)
+		for i := range getToolConfig(config, tool).Models {
+			m := &getToolConfig(config, tool).Models[i]
+			base := "apikey/" + tool + "/" + strings.ToLower(m.ModelName)
+			if seen[base] {
+				base += "#" + strconv.Itoa(i)
+			}
+			seen[base] = true
+			fn(base, &m.ApiKey)
+			for k := range m.ApiKeys {
+				fn(base+"/"+strconv.Itoa(k), &m.ApiKeys[k].Key)
+			}
+		}
+	}
+	fn("proxy/default", &config.DefaultProxyPassword)
+	fn("github/token", &config.GitHubToken)
+	for i := range config.Projects {
+		id := config.Projects[i].Id
+		if id == "" {
+			id = "#" + strconv.Itoa(i)
+		}
+		fn("proxy/project/"+id, &config.Projects[i].ProxyPassword)
+	}
+}
+
+// resolve replaces the references in config with the secrets. It reports how many
+// secrets were still stored in plain text, so the caller can move them out. A secret
+// that cannot be read is left empty and its reference kept for the next save.
+func (s *secretStore) resolve(config *AppConfig, logf func(string)) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	plain := 0
+	forEachSecret(config, func(account string, value *string) {
+		if *value == "" {
+			return
+		}
+		if !isSecretRef(*value) {
+			plain++
+			return
+		}
+		ref := *value
+		*value = ""
+		if secret, ok := s.cache[ref]; ok {
+			delete(s.unresolved, account)
+			*value = secret
+			return
+		}
+		backend, acct, ok := parseSecretRef(ref)
+		b := s.backends[backend]
+		if !ok || b == nil {
+			logf("Secret store: unknown reference " + ref)
+			s.unresolved[account] = ref
+			return
+		}
+		secret, err := b.get(acct)
+		if errors.Is(err, errSecretNotFound) {
+			logf("Secret store: " + acct + " is missing")
+			delete(s.unresolved, account)
+			return
+		}
+		if err != nil {
+			logf(fmt.Sprintf("Secret store: cannot read %s: %v", acct, err))
+			s.unresolved[account] = ref
+			return
+		}
+		delete(s.unresolved, account)
+		s.cache[ref] = secret
+		*value = secret
+	})
+	return plain
+}
+
+// externalize returns a copy of config with every secret replaced by a reference,
+// storing the secrets in the primary backend, or the file when the keyring refuses.
+// A secret resolve could not read keeps its reference until a new one is entered.
+// It also returns the references in use.
+func (s *secretStore) externalize(config AppConfig, logf func(string)) (AppConfig, map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var stored AppConfig
+	data, err := json.Marshal(config)
+	if err != nil {
+		return stored, nil, err
+	}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return stored, nil, err
+	}
+	refs := make(map[string]bool)
+	var firstErr error
+	forEachSecret(&stored, func(account string, value *string) {
+		if ref, ok := s.unresolved[account]; ok && *value == "" {
+			*value = ref
+		}
+		if *value == "" || firstErr != nil {
+			return
+		}
+		if isSecretRef(*value) {
+			refs[*value] = true
+			return
+		}
+		ref, err := s.put(account, *value, logf)
+		if err != nil {
+			firstErr = err
+			return
+		}
+		delete(s.unresolved, account)
+		refs[ref] = true
+		*value = ref
+	})
+	return stored, refs, firstErr
+}
+
+// writeOrder lists the backends secrets are written to: the primary, then the file
+// as the fallback.
+func (s *secretStore) writeOrder() []secretBackend {
+	order := []secretBackend{s.primary}
+	if file := s.backends["file"]; file != nil && file != s.primary {
+		order = append(order, file)
+	}
+	return order
+}
+
+// put stores one secret unless the cache shows it is already there.
+func (s *secretStore) put(account, secret string, logf func(string)) (string, error) {
+	for _, b := range s.writeOrder() {
+		ref := secretRef(b.name(), account)
+		if cached, ok := s.cache[ref]; ok && cached == secret {
+			return ref, nil
+		}
+		if err := b.set(account, secret); err != nil {
+			logf(fmt.Sprintf("Secret store: %s refused %s: %v", b.name(), account, err))
+			continue
+		}
+		s.cache[ref] = secret
+		return ref, nil
+	}
+	return "", fmt.Errorf("no secret store could save %s", account)
+}
+
+// secret returns the secret AICoder itself keeps under account, not one from the
+// config, creating it with create the first time.
+func (s *secretStore) secret(account string, create func() (string, error), logf func(string)) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, b := range s.writeOrder() {
+		ref := secretRef(b.name(), account)
+		if cached, ok := s.cache[ref]; ok {
+			return cached, nil
+		}
+		secret, err := b.get(account)
+		if err == nil {
+			s.cache[ref] = secret
+			return secret, nil
+		}
+		// Replacing a secret that merely could not be read would lose what it protects
+		if i == 0 && !errors.Is(err, errSecretNotFound) {
+			return "", err
+		}
+	}
+	secret, err := create()
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.put(account, secret, logf); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// removeUnused deletes secrets an older config referenced and the new one does not.
+// Secrets that could not be read are never removed.
+func (s *secretStore) removeUnused(old, current map[string]bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := make(map[string]bool)
+	for _, ref := range s.unresolved {
+		pending[ref] = true
+	}
+	for ref := range old {
+		if current[ref] || pending[ref] {
+			continue
+		}
+		if backend, account, ok := parseSecretRef(ref); ok && s.backends[backend] != nil {
+			s.backends[backend].remove(account)
+		}
+		delete(s.cache, ref)
+	}
+}
+
+// secretRefsInFile lists the references a saved config holds.
+func secretRefsInFile(path string) map[string]bool {
+	refs := make(map[string]bool)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return refs
+	}
+	var config AppConfig
+	if json.Unmarshal(data, &config) != nil {
+		return refs
+	}
+	forEachSecret(&config, func(account string, value *string) {
+		if isSecretRef(*value) {
+			refs[*value] = true
+		}
+	})
+	return refs
+}
+
+type SecretStoreStatus struct {
+	Backend          string `json:"backend"` // "keyring" or "file"
+	KeyringAvailable bool   `json:"keyring_available"`
+	Secrets          int    `json:"secrets"`   // Secrets referenced by the config
+	Plaintext        int    `json:"plaintext"` // Secrets still in the config file itself
+	// "keyring", "passphrase", or "key_file" when the file backend keeps its key
+	// next to the encrypted secrets, which is not protected storage
+	Protection string `json:"protection"`
+	Protected  bool   `json:"protected"`
+}
+
+// GetSecretStoreStatus reports where API keys and proxy passwords are kept.
+func (a *App) GetSecretStoreStatus() (SecretStoreStatus, error) {
+	backend := a.secrets().primary.name()
+	status := SecretStoreStatus{Backend: backend, Protection: backend, Protected: true}
+	// A test home directory must not reach into the real keyring
+	status.KeyringAvailable = a.testHomeDir == "" && keyringAvailable()
+	if backend == "file" {
+		status.Protection = "passphrase"
+		if secretsPassphrase() == "" {
+			status.Protection, status.Protected = "key_file", false
+		}
+	}
+	path, err := a.getConfigPath()
+	if err != nil {
+		return status, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return status, err
+	}
+	var config AppConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return status, err
+	}
+	forEachSecret(&config, func(account string, value *string) {
+		switch {
+		case isSecretRef(*value):
+			status.Secrets++
+		case *value != "":
+			status.Plaintext++
+		}
+	})
+	return status, nil
+}
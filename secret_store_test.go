@@ -0,0 +1,277 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// fakeSecretBackend keeps secrets in memory; getErr makes every read fail.
+type fakeSecretBackend struct {
+	mu      sync.Mutex
+	label   string
+	secrets map[string]string
+	getErr  error
+}
+
+func newFakeSecretBackend(label string) *fakeSecretBackend {
+	return &fakeSecretBackend{label: label, secrets: make(map[string]string)}
+}
+
+func (b *fakeSecretBackend) name() string { return b.label }
+
+func (b *fakeSecretBackend) get(account string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.getErr != nil {
+		return "", b.getErr
+	}
+	secret, ok := b.secrets[account]
+	if !ok {
+		return "", errSecretNotFound
+	}
+	return secret, nil
+}
+
+func (b *fakeSecretBackend) set(account, secret string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.secrets[account] = secret
+	return nil
+}
+
+func (b *fakeSecretBackend) remove(account string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.secrets, account)
+	return nil
+}
+
+func (b *fakeSecretBackend) has(account string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.secrets[account]
+	return ok
+}
+
+// newSecretTestApp returns an App with a test home whose secrets go to the fake backend.
+func newSecretTestApp(t *testing.T, backend secretBackend) *App {
+	t.Helper()
+	a := NewApp()
+	a.testHomeDir = t.TempDir()
+	a.secretStore = newSecretStore(backend, newFileSecretBackend(a.testHomeDir))
+	a.secretsOnce.Do(func() {})
+	return a
+}
+
+func secretTestConfig() AppConfig {
+	return AppConfig{
+		Claude: ToolConfig{CurrentModel: "GLM", Models: []ModelConfig{
+			{ModelName: "Original"},
+			{ModelName: "GLM", ApiKey: "sk-glm-secret-1"},
+		}},
+		DefaultProxyPassword: "proxy-password",
+		SchemaVersion:        latestSchemaVersion(),
+	}
+}
+
+func TestSecretStoreKeepsSecretsThatCannotBeRead(t *testing.T) {
+	backend := newFakeSecretBackend("fake")
+	a := newSecretTestApp(t, backend)
+	path, _ := a.getConfigPath()
+	if err := a.saveToPath(path, secretTestConfig()); err != nil {
+		t.Fatal(err)
+	}
+	if !backend.has("apikey/claude/glm") || !backend.has("proxy/default") {
+		t.Fatalf("secrets not stored: %v", backend.secrets)
+	}
+
+	// A fresh store, as after a restart, while the backend cannot be read
+	a.secretStore = newSecretStore(backend)
+	backend.getErr = errors.New("keyring locked")
+	config, err := a.readConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := getProviderModel(&config.Claude, "GLM"); m.ApiKey != "" {
+		t.Fatalf("unreadable secret resolved to %q", m.ApiKey)
+	}
+	// Saving the config with the empty value must keep the reference and the secret
+	config.EnvCheckInterval = 9
+	if err := a.saveToPath(path, config); err != nil {
+		t.Fatal(err)
+	}
+	if !backend.has("apikey/claude/glm") || !backend.has("proxy/default") {
+		t.Fatalf("save removed secrets that could not be read: %v", backend.secrets)
+	}
+	refs := secretRefsInFile(path)
+	if !refs[secretRef("fake", "apikey/claude/glm")] {
+		t.Fatalf("reference dropped from the config file: %v", refs)
+	}
+
+	// Once the backend works again the secret is back
+	backend.getErr = nil
+	config, err = a.readConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := getProviderModel(&config.Claude, "GLM"); m.ApiKey != "sk-glm-secret-1" {
+		t.Fatalf("secret after recovery = %q", m.ApiKey)
+	}
+}
+
+func TestSecretStoreReplacesUnreadableSecretWithNewOne(t *testing.T) {
+	backend := newFakeSecretBackend("fake")
+	a := newSecretTestApp(t, backend)
+	path, _ := a.getConfigPath()
+	if err := a.saveToPath(path, secretTestConfig()); err != nil {
+		t.Fatal(err)
+	}
+	a.secretStore = newSecretStore(backend)
+	backend.getErr = errors.New("keyring locked")
+	config, _ := a.readConfig()
+	getProviderModel(&config.Claude, "GLM").ApiKey = "sk-glm-secret-2"
+	if err := a.saveToPath(path, config); err != nil {
+		t.Fatal(err)
+	}
+	if backend.secrets["apikey/claude/glm"] != "sk-glm-secret-2" {
+		t.Fatalf("new key not stored: %v", backend.secrets)
+	}
+}
+
+func TestSecretStoreDropsMissingSecret(t *testing.T) {
+	backend := newFakeSecretBackend("fake")
+	a := newSecretTestApp(t, backend)
+	path, _ := a.getConfigPath()
+	if err := a.saveToPath(path, secretTestConfig()); err != nil {
+		t.Fatal(err)
+	}
+	a.secretStore = newSecretStore(backend)
+	backend.remove("apikey/claude/glm")
+	config, _ := a.readConfig()
+	if err := a.saveToPath(path, config); err != nil {
+		t.Fatal(err)
+	}
+	if refs := secretRefsInFile(path); refs[secretRef("fake", "apikey/claude/glm")] {
+		t.Fatal("reference to a deleted secret kept")
+	}
+}
+
+func TestForEachSecretGivesDuplicateNamesTheirOwnAccount(t *testing.T) {
+	config := AppConfig{Codex: ToolConfig{Models: []ModelConfig{
+		{ModelName: "Custom", IsCustom: true, ApiKey: "a"},
+		{ModelName: "custom", IsCustom: true, ApiKey: "b"},
+		{ModelName: "Custom", IsCustom: true, ApiKey: "c"},
+	}}}
+	accounts := make(map[string]string)
+	forEachSecret(&config, func(account string, value *string) {
+		if *value == "" {
+			return
+		}
+		if other, ok := accounts[account]; ok {
+			t.Fatalf("%s holds both %q and %q", account, other, *value)
+		}
+		accounts[account] = *value
+	})
+	if len(accounts) != 3 {
+		t.Fatalf("accounts = %v", accounts)
+	}
+}
+
+func TestSanitizeCustomNames(t *testing.T) {
+	models := []ModelConfig{
+		{ModelName: "Original"},
+		{ModelName: "", IsCustom: true},
+		{ModelName: " ", IsCustom: true},
+		{ModelName: "custom", IsCustom: true},
+		{ModelName: "original", IsCustom: true},
+	}
+	sanitizeCustomNames(models)
+	want := []string{"Original", "Custom", "Custom 2", "custom 3", "original 2"}
+	for i, m := range models {
+		if m.ModelName != want[i] {
+			t.Errorf("models[%d] = %q, want %q", i, m.ModelName, want[i])
+		}
+	}
+}
+
+func TestFileSecretBackendRoundTrip(t *testing.T) {
+	b := newFileSecretBackend(t.TempDir())
+	if err := b.set("apikey/claude/glm", "sk-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.set("proxy/default", "pw"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := b.get("apikey/claude/glm"); err != nil || v != "sk-1" {
+		t.Fatalf("get = %q, %v", v, err)
+	}
+	if err := b.remove("apikey/claude/glm"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.get("apikey/claude/glm"); !errors.Is(err, errSecretNotFound) {
+		t.Fatalf("removed secret: %v", err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(b.path), ".*.tmp-*"))
+	if len(matches) > 0 {
+		t.Fatalf("temporary files left: %v", matches)
+	}
+}
+
+// With a passphrase the scrypt key is derived once and the salt kept across saves.
+func TestFileSecretBackendDerivesPassphraseKeyOnce(t *testing.T) {
+	t.Setenv("AICODER_SECRETS_PASSPHRASE", "correct horse")
+	dir := t.TempDir()
+	b := newFileSecretBackend(dir)
+	if err := b.set("apikey/claude/glm", "sk-1"); err != nil {
+		t.Fatal(err)
+	}
+	derived := b.derived
+	if derived == nil {
+		t.Fatal("key not kept")
+	}
+	if err := b.set("proxy/default", "pw"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := b.get("apikey/claude/glm"); err != nil || v != "sk-1" || b.derived != derived {
+		t.Fatalf("get = %q, %v; key derived again: %v", v, err, b.derived != derived)
+	}
+	if _, err := os.Stat(b.keyPath); !os.IsNotExist(err) {
+		t.Fatal("key file written with a passphrase")
+	}
+
+	// Another process, with the right passphrase and then a wrong one
+	if v, err := newFileSecretBackend(dir).get("proxy/default"); err != nil || v != "pw" {
+		t.Fatalf("get = %q, %v", v, err)
+	}
+	t.Setenv("AICODER_SECRETS_PASSPHRASE", "wrong")
+	if _, err := b.get("proxy/default"); err == nil {
+		t.Fatal("decrypted with the wrong passphrase")
+	}
+}
+
+func TestSecretStoreStatusProtection(t *testing.T) {
+	a := NewApp()
+	a.testHomeDir = t.TempDir()
+	t.Setenv("HOME", a.testHomeDir)
+	t.Setenv("AICODER_SECRET_BACKEND", "file")
+	t.Setenv("AICODER_SECRETS_PASSPHRASE", "")
+	path, _ := a.getConfigPath()
+	if err := a.saveToPath(path, secretTestConfig()); err != nil {
+		t.Fatal(err)
+	}
+	status, err := a.GetSecretStoreStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The key file sits next to the secrets it encrypts
+	if status.Backend != "file" || status.Protection != "key_file" || status.Protected || status.KeyringAvailable || status.Secrets != 2 || status.Plaintext != 0 {
+		t.Fatalf("status = %+v", status)
+	}
+	t.Setenv("AICODER_SECRETS_PASSPHRASE", "correct horse")
+	if status, _ := a.GetSecretStoreStatus(); status.Protection != "passphrase" || !status.Protected {
+		t.Fatalf("status with a passphrase = %+v", status)
+	}
+}
@@ -0,0 +1,793 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// tomlDocument is a statement-level view of a TOML file. It understands enough
+// of the syntax to find tables and key/value pairs, and writes every statement
+// it did not touch back byte for byte, comments and formatting included.
+type tomlDocument struct {
+	stmts []tomlStatement
+}
+
+type tomlStatementKind int
+
+const (
+	tomlTrivia   tomlStatementKind = iota // Blank line or comment
+	tomlKeyValue                          // key = value, possibly spanning lines
+	tomlTable                             // [table] or [[array.of.tables]]
+)
+
+type tomlStatement struct {
+	kind  tomlStatementKind
+	text  string   // Original text without the trailing newline
+	key   []string // Key path of a key/value, or header path of a table
+	array bool     // [[...]] header
+	value string   // Raw value text of a key/value
+}
+
+func parseToml(data string) (*tomlDocument, error) {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	lines := strings.Split(data, "\n")
+	// A trailing newline does not start another statement
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	doc := &tomlDocument{}
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			doc.stmts = append(doc.stmts, tomlStatement{kind: tomlTrivia, text: line})
+		case strings.HasPrefix(trimmed, "["):
+			array := strings.HasPrefix(trimmed, "[[")
+			rest := trimmed[1:]
+			if array {
+				rest = trimmed[2:]
+			}
+			key, rest, err := parseTomlKey(rest)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", i+1, err)
+			}
+			closing := "]"
+			if array {
+				closing = "]]"
+			}
+			rest = strings.TrimSpace(rest)
+			if !strings.HasPrefix(rest, closing) {
+				return nil, fmt.Errorf("line %d: malformed table header", i+1)
+			}
+			if after := strings.TrimSpace(rest[len(closing):]); after != "" && !strings.HasPrefix(after, "#") {
+				return nil, fmt.Errorf("line %d: unexpected text after table header", i+1)
+			}
+			doc.stmts = append(doc.stmts, tomlStatement{kind: tomlTable, text: line, key: key, array: array})
+		default:
+			key, rest, err := parseTomlKey(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", i+1, err)
+			}
+			rest = strings.TrimLeft(rest, " \t")
+			if !strings.HasPrefix(rest, "=") {
+				return nil, fmt.Errorf("line %d: expected '=' after key", i+1)
+			}
+			value := rest[1:]
+			text := line
+			// Arrays and multi-line strings continue on the following lines
+			for {
+				complete, err := tomlValueComplete(value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v", i+1, err)
+				}
+				if complete {
+					break
+				}
+				if i+1 >= len(lines) {
+					return nil, fmt.Errorf("line %d: unterminated value", i+1)
+				}
+				i++
+				value += "\n" + lines[i]
+				text += "\n" + lines[i]
+			}
+			doc.stmts = append(doc.stmts, tomlStatement{kind: tomlKeyValue, text: text, key: key, value: value})
+		}
+	}
+	return doc, nil
+}
+
+// readTomlFile returns an empty document for a missing file.
+func readTomlFile(path string) (*tomlDocument, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &tomlDocument{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseToml(string(data))
+}
+
+func (d *tomlDocument) String() string {
+	var b strings.Builder
+	for _, s := range d.stmts {
+		b.WriteString(s.text)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// parseTomlKey reads a possibly dotted, possibly quoted key and returns its parts and the remaining text.
+func parseTomlKey(s string) ([]string, string, error) {
+	var parts []string
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return nil, "", fmt.Errorf("missing key")
+		}
+		switch s[0] {
+		case '"':
+			str, n, err := parseTomlBasicString(s)
+			if err != nil {
+				return nil, "", err
+			}
+			parts = append(parts, str)
+			s = s[n:]
+		case '\'':
+			end := strings.IndexByte(s[1:], '\'')
+			if end < 0 {
+				return nil, "", fmt.Errorf("unterminated quoted key")
+			}
+			parts = append(parts, s[1:end+1])
+			s = s[end+2:]
+		default:
+			n := 0
+			for n < len(s) && isTomlBareKeyChar(s[n]) {
+				n++
+			}
+			if n == 0 {
+				return nil, "", fmt.Errorf("invalid key")
+			}
+			parts = append(parts, s[:n])
+			s = s[n:]
+		}
+		rest := strings.TrimLeft(s, " \t")
+		if !strings.HasPrefix(rest, ".") {
+			return parts, s, nil
+		}
+		s = rest[1:]
+	}
+}
+
+func isTomlBareKeyChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '-'
+}
+
+// tomlValueComplete reports whether a raw value has closed all of its brackets and strings.
+func tomlValueComplete(s string) (bool, error) {
+	depth := 0
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], `"""`):
+			end := findTomlMultilineEnd(s, i+3, `"""`, true)
+			if end < 0 {
+				return false, nil
+			}
+			i = end
+		case strings.HasPrefix(s[i:], `'''`):
+			end := findTomlMultilineEnd(s, i+3, `'''`, false)
+			if end < 0 {
+				return false, nil
+			}
+			i = end
+		case s[i] == '"':
+			_, n, err := parseTomlBasicString(s[i:])
+			if err != nil {
+				return false, err
+			}
+			i += n
+		case s[i] == '\'':
+			end := strings.IndexAny(s[i+1:], "'\n")
+			if end < 0 || s[i+1+end] != '\'' {
+				return false, fmt.Errorf("unterminated literal string")
+			}
+			i += end + 2
+		case s[i] == '#':
+			nl := strings.IndexByte(s[i:], '\n')
+			if nl < 0 {
+				i = len(s)
+			} else {
+				i += nl
+			}
+		case s[i] == '[' || s[i] == '{':
+			depth++
+			i++
+		case s[i] == ']' || s[i] == '}':
+			depth--
+			if depth < 0 {
+				return false, fmt.Errorf("unbalanced brackets")
+			}
+			i++
+		default:
+			i++
+		}
+	}
+	return depth == 0, nil
+}
+
+// findTomlMultilineEnd returns the index just past the closing delimiter, or -1.
+func findTomlMultilineEnd(s string, from int, delim string, escapes bool) int {
+	for i := from; i < len(s); i++ {
+		if escapes && s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], delim) {
+			end := i + len(delim)
+			// Up to two quotes may directly precede the closing delimiter
+			for n := 0; n < 2 && end < len(s) && s[end] == delim[0]; n++ {
+				end++
+			}
+			return end
+		}
+	}
+	return -1
+}
+
+// parseTomlBasicString decodes a "..." string at the start of s and returns it with the number of bytes consumed.
+func parseTomlBasicString(s string) (string, int, error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '\n':
+			return "", 0, fmt.Errorf("unterminated string")
+		case '"':
+			str, err := unescapeToml(s[1:i])
+			return str, i + 1, err
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// unescapeToml resolves the escape sequences of a basic string body.
+func unescapeToml(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 >= len(s) {
+			return "", fmt.Errorf("trailing backslash")
+		}
+		i++
+		switch s[i] {
+		case 'b':
+			b.WriteByte('\b')
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'f':
+			b.WriteByte('\f')
+		case 'r':
+			b.WriteByte('\r')
+		case '"':
+			b.WriteByte('"')
+		case '\\':
+			b.WriteByte('\\')
+		case 'u', 'U':
+			size := 4
+			if s[i] == 'U' {
+				size = 8
+			}
+			if i+size >= len(s) {
+				return "", fmt.Errorf("invalid unicode escape")
+			}
+			r, err := strconv.ParseUint(s[i+1:i+1+size], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid unicode escape")
+			}
+			b.WriteRune(rune(r))
+			i += size
+		case ' ', '\t', '\n':
+			// Line-ending backslash: skip the newline and leading whitespace that follows
+			i = i + len(s[i:]) - len(strings.TrimLeft(s[i:], " \t\n")) - 1
+		default:
+			return "", fmt.Errorf("invalid escape \\%c", s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// decodeTomlValue decodes strings, booleans and numbers. Anything else
+// (arrays, inline tables, dates) is returned as its trimmed source text.
+func decodeTomlValue(raw string) interface{} {
+	s := strings.TrimSpace(raw)
+	switch {
+	case strings.HasPrefix(s, `"""`):
+		end := findTomlMultilineEnd(s, 3, `"""`, true)
+		if end > 0 {
+			if str, err := unescapeToml(strings.TrimPrefix(s[3:end-3], "\n")); err == nil {
+				return str
+			}
+		}
+	case strings.HasPrefix(s, `'''`):
+		end := findTomlMultilineEnd(s, 3, `'''`, false)
+		if end > 0 {
+			return strings.TrimPrefix(s[3:end-3], "\n")
+		}
+	case strings.HasPrefix(s, `"`):
+		if str, _, err := parseTomlBasicString(s); err == nil {
+			return str
+		}
+	case strings.HasPrefix(s, `'`):
+		if end := strings.IndexByte(s[1:], '\''); end >= 0 {
+			return s[1 : end+1]
+		}
+	}
+	if i := strings.IndexByte(s, '#'); i >= 0 && !strings.HasPrefix(s, "[") && !strings.HasPrefix(s, "{") {
+		s = strings.TrimSpace(s[:i])
+	}
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	plain := strings.ReplaceAll(s, "_", "")
+	if n, err := strconv.ParseInt(plain, 0, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(plain, 64); err == nil {
+		return f
+	}
+	return s
+}
+
+// tomlString quotes s as a TOML basic string.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// tomlKey returns k as a bare key when possible and quoted otherwise.
+func tomlKey(k string) string {
+	if k == "" {
+		return `""`
+	}
+	for i := 0; i < len(k); i++ {
+		if !isTomlBareKeyChar(k[i]) {
+			return tomlString(k)
+		}
+	}
+	return k
+}
+
+func tomlKeyPath(path []string) string {
+	parts := make([]string, len(path))
+	for i, p := range path {
+		parts[i] = tomlKey(p)
+	}
+	return strings.Join(parts, ".")
+}
+
+// tomlLiteral renders a Go value (as found in JSON-decoded catalog data) as a TOML value.
+func tomlLiteral(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return tomlString(val)
+	case bool:
+		return strconv.FormatBool(val)
+	case int:
+		return strconv.Itoa(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		if val == math.Trunc(val) && math.Abs(val) < 1e15 {
+			return strconv.FormatInt(int64(val), 10)
+		}
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	}
+	return tomlString(fmt.Sprint(v))
+}
+
+func sameTomlPath(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// tableRange returns the statement range [start, end) holding the body of a table.
+// The root table (nil path) is everything before the first header. found is false
+// if the table has no [header] of its own.
+func (d *tomlDocument) tableRange(table []string) (start, end int, found bool) {
+	if len(table) == 0 {
+		end = len(d.stmts)
+		for i, s := range d.stmts {
+			if s.kind == tomlTable {
+				end = i
+				break
+			}
+		}
+		return 0, end, true
+	}
+	for i, s := range d.stmts {
+		if s.kind == tomlTable && !s.array && sameTomlPath(s.key, table) {
+			end = len(d.stmts)
+			for j := i + 1; j < len(d.stmts); j++ {
+				if d.stmts[j].kind == tomlTable {
+					end = j
+					break
+				}
+			}
+			return i + 1, end, true
+		}
+	}
+	return 0, 0, false
+}
+
+// parentRange returns the body of the nearest table on the way to table that
+// has a [header] of its own (or the root), and the rest of the path below it.
+func (d *tomlDocument) parentRange(table []string) (start, end int, rest []string) {
+	for i := len(table); i > 0; i-- {
+		if start, end, found := d.tableRange(table[:i]); found {
+			return start, end, table[i:]
+		}
+	}
+	start, end, _ = d.tableRange(nil)
+	return start, end, table
+}
+
+// definingRange returns where the key/values of table are: the body of its own
+// [header], or the body of a parent whose dotted keys (prefix.key = ...) define it.
+// found is false if neither exists.
+func (d *tomlDocument) definingRange(table []string) (start, end int, prefix []string, found bool) {
+	start, end, prefix = d.parentRange(table)
+	if len(prefix) == 0 {
+		return start, end, nil, true
+	}
+	for i := start; i < end; i++ {
+		if s := d.stmts[i]; s.kind == tomlKeyValue && hasTomlPrefix(s.key, prefix) {
+			return start, end, prefix, true
+		}
+	}
+	return 0, 0, nil, false
+}
+
+// hasTomlPrefix reports whether key lies below prefix.
+func hasTomlPrefix(key, prefix []string) bool {
+	return len(key) > len(prefix) && sameTomlPath(key[:len(prefix)], prefix)
+}
+
+// inlineDefinition returns the statement holding the inline table { ... } that
+// table is defined in, or -1.
+func (d *tomlDocument) inlineDefinition(table []string) int {
+	start, end, rest := d.parentRange(table)
+	for i := start; i < end; i++ {
+		s := d.stmts[i]
+		if s.kind != tomlKeyValue || len(s.key) > len(rest) || !sameTomlPath(s.key, rest[:len(s.key)]) {
+			continue
+		}
+		if _, _, ok := splitTomlInlineTable(s.value); ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// expandInlineTables rewrites the inline tables table is defined in as dotted
+// keys. Inline tables cannot be extended, neither in place nor with a [header]
+// elsewhere, so this comes before adding or removing keys.
+func (d *tomlDocument) expandInlineTables(table []string) {
+	for {
+		i := d.inlineDefinition(table)
+		if i < 0 {
+			return
+		}
+		s := d.stmts[i]
+		entries, comment, _ := splitTomlInlineTable(s.value)
+		var expanded []tomlStatement
+		if comment != "" {
+			expanded = append(expanded, tomlStatement{kind: tomlTrivia, text: comment})
+		}
+		for _, e := range entries {
+			expanded = append(expanded, newTomlKeyValue(append(append([]string{}, s.key...), e.key...), e.value))
+		}
+		d.stmts = append(d.stmts[:i], append(expanded, d.stmts[i+1:]...)...)
+	}
+}
+
+func (d *tomlDocument) clone() *tomlDocument {
+	return &tomlDocument{stmts: append([]tomlStatement(nil), d.stmts...)}
+}
+
+func newTomlKeyValue(key []string, literal string) tomlStatement {
+	return tomlStatement{kind: tomlKeyValue, text: tomlKeyPath(key) + " = " + literal, key: key, value: " " + literal}
+}
+
+// splitTomlInlineTable splits an inline table { a = 1, b.c = "x" } into its
+// key/values and returns the comment that may follow it.
+func splitTomlInlineTable(raw string) (entries []tomlStatement, comment string, ok bool) {
+	s := strings.TrimSpace(raw)
+	if !strings.HasPrefix(s, "{") {
+		return nil, "", false
+	}
+	var parts []string
+	depth, from, closed := 0, 1, false
+	for i := 0; i < len(s) && !closed; {
+		switch {
+		case strings.HasPrefix(s[i:], `"""`), strings.HasPrefix(s[i:], `'''`):
+			end := findTomlMultilineEnd(s, i+3, s[i:i+3], s[i] == '"')
+			if end < 0 {
+				return nil, "", false
+			}
+			i = end
+		case s[i] == '"':
+			_, n, err := parseTomlBasicString(s[i:])
+			if err != nil {
+				return nil, "", false
+			}
+			i += n
+		case s[i] == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, "", false
+			}
+			i += end + 2
+		case s[i] == '#':
+			// A comment in an array that spans lines
+			nl := strings.IndexByte(s[i:], '\n')
+			if nl < 0 {
+				return nil, "", false
+			}
+			i += nl
+		case s[i] == '[' || s[i] == '{':
+			depth++
+			i++
+		case s[i] == ']' || s[i] == '}':
+			depth--
+			i++
+			if depth == 0 {
+				parts = append(parts, s[from:i-1])
+				comment = strings.TrimSpace(s[i:])
+				closed = true
+			}
+		case s[i] == ',' && depth == 1:
+			parts = append(parts, s[from:i])
+			from = i + 1
+			i++
+		default:
+			i++
+		}
+	}
+	if !closed || (comment != "" && !strings.HasPrefix(comment, "#")) {
+		return nil, "", false
+	}
+	for _, p := range parts {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		key, rest, err := parseTomlKey(p)
+		if err != nil {
+			return nil, "", false
+		}
+		rest = strings.TrimSpace(rest)
+		if !strings.HasPrefix(rest, "=") {
+			return nil, "", false
+		}
+		entries = append(entries, tomlStatement{kind: tomlKeyValue, key: key, value: strings.TrimSpace(rest[1:])})
+	}
+	return entries, comment, true
+}
+
+// find returns the statement defining key in table, under the table's own
+// [header] or as a dotted key of a parent.
+func (d *tomlDocument) find(table []string, key string) int {
+	start, end, prefix, found := d.definingRange(table)
+	if !found {
+		return -1
+	}
+	want := append(append([]string{}, prefix...), key)
+	for i := start; i < end; i++ {
+		if s := d.stmts[i]; s.kind == tomlKeyValue && sameTomlPath(s.key, want) {
+			return i
+		}
+	}
+	return -1
+}
+
+// hasTable reports whether table is defined, with a [header], dotted keys or inline.
+func (d *tomlDocument) hasTable(table []string) bool {
+	if _, _, _, found := d.definingRange(table); found {
+		return true
+	}
+	if d.inlineDefinition(table) < 0 {
+		return false
+	}
+	c := d.clone()
+	c.expandInlineTables(table)
+	return c.hasTable(table)
+}
+
+// get returns the decoded value of key in table.
+func (d *tomlDocument) get(table []string, key string) (interface{}, bool) {
+	raw, ok := d.rawValue(table, key)
+	if !ok {
+		return nil, false
+	}
+	return decodeTomlValue(raw), true
+}
+
+// rawValue returns the source text of key's value in table, trailing comment included.
+func (d *tomlDocument) rawValue(table []string, key string) (string, bool) {
+	if i := d.find(table, key); i >= 0 {
+		return strings.TrimSpace(d.stmts[i].value), true
+	}
+	if d.inlineDefinition(table) < 0 {
+		return "", false
+	}
+	c := d.clone()
+	c.expandInlineTables(table)
+	return c.rawValue(table, key)
+}
+
+// keys lists the plain keys of a table in file order.
+func (d *tomlDocument) keys(table []string) []string {
+	start, end, prefix, found := d.definingRange(table)
+	if !found {
+		return nil
+	}
+	var keys []string
+	for i := start; i < end; i++ {
+		if s := d.stmts[i]; s.kind == tomlKeyValue && len(s.key) == len(prefix)+1 && hasTomlPrefix(s.key, prefix) {
+			keys = append(keys, s.key[len(prefix)])
+		}
+	}
+	return keys
+}
+
+// set writes key = literal into table, replacing an existing value in place,
+// appending to the table body, or creating the table at the end of the file.
+// A table defined with dotted keys gets another dotted key, and one defined
+// inline is expanded to dotted keys first. It reports whether the document changed.
+func (d *tomlDocument) set(table []string, key, literal string) bool {
+	if raw, ok := d.rawValue(table, key); ok && raw == literal {
+		return false
+	}
+	d.expandInlineTables(table)
+	if i := d.find(table, key); i >= 0 {
+		d.stmts[i] = newTomlKeyValue(d.stmts[i].key, literal)
+		return true
+	}
+	start, end, prefix, found := d.definingRange(table)
+	if !found {
+		if n := len(d.stmts); n > 0 && strings.TrimSpace(d.stmts[n-1].text) != "" {
+			d.stmts = append(d.stmts, tomlStatement{kind: tomlTrivia})
+		}
+		d.stmts = append(d.stmts,
+			tomlStatement{kind: tomlTable, text: "[" + tomlKeyPath(table) + "]", key: table},
+			newTomlKeyValue([]string{key}, literal))
+		return true
+	}
+	// Insert after the last key/value of the table so trailing comments and
+	// blank lines keep separating it from the next table
+	at := start
+	for i := start; i < end; i++ {
+		if s := d.stmts[i]; s.kind == tomlKeyValue && hasTomlPrefix(s.key, prefix) {
+			at = i + 1
+		}
+	}
+	d.stmts = append(d.stmts, tomlStatement{})
+	copy(d.stmts[at+1:], d.stmts[at:])
+	d.stmts[at] = newTomlKeyValue(append(append([]string{}, prefix...), key), literal)
+	return true
+}
+
+// setIfAbsent writes key only if the table does not define it yet.
+func (d *tomlDocument) setIfAbsent(table []string, key, literal string) bool {
+	if _, ok := d.rawValue(table, key); ok {
+		return false
+	}
+	return d.set(table, key, literal)
+}
+
+// remove deletes key from table and reports whether it was present.
+func (d *tomlDocument) remove(table []string, key string) bool {
+	if _, ok := d.rawValue(table, key); !ok {
+		return false
+	}
+	d.expandInlineTables(table)
+	i := d.find(table, key)
+	d.stmts = append(d.stmts[:i], d.stmts[i+1:]...)
+	return true
+}
+
+// tomlLeaf is a key/value together with its full path from the document root.
+type tomlLeaf struct {
+	path    []string // Table path followed by the key path
+	table   []string
+	key     []string
+	inArray bool // Defined in an [[array.of.tables]] entry, which set and remove cannot address
+	value   string
+}
+
+// leaves lists every key/value in file order, looking into inline tables.
+// Entries of an array of tables get their index in the path, so keys of
+// different entries can be told apart.
+func (d *tomlDocument) leaves() []tomlLeaf {
+	var leaves []tomlLeaf
+	var table []string
+	inArray := false
+	counts := make(map[string]int)
+	for _, s := range d.stmts {
+		switch s.kind {
+		case tomlTable:
+			table = s.key
+			inArray = s.array
+			if s.array {
+				id := strings.Join(s.key, "\x00")
+				table = append(append([]string{}, s.key...), strconv.Itoa(counts[id]))
+				counts[id]++
+			}
+		case tomlKeyValue:
+			leaves = appendTomlLeaves(leaves, table, s.key, s.value, inArray)
+		}
+	}
+	return leaves
+}
+
+// appendTomlLeaves adds a key/value to leaves, or the key/values inside it if
+// it is an inline table.
+func appendTomlLeaves(leaves []tomlLeaf, table, key []string, value string, inArray bool) []tomlLeaf {
+	if entries, _, ok := splitTomlInlineTable(value); ok {
+		for _, e := range entries {
+			leaves = appendTomlLeaves(leaves, table, append(append([]string{}, key...), e.key...), e.value, inArray)
+		}
+		return leaves
+	}
+	path := append(append([]string{}, table...), key...)
+	return append(leaves, tomlLeaf{path: path, table: table, key: key, inArray: inArray, value: strings.TrimSpace(value)})
+}
+
+// parent returns the table that directly holds the leaf's last key.
+func (l tomlLeaf) parent() []string {
+	return append(append([]string{}, l.table...), l.key[:len(l.key)-1]...)
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// checkTomlTables fails if a table is defined twice: by two headers, or by a
+// header and by keys outside it (dotted keys or an inline table of a parent).
+func checkTomlTables(t *testing.T, doc *tomlDocument) {
+	t.Helper()
+	headers := make(map[string]bool)
+	for _, s := range doc.stmts {
+		if s.kind != tomlTable || s.array {
+			continue
+		}
+		id := strings.Join(s.key, ".")
+		if headers[id] {
+			t.Fatalf("[%s] defined twice:\n%s", id, doc)
+		}
+		headers[id] = true
+		for _, l := range doc.leaves() {
+			if hasTomlPrefix(l.path, s.key) && len(l.table) < len(s.key) {
+				t.Fatalf("[%s] also defined by %s:\n%s", id, strings.Join(l.path, "."), doc)
+			}
+		}
+	}
+}
+
+func mustParseToml(t *testing.T, data string) *tomlDocument {
+	t.Helper()
+	doc, err := parseToml(data)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, data)
+	}
+	return doc
+}
+
+func TestTomlRoundTrip(t *testing.T) {
+	for _, data := range []string{
+		"",
+		"# only a comment\n",
+		"model = \"gpt-5\" # trailing\n\n# MCP servers\n[mcp_servers.docs]\ncommand = \"npx\"\n",
+		"[b]\nx = 1\n\n[a]\ny = 2\n",
+		"args = [\n  \"a\", # first\n  \"b\",\n]\n",
+		"prompt = \"\"\"\nline [one]\n  line { two }\n\"\"\"\nraw = '''C:\\path'''\n",
+		"\"quoted key\".'lit' = { a = 1, b = [1, 2] }\n",
+		"[[profiles]]\nname = \"a\"\n[[profiles]]\nname = \"b\"\n",
+		"  indented = true\t# tab\n",
+	} {
+		doc := mustParseToml(t, data)
+		if got := doc.String(); got != data {
+			t.Errorf("round trip changed\n%q\nto\n%q", data, got)
+		}
+	}
+}
+
+func TestTomlParseErrors(t *testing.T) {
+	for _, data := range []string{
+		"key\n",
+		"[table\n",
+		"[table] x = 1\n",
+		"args = [\n  \"a\",\n",
+		"name = \"unterminated\n",
+		"name = \"bad \\q escape\"\n",
+	} {
+		if _, err := parseToml(data); err == nil {
+			t.Errorf("%q parsed", data)
+		}
+	}
+}
+
+var tomlTestProvider = []string{"model_providers", "p"}
+
+func TestTomlSet(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		data  string
+		table []string
+		key   string
+		value string
+		want  string
+	}{
+		{
+			name:  "replace keeps the rest",
+			data:  "# mine\nmodel = \"old\" # comment\nother = 1\n",
+			key:   "model",
+			value: `"new"`,
+			want:  "# mine\nmodel = \"new\"\nother = 1\n",
+		},
+		{
+			name:  "new root key goes before the first table",
+			data:  "a = 1\n\n[t]\nb = 2\n",
+			key:   "c",
+			value: "3",
+			want:  "a = 1\nc = 3\n\n[t]\nb = 2\n",
+		},
+		{
+			name:  "append to a table keeps its trailing comment",
+			data:  "[model_providers.p]\nname = \"p\"\n# end of p\n\n[other]\n",
+			table: tomlTestProvider,
+			key:   "base_url",
+			value: `"https://x"`,
+			want:  "[model_providers.p]\nname = \"p\"\nbase_url = \"https://x\"\n# end of p\n\n[other]\n",
+		},
+		{
+			name:  "missing table is created at the end",
+			data:  "model = \"m\"\n",
+			table: tomlTestProvider,
+			key:   "name",
+			value: `"p"`,
+			want:  "model = \"m\"\n\n[model_providers.p]\nname = \"p\"\n",
+		},
+		{
+			name:  "quoted table and key",
+			data:  "",
+			table: []string{"model_providers", "my proxy"},
+			key:   "a.b",
+			value: "1",
+			want:  "[model_providers.\"my proxy\"]\n\"a.b\" = 1\n",
+		},
+		{
+			name:  "dotted keys at the root",
+			data:  "model_providers.p.name = \"p\"\nmodel_providers.p.base_url = \"old\"\n\n[other]\n",
+			table: tomlTestProvider,
+			key:   "wire_api",
+			value: `"chat"`,
+			want:  "model_providers.p.name = \"p\"\nmodel_providers.p.base_url = \"old\"\nmodel_providers.p.wire_api = \"chat\"\n\n[other]\n",
+		},
+		{
+			name:  "dotted key replaced",
+			data:  "[model_providers]\np.base_url = \"old\"\n",
+			table: tomlTestProvider,
+			key:   "base_url",
+			value: `"new"`,
+			want:  "[model_providers]\np.base_url = \"new\"\n",
+		},
+		{
+			name:  "inline table expanded",
+			data:  "[model_providers]\np = { name = \"p\", base_url = \"old\" } # mine\nq = { name = \"q\" }\n",
+			table: tomlTestProvider,
+			key:   "base_url",
+			value: `"new"`,
+			want:  "[model_providers]\n# mine\np.name = \"p\"\np.base_url = \"new\"\nq = { name = \"q\" }\n",
+		},
+		{
+			name:  "nested inline tables expanded",
+			data:  "model_providers = { p = { name = \"p, with comma\", headers = { a = \"b\" } } }\n",
+			table: tomlTestProvider,
+			key:   "wire_api",
+			value: `"chat"`,
+			want:  "model_providers.p.name = \"p, with comma\"\nmodel_providers.p.headers = { a = \"b\" }\nmodel_providers.p.wire_api = \"chat\"\n",
+		},
+		{
+			name:  "sibling defined inline",
+			data:  "model_providers = { q = { name = \"q\" } }\n",
+			table: tomlTestProvider,
+			key:   "name",
+			value: `"p"`,
+			want:  "model_providers.q = { name = \"q\" }\n\n[model_providers.p]\nname = \"p\"\n",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			doc := mustParseToml(t, c.data)
+			if !doc.set(c.table, c.key, c.value) {
+				t.Fatal("set reported no change")
+			}
+			if got := doc.String(); got != c.want {
+				t.Fatalf("got\n%s\nwant\n%s", got, c.want)
+			}
+			doc = mustParseToml(t, doc.String())
+			checkTomlTables(t, doc)
+			if raw, ok := doc.rawValue(c.table, c.key); !ok || raw != c.value {
+				t.Fatalf("read back %q, %v", raw, ok)
+			}
+			if doc.set(c.table, c.key, c.value) {
+				t.Fatal("setting the same value again changed the document")
+			}
+		})
+	}
+}
+
+func TestTomlSetSameValueLeavesInlineTable(t *testing.T) {
+	data := "model_providers = { p = { name = \"p\" } }\n"
+	doc := mustParseToml(t, data)
+	if doc.set(tomlTestProvider, "name", `"p"`) || doc.setIfAbsent(tomlTestProvider, "name", `"x"`) {
+		t.Fatal("document changed")
+	}
+	if doc.String() != data {
+		t.Fatalf("inline table rewritten:\n%s", doc)
+	}
+}
+
+func TestTomlRemove(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		data string
+		want string
+	}{
+		{"header", "[model_providers.p]\nname = \"p\" # c\nbase_url = \"u\"\n", "[model_providers.p]\nbase_url = \"u\"\n"},
+		{"dotted", "[model_providers]\np.name = \"p\"\np.base_url = \"u\"\n", "[model_providers]\np.base_url = \"u\"\n"},
+		{"inline", "model_providers.p = { name = \"p\", base_url = \"u\" }\n", "model_providers.p.base_url = \"u\"\n"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			doc := mustParseToml(t, c.data)
+			if !doc.remove(tomlTestProvider, "name") {
+				t.Fatal("name not removed")
+			}
+			if got := doc.String(); got != c.want {
+				t.Fatalf("got\n%s\nwant\n%s", got, c.want)
+			}
+			if doc.remove(tomlTestProvider, "name") || doc.remove(tomlTestProvider, "missing") {
+				t.Fatal("removed a missing key")
+			}
+		})
+	}
+}
+
+func TestTomlGet(t *testing.T) {
+	doc := mustParseToml(t, `model = "m" # comment
+n = 1_000
+f = 0.5
+on = true
+hex = 0xff
+list = [1, 2]
+
+[model_providers]
+p = { name = "p", retries = 3, "odd key" = 'lit' }
+q.name = "q"
+
+[model_providers.r]
+name = "r"
+`)
+	for _, c := range []struct {
+		table []string
+		key   string
+		want  interface{}
+	}{
+		{nil, "model", "m"},
+		{nil, "n", int64(1000)},
+		{nil, "f", 0.5},
+		{nil, "on", true},
+		{nil, "hex", int64(255)},
+		{nil, "list", "[1, 2]"},
+		{tomlTestProvider, "name", "p"},
+		{tomlTestProvider, "retries", int64(3)},
+		{tomlTestProvider, "odd key", "lit"},
+		{[]string{"model_providers", "q"}, "name", "q"},
+		{[]string{"model_providers", "r"}, "name", "r"},
+	} {
+		if v, ok := doc.get(c.table, c.key); !ok || v != c.want {
+			t.Errorf("%v %s = %#v, %v; want %#v", c.table, c.key, v, ok, c.want)
+		}
+	}
+	if _, ok := doc.get(tomlTestProvider, "missing"); ok {
+		t.Error("missing key found")
+	}
+	for _, id := range []string{"p", "q", "r"} {
+		if !doc.hasTable([]string{"model_providers", id}) {
+			t.Errorf("table %s not found", id)
+		}
+	}
+	if doc.hasTable([]string{"model_providers", "s"}) {
+		t.Error("undefined table found")
+	}
+	if got := strings.Join(doc.keys([]string{"model_providers", "q"}), ","); got != "name" {
+		t.Errorf("keys of q = %s", got)
+	}
+}
+
+func TestTomlLeaves(t *testing.T) {
+	doc := mustParseToml(t, `model_providers.a = { name = "a", env = { K = "v" } }
+
+[model_providers]
+b.name = "b"
+
+[[profiles]]
+name = "one"
+[[profiles]]
+name = "two"
+`)
+	var got []string
+	for _, l := range doc.leaves() {
+		got = append(got, strings.Join(l.path, ".")+"="+l.value)
+	}
+	want := `model_providers.a.name="a" model_providers.a.env.K="v" model_providers.b.name="b" profiles.0.name="one" profiles.1.name="two"`
+	if strings.Join(got, " ") != want {
+		t.Fatalf("leaves = %s", strings.Join(got, " "))
+	}
+}
+
+func TestTomlStringEscapes(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"plain",
+		`quote " and backslash \`,
+		"tab\tnewline\nreturn\r",
+		"control \x01 \x7f",
+		"unicode é 日本",
+	} {
+		quoted := tomlString(s)
+		if strings.ContainsAny(quoted, "\n\r\t\x01\x7f") {
+			t.Errorf("%q written with a raw control character: %s", s, quoted)
+		}
+		got, n, err := parseTomlBasicString(quoted)
+		if err != nil || got != s || n != len(quoted) {
+			t.Errorf("%q -> %s -> %q, %d, %v", s, quoted, got, n, err)
+		}
+	}
+	for _, c := range []struct {
+		raw  string
+		want interface{}
+	}{
+		{` "a\u00e9\U0001F600" `, "aé😀"},
+		{`'C:\path'`, `C:\path`},
+		{"\"\"\"\nfirst\\\n   second\"\"\"", "firstsecond"},
+		{"'''\nraw \\n'''", `raw \n`},
+		{`"#not a comment" # comment`, "#not a comment"},
+		{`42 # answer`, int64(42)},
+		{`-1.5e3`, -1500.0},
+		{`2024-01-01T00:00:00Z`, "2024-01-01T00:00:00Z"},
+	} {
+		if got := decodeTomlValue(c.raw); got != c.want {
+			t.Errorf("decode %s = %#v, want %#v", c.raw, got, c.want)
+		}
+	}
+	for k, want := range map[string]string{"bare-key_1": "bare-key_1", "has space": `"has space"`, "": `""`, "a.b": `"a.b"`} {
+		if got := tomlKey(k); got != want {
+			t.Errorf("tomlKey(%q) = %s", k, got)
+		}
+	}
+}
@@ -50,10 +50,11 @@ func setupTray(app *App, appOptions *options.App) {
 				modelName := model.ModelName
 				m.Click(func() {
 					go func() {
-						currentConfig, _ := app.LoadConfig()
-						currentConfig.Claude.CurrentModel = modelName
-						currentConfig.ActiveTool = "claude"
-						app.SaveConfig(currentConfig)
+						currentConfig, _ := app.modifyConfigFrom(configSourceTray, func(c *AppConfig) error {
+							c.Claude.CurrentModel = modelName
+							c.ActiveTool = "claude"
+							return nil
+						})
 
 						// Check if API key is missing
 						for _, m := range currentConfig.Claude.Models {
@@ -75,10 +76,11 @@ func setupTray(app *App, appOptions *options.App) {
 				modelName := model.ModelName
 				m.Click(func() {
 					go func() {
-						currentConfig, _ := app.LoadConfig()
-						currentConfig.Gemini.CurrentModel = modelName
-						currentConfig.ActiveTool = "gemini"
-						app.SaveConfig(currentConfig)
+						currentConfig, _ := app.modifyConfigFrom(configSourceTray, func(c *AppConfig) error {
+							c.Gemini.CurrentModel = modelName
+							c.ActiveTool = "gemini"
+							return nil
+						})
 
 						// Check if API key is missing
 						for _, m := range currentConfig.Gemini.Models {
@@ -100,10 +102,11 @@ func setupTray(app *App, appOptions *options.App) {
 				modelName := model.ModelName
 				m.Click(func() {
 					go func() {
-						currentConfig, _ := app.LoadConfig()
-						currentConfig.Codex.CurrentModel = modelName
-						currentConfig.ActiveTool = "codex"
-						app.SaveConfig(currentConfig)
+						currentConfig, _ := app.modifyConfigFrom(configSourceTray, func(c *AppConfig) error {
+							c.Codex.CurrentModel = modelName
+							c.ActiveTool = "codex"
+							return nil
+						})
 
 						// Check if API key is missing
 						for _, m := range currentConfig.Codex.Models {
@@ -125,10 +128,11 @@ func setupTray(app *App, appOptions *options.App) {
 				modelName := model.ModelName
 				m.Click(func() {
 					go func() {
-						currentConfig, _ := app.LoadConfig()
-						currentConfig.Opencode.CurrentModel = modelName
-						currentConfig.ActiveTool = "opencode"
-						app.SaveConfig(currentConfig)
+						currentConfig, _ := app.modifyConfigFrom(configSourceTray, func(c *AppConfig) error {
+							c.Opencode.CurrentModel = modelName
+							c.ActiveTool = "opencode"
+							return nil
+						})
 
 						// Check if API key is missing
 						for _, m := range currentConfig.Opencode.Models {
@@ -150,10 +154,11 @@ func setupTray(app *App, appOptions *options.App) {
 				modelName := model.ModelName
 				m.Click(func() {
 					go func() {
-						currentConfig, _ := app.LoadConfig()
-						currentConfig.CodeBuddy.CurrentModel = modelName
-						currentConfig.ActiveTool = "codebuddy"
-						app.SaveConfig(currentConfig)
+						currentConfig, _ := app.modifyConfigFrom(configSourceTray, func(c *AppConfig) error {
+							c.CodeBuddy.CurrentModel = modelName
+							c.ActiveTool = "codebuddy"
+							return nil
+						})
 
 						// Check if API key is missing
 						for _, m := range currentConfig.CodeBuddy.Models {
@@ -175,10 +180,11 @@ func setupTray(app *App, appOptions *options.App) {
 				modelName := model.ModelName
 				m.Click(func() {
 					go func() {
-						currentConfig, _ := app.LoadConfig()
-						currentConfig.Qoder.CurrentModel = modelName
-						currentConfig.ActiveTool = "qoder"
-						app.SaveConfig(currentConfig)
+						currentConfig, _ := app.modifyConfigFrom(configSourceTray, func(c *AppConfig) error {
+							c.Qoder.CurrentModel = modelName
+							c.ActiveTool = "qoder"
+							return nil
+						})
 
 						// Check if API key is missing
 						for _, m := range currentConfig.Qoder.Models {
@@ -200,10 +206,11 @@ func setupTray(app *App, appOptions *options.App) {
 				modelName := model.ModelName
 				m.Click(func() {
 					go func() {
-						currentConfig, _ := app.LoadConfig()
-						currentConfig.IFlow.CurrentModel = modelName
-						currentConfig.ActiveTool = "iflow"
-						app.SaveConfig(currentConfig)
+						currentConfig, _ := app.modifyConfigFrom(configSourceTray, func(c *AppConfig) error {
+							c.IFlow.CurrentModel = modelName
+							c.ActiveTool = "iflow"
+							return nil
+						})
 
 						// Check if API key is missing
 						for _, m := range currentConfig.IFlow.Models {
@@ -225,10 +232,11 @@ func setupTray(app *App, appOptions *options.App) {
 			modelName := model.ModelName
 			m.Click(func() {
 				go func() {
-					currentConfig, _ := app.LoadConfig()
-					currentConfig.Kilo.CurrentModel = modelName
-					currentConfig.ActiveTool = "kilo"
-					app.SaveConfig(currentConfig)
+					currentConfig, _ := app.modifyConfigFrom(configSourceTray, func(c *AppConfig) error {
+						c.Kilo.CurrentModel = modelName
+						c.ActiveTool = "kilo"
+						return nil
+					})
 
 					// Check if API key is missing
 					for _, m := range currentConfig.Kilo.Models {
@@ -250,10 +258,11 @@ func setupTray(app *App, appOptions *options.App) {
 			modelName := model.ModelName
 			m.Click(func() {
 				go func() {
-					currentConfig, _ := app.LoadConfig()
-					currentConfig.Kode.CurrentModel = modelName
-					currentConfig.ActiveTool = "kode"
-					app.SaveConfig(currentConfig)
+					currentConfig, _ := app.modifyConfigFrom(configSourceTray, func(c *AppConfig) error {
+						c.Kode.CurrentModel = modelName
+						c.ActiveTool = "kode"
+						return nil
+					})
 
 					// Check if API key is missing
 					for _, m := range currentConfig.Kode.Models {
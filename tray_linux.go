@@ -44,10 +44,11 @@ func setupTray(app *App, appOptions *options.App) {
 					modelName := model.ModelName
 					m.Click(func() {
 						go func() {
-							currentConfig, _ := app.LoadConfig()
-							currentConfig.Claude.CurrentModel = modelName
-							currentConfig.ActiveTool = "claude"
-							app.SaveConfig(currentConfig)
+							currentConfig, _ := app.modifyConfigFrom(configSourceTray, func(c *AppConfig) error {
+								c.Claude.CurrentModel = modelName
+								c.ActiveTool = "claude"
+								return nil
+							})
 
 							for _, m := range currentConfig.Claude.Models {
 								if m.ModelName == modelName && m.ApiKey == "" {
@@ -68,10 +69,11 @@ func setupTray(app *App, appOptions *options.App) {
 					modelName := model.ModelName
 					m.Click(func() {
 						go func() {
-							currentConfig, _ := app.LoadConfig()
-							currentConfig.Gemini.CurrentModel = modelName
-							currentConfig.ActiveTool = "gemini"
-							app.SaveConfig(currentConfig)
+							currentConfig, _ := app.modifyConfigFrom(configSourceTray, func(c *AppConfig) error {
+								c.Gemini.CurrentModel = modelName
+								c.ActiveTool = "gemini"
+								return nil
+							})
 
 							for _, m := range currentConfig.Gemini.Models {
 								if m.ModelName == modelName && m.ApiKey == "" {
@@ -92,10 +94,11 @@ func setupTray(app *App, appOptions *options.App) {
 					modelName := model.ModelName
 					m.Click(func() {
 						go func() {
-							currentConfig, _ := app.LoadConfig()
-							currentConfig.Codex.CurrentModel = modelName
-							currentConfig.ActiveTool = "codex"
-							app.SaveConfig(currentConfig)
+							currentConfig, _ := app.modifyConfigFrom(configSourceTray, func(c *AppConfig) error {
+								c.Codex.CurrentModel = modelName
+								c.ActiveTool = "codex"
+								return nil
+							})
 
 							for _, m := range currentConfig.Codex.Models {
 								if m.ModelName == modelName && m.ApiKey == "" {
@@ -116,10 +119,11 @@ func setupTray(app *App, appOptions *options.App) {
 					modelName := model.ModelName
 					m.Click(func() {
 						go func() {
-							currentConfig, _ := app.LoadConfig()
-							currentConfig.Opencode.CurrentModel = modelName
-							currentConfig.ActiveTool = "opencode"
-							app.SaveConfig(currentConfig)
+							currentConfig, _ := app.modifyConfigFrom(configSourceTray, func(c *AppConfig) error {
+								c.Opencode.CurrentModel = modelName
+								c.ActiveTool = "opencode"
+								return nil
+							})
 
 							for _, m := range currentConfig.Opencode.Models {
 								if m.ModelName == modelName && m.ApiKey == "" {
@@ -140,10 +144,11 @@ func setupTray(app *App, appOptions *options.App) {
 					modelName := model.ModelName
 					m.Click(func() {
 						go func() {
-							currentConfig, _ := app.LoadConfig()
-							currentConfig.CodeBuddy.CurrentModel = modelName
-							currentConfig.ActiveTool = "codebuddy"
-							app.SaveConfig(currentConfig)
+							currentConfig, _ := app.modifyConfigFrom(configSourceTray, func(c *AppConfig) error {
+								c.CodeBuddy.CurrentModel = modelName
+								c.ActiveTool = "codebuddy"
+								return nil
+							})
 
 							for _, m := range currentConfig.CodeBuddy.Models {
 								if m.ModelName == modelName && m.ApiKey == "" {
@@ -164,10 +169,11 @@ func setupTray(app *App, appOptions *options.App) {
 					modelName := model.ModelName
 					m.Click(func() {
 						go func() {
-							currentConfig, _ := app.LoadConfig()
-							currentConfig.Qoder.CurrentModel = modelName
-							currentConfig.ActiveTool = "qoder"
-							app.SaveConfig(currentConfig)
+							currentConfig, _ := app.modifyConfigFrom(configSourceTray, func(c *AppConfig) error {
+								c.Qoder.CurrentModel = modelName
+								c.ActiveTool = "qoder"
+								return nil
+							})
 
 							for _, m := range currentConfig.Qoder.Models {
 								if m.ModelName == modelName && m.ApiKey == "" {
@@ -188,10 +194,11 @@ func setupTray(app *App, appOptions *options.App) {
 					modelName := model.ModelName
 					m.Click(func() {
 						go func() {
-							currentConfig, _ := app.LoadConfig()
-							currentConfig.IFlow.CurrentModel = modelName
-							currentConfig.ActiveTool = "iflow"
-							app.SaveConfig(currentConfig)
+							currentConfig, _ := app.modifyConfigFrom(configSourceTray, func(c *AppConfig) error {
+								c.IFlow.CurrentModel = modelName
+								c.ActiveTool = "iflow"
+								return nil
+							})
 
 							for _, m := range currentConfig.IFlow.Models {
 								if m.ModelName == modelName && m.ApiKey == "" {
@@ -212,10 +219,11 @@ func setupTray(app *App, appOptions *options.App) {
 			modelName := model.ModelName
 			m.Click(func() {
 				go func() {
-					currentConfig, _ := app.LoadConfig()
-					currentConfig.Kilo.CurrentModel = modelName
-					currentConfig.ActiveTool = "kilo"
-					app.SaveConfig(currentConfig)
+					currentConfig, _ := app.modifyConfigFrom(configSourceTray, func(c *AppConfig) error {
+						c.Kilo.CurrentModel = modelName
+						c.ActiveTool = "kilo"
+						return nil
+					})
 
 					for _, m := range currentConfig.Kilo.Models {
 						if m.ModelName == modelName && m.ApiKey == "" {
@@ -236,10 +244,11 @@ func setupTray(app *App, appOptions *options.App) {
 			modelName := model.ModelName
 			m.Click(func() {
 				go func() {
-					currentConfig, _ := app.LoadConfig()
-					currentConfig.Kode.CurrentModel = modelName
-					currentConfig.ActiveTool = "kode"
-					app.SaveConfig(currentConfig)
+					currentConfig, _ := app.modifyConfigFrom(configSourceTray, func(c *AppConfig) error {
+						c.Kode.CurrentModel = modelName
+						c.ActiveTool = "kode"
+						return nil
+					})
 
 					for _, m := range currentConfig.Kode.Models {
 						if m.ModelName == modelName && m.ApiKey == "" {